@@ -2,10 +2,13 @@ package main
 
 import (
 	"log"
+	"path/filepath"
+	"time"
 
 	"github.com/anidex/backend/internal/config"
 	"github.com/anidex/backend/internal/controllers"
 	"github.com/anidex/backend/internal/middleware"
+	"github.com/anidex/backend/internal/models"
 	"github.com/anidex/backend/internal/repositories"
 	"github.com/anidex/backend/internal/services"
 
@@ -44,15 +47,76 @@ func main() {
 	speciesRepo := repositories.NewSpeciesRepository()
 	animalCatchRepo := repositories.NewAnimalCatchRepository()
 	locationRepo := repositories.NewLocationRepository()
+	photoRepo := repositories.NewPhotoRepository()
+	idempotencyRepo := repositories.NewIdempotencyRepository()
+	userStatsRepo := repositories.NewUserStatsRepository()
+	badgeRepo := repositories.NewBadgeRepository()
+	interactionRepo := repositories.NewCatchInteractionRepository()
+	followRepo := repositories.NewFollowRepository()
+	feedRepo := repositories.NewFeedRepository()
+	notificationRepo := repositories.NewNotificationRepository()
+	leaderboardRepo := repositories.NewLeaderboardRepository()
 	
 	firebaseService := services.NewFirebaseService()
-	authService := services.NewAuthService(userRepo, firebaseService)
+	eventBus := services.NewEventBus()
+	// Events go through the notification service, which records and pushes the
+	// ones users are notified of before passing them on to the bus
+	notificationService := services.NewNotificationService(notificationRepo, userRepo, services.NewPushProvider(firebaseService), eventBus)
+	feedService := services.NewFeedService(feedRepo, animalCatchRepo, locationRepo, services.FeedRanking{
+		HalfLife: time.Duration(config.AppConfig.FeedHalfLifeHours * float64(time.Hour)),
+		ReasonWeights: map[models.FeedReason]float64{
+			models.FeedReasonFollowing: config.AppConfig.FeedWeightFollowing,
+			models.FeedReasonNearby:    config.AppConfig.FeedWeightNearby,
+			models.FeedReasonRare:      config.AppConfig.FeedWeightRare,
+		},
+		NearbyRadiusKm: config.AppConfig.FeedNearbyRadiusKm,
+	})
+	followService := services.NewFollowService(userRepo, followRepo, feedService, notificationService)
+	authService := services.NewAuthService(userRepo, firebaseService, followService)
 	oauthService := services.NewOAuthService()
+	storageService := services.NewStorageService()
+	photoService := services.NewPhotoService(photoRepo, storageService, config.AppConfig.MaxUploadSize, config.AppConfig.MaxImagePixels)
+
+	verifiers := []services.WeightedVerifier{
+		{Verifier: services.NewRuleVerifier(animalCatchRepo), Weight: 1.0},
+	}
+	if config.AppConfig.ClassifierURL != "" {
+		verifiers = append(verifiers, services.WeightedVerifier{
+			Verifier: services.NewClassifierVerifier(config.AppConfig.ClassifierURL),
+			Weight:   2.0,
+		})
+	}
+	badgeEngine := services.NewBadgeEngine(animalCatchRepo, badgeRepo, userStatsRepo, speciesRepo, notificationService)
+	verificationService := services.NewVerificationService(animalCatchRepo, verifiers, config.AppConfig.AutoVerifyThreshold, notificationService, badgeEngine)
+	scoringService := services.NewScoringService(animalCatchRepo)
+	streakService := services.NewStreakService(userRepo, animalCatchRepo, userStatsRepo, notificationService, services.StreakConfig{
+		Grace:      config.AppConfig.StreakGrace,
+		MaxFreezes: config.AppConfig.MaxStreakFreezes,
+	})
+	statsService := services.NewStatsService(userRepo, animalCatchRepo, userStatsRepo, badgeEngine, streakService)
+	catchService := services.NewCatchService(userRepo, animalCatchRepo, speciesRepo, locationRepo, userStatsRepo, badgeEngine, statsService, streakService, photoService, scoringService, verificationService, feedService, notificationService)
+	interactionService := services.NewInteractionService(animalCatchRepo, interactionRepo, followService, notificationService)
+	badgeService := services.NewBadgeService(userRepo, badgeRepo, badgeEngine)
+	badgeAdminService := services.NewBadgeAdminService(userRepo, badgeRepo, badgeEngine)
+	leaderboardService := services.NewLeaderboardService(leaderboardRepo, userStatsRepo, config.AppConfig.StreakGrace)
 	
 	authController := controllers.NewAuthController(authService, oauthService)
 	speciesController := controllers.NewSpeciesController(speciesRepo)
-	catchController := controllers.NewCatchController(animalCatchRepo, speciesRepo, locationRepo)
-	locationController := controllers.NewLocationController(locationRepo, animalCatchRepo)
+	catchController := controllers.NewCatchController(userRepo, animalCatchRepo, catchService, followService)
+	locationController := controllers.NewLocationController(userRepo, locationRepo, animalCatchRepo)
+	moderationController := controllers.NewModerationController(animalCatchRepo, catchService, notificationService, badgeEngine)
+	photoController := controllers.NewPhotoController(photoService)
+	interactionController := controllers.NewInteractionController(userRepo, interactionService)
+	followController := controllers.NewFollowController(userRepo, followService)
+	feedController := controllers.NewFeedController(feedService)
+	eventController := controllers.NewEventController(eventBus)
+	notificationController := controllers.NewNotificationController(notificationService)
+	badgeController := controllers.NewBadgeController(badgeService)
+	badgeAdminController := controllers.NewBadgeAdminController(badgeAdminService)
+	leaderboardController := controllers.NewLeaderboardController(leaderboardService)
+	statsController := controllers.NewStatsController(statsService)
+
+	idempotency := middleware.IdempotencyMiddleware(idempotencyRepo)
 
 	api := router.Group("/api")
 	{
@@ -72,6 +136,7 @@ func main() {
 			protected.Use(middleware.AuthMiddleware())
 			{
 				protected.GET("/profile", authController.GetProfile)
+				protected.PATCH("/profile", authController.UpdateProfile)
 			}
 		}
 
@@ -87,23 +152,121 @@ func main() {
 		catches := api.Group("/catches")
 		{
 			// Public routes
-			catches.GET("/:id", catchController.GetCatchById)
+			catches.GET("/:id", middleware.OptionalAuthMiddleware(), catchController.GetCatchById)
+			catches.GET("/:id/comments", middleware.OptionalAuthMiddleware(), interactionController.GetComments)
 			
 			// Protected routes
 			protected := catches.Group("")
 			protected.Use(middleware.AuthMiddleware())
 			{
-				protected.POST("", catchController.CreateCatch)
+				protected.POST("", idempotency, catchController.CreateCatch)
+				protected.POST("/sync", idempotency, catchController.SyncCatches)
 				protected.GET("/my", catchController.GetUserCatches)
+				protected.PUT("/:id", catchController.UpdateCatch)
+				protected.PATCH("/:id", catchController.UpdateCatch)
+				protected.DELETE("/:id", catchController.DeleteCatch)
+
+				// Likes and comments
+				protected.POST("/:id/like", interactionController.LikeCatch)
+				protected.DELETE("/:id/like", interactionController.UnlikeCatch)
+				protected.POST("/:id/comments", interactionController.CreateComment)
+				protected.PATCH("/:id/comments/:commentId", interactionController.UpdateComment)
+				protected.DELETE("/:id/comments/:commentId", interactionController.DeleteComment)
+			}
+		}
+
+		// User routes
+		users := api.Group("/users")
+		{
+			users.GET("/:id/followers", middleware.OptionalAuthMiddleware(), followController.GetFollowers)
+			users.GET("/:id/following", middleware.OptionalAuthMiddleware(), followController.GetFollowing)
+			users.GET("/:id/badges", badgeController.GetUserShowcase)
+			users.GET("/:id/stats", statsController.GetUserStats)
+
+			protected := users.Group("")
+			protected.Use(middleware.AuthMiddleware())
+			{
+				protected.GET("/me/follow-requests", followController.GetFollowRequests)
+				protected.POST("/me/follow-requests/:userId/approve", followController.ApproveFollowRequest)
+				protected.DELETE("/me/follow-requests/:userId", followController.RejectFollowRequest)
+				protected.DELETE("/me/followers/:userId", followController.RemoveFollower)
+				protected.GET("/me/badges", badgeController.GetMyBadges)
+				protected.PUT("/me/badges/showcase", badgeController.UpdateShowcase)
+				protected.POST("/:id/follow", followController.FollowUser)
+				protected.DELETE("/:id/follow", followController.UnfollowUser)
+				protected.GET("/:id/relationship", followController.GetRelationship)
 			}
 		}
 
+		// Badge catalog
+		api.GET("/badges", middleware.OptionalAuthMiddleware(), badgeController.GetBadges)
+
+		// Leaderboards
+		api.GET("/leaderboards", middleware.OptionalAuthMiddleware(), leaderboardController.GetLeaderboard)
+		api.GET("/leaderboards/around-me", middleware.AuthMiddleware(), leaderboardController.GetLeaderboardAroundMe)
+
+		// Home feed
+		api.GET("/feed", middleware.AuthMiddleware(), feedController.GetFeed)
+
+		// Real-time events
+		api.GET("/events/stream", middleware.AuthMiddleware(), eventController.Stream)
+
+		// Notification inbox, preferences and push devices
+		notifications := api.Group("/notifications")
+		notifications.Use(middleware.AuthMiddleware())
+		{
+			notifications.GET("", notificationController.GetNotifications)
+			notifications.GET("/unread-count", notificationController.GetUnreadCount)
+			notifications.POST("/read-all", notificationController.MarkAllRead)
+			notifications.POST("/:id/read", notificationController.MarkRead)
+			notifications.GET("/preferences", notificationController.GetPreferences)
+			notifications.PUT("/preferences", notificationController.UpdatePreferences)
+			notifications.POST("/devices", notificationController.RegisterDevice)
+			notifications.DELETE("/devices", notificationController.UnregisterDevice)
+		}
+
+		// Photo upload routes
+		photos := api.Group("/photos")
+		photos.Use(middleware.AuthMiddleware())
+		{
+			photos.POST("", photoController.UploadPhoto)
+			photos.POST("/upload-url", photoController.CreateSignedUpload)
+			photos.POST("/:id/complete", photoController.CompleteUpload)
+		}
+
 		// Location routes (public)
 		locations := api.Group("/locations")
 		{
 			locations.GET("/nearby", locationController.GetNearbyLocations)
-			locations.GET("/catches", locationController.GetLocationCatches)
+			locations.GET("/catches", middleware.OptionalAuthMiddleware(), locationController.GetLocationCatches)
 		}
+
+		// Moderation routes (moderators and admins only)
+		moderation := api.Group("/moderation")
+		moderation.Use(middleware.AuthMiddleware(), middleware.ModeratorMiddleware(userRepo))
+		{
+			moderation.GET("/catches/pending", moderationController.GetPendingCatches)
+			moderation.POST("/catches/review", moderationController.ReviewCatches)
+		}
+
+		// Admin routes (admins only)
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware(userRepo))
+		{
+			admin.GET("/badges", badgeAdminController.ListBadges)
+			admin.POST("/badges", badgeAdminController.CreateBadge)
+			admin.PUT("/badges/:id", badgeAdminController.UpdateBadge)
+			admin.DELETE("/badges/:id", badgeAdminController.DeactivateBadge)
+			admin.POST("/badges/rules/validate", badgeAdminController.ValidateRule)
+			admin.POST("/badges/rules/evaluate", badgeAdminController.EvaluateRule)
+		}
+	}
+
+	// Locally stored photos are served and receive signed uploads from this API.
+	// Only the public renditions are served; originals still carry their EXIF data.
+	if config.AppConfig.StorageBackend == "local" {
+		router.Static(services.LocalUploadPrefix+"/"+services.PublicPhotoPrefix, filepath.Join(config.AppConfig.StorageLocalDir, services.PublicPhotoPrefix))
+		router.PUT(services.LocalUploadPrefix+"/*filepath", photoController.ReceiveSignedUpload)
 	}
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
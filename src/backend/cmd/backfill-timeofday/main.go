@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/timezone"
+	"github.com/google/uuid"
+)
+
+// Recomputes TimeOfDay for existing catches from their coordinates and the
+// position of the sun, and fills in missing location time zones
+func main() {
+	var (
+		batchSize = flag.Int("batch", 500, "Number of catches to process per batch")
+		dryRun    = flag.Bool("dry-run", false, "Report changes without writing them")
+	)
+	flag.Parse()
+
+	if *batchSize < 1 {
+		log.Fatal("-batch must be at least 1")
+	}
+
+	config.LoadConfig()
+	config.ConnectDatabase()
+
+	catchRepo := repositories.NewAnimalCatchRepository()
+	locationRepo := repositories.NewLocationRepository()
+
+	var scanned, changed, locationsUpdated int
+	updatedLocations := map[uuid.UUID]bool{}
+	lastID := uuid.Nil
+
+	for {
+		catches, err := catchRepo.GetBatchAfter(lastID, *batchSize)
+		if err != nil {
+			log.Fatalf("Failed to load catches: %v", err)
+		}
+		if len(catches) == 0 {
+			break
+		}
+
+		for i := range catches {
+			catch := &catches[i]
+			lastID = catch.ID
+			scanned++
+
+			location := &catch.Location
+			if location.ID == uuid.Nil {
+				log.Printf("Catch %s has no location, skipping", catch.ID)
+				continue
+			}
+
+			if location.Timezone == "" && !updatedLocations[location.ID] {
+				location.Timezone = timezone.Name(location.Latitude, location.Longitude)
+				updatedLocations[location.ID] = true
+				locationsUpdated++
+				if !*dryRun {
+					if err := locationRepo.Update(location); err != nil {
+						log.Fatalf("Failed to update location %s: %v", location.ID, err)
+					}
+				}
+			}
+
+			timeOfDay := models.GetTimeOfDay(catch.CaughtAt, location.Latitude, location.Longitude)
+			if timeOfDay == catch.TimeOfDay {
+				continue
+			}
+
+			changed++
+			fmt.Printf("%s: %s -> %s\n", catch.ID, catch.TimeOfDay, timeOfDay)
+			if !*dryRun {
+				if err := catchRepo.UpdateFields(catch.ID, map[string]interface{}{"time_of_day": timeOfDay}); err != nil {
+					log.Fatalf("Failed to update catch %s: %v", catch.ID, err)
+				}
+			}
+		}
+	}
+
+	mode := "Updated"
+	if *dryRun {
+		mode = "Would update"
+	}
+	fmt.Printf("Scanned %d catches. %s %d catches and %d location time zones.\n", scanned, mode, changed, locationsUpdated)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+)
+
+// A stand-in for the external species classifier used by auto-verification.
+// It confirms whatever species the catch claims with a fixed confidence, which
+// is enough to exercise the pipeline locally:
+//
+//	go run cmd/classifier-stub/main.go -confidence 0.95
+//	CLASSIFIER_URL=http://localhost:9090/classify make run
+func main() {
+	var (
+		addr       = flag.String("addr", ":9090", "Address to listen on")
+		confidence = flag.Float64("confidence", 0.9, "Confidence returned for every request")
+		label      = flag.String("label", "", "Always answer with this scientific name instead of the claimed one")
+	)
+	flag.Parse()
+
+	http.HandleFunc("/classify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			CatchID        string `json:"catch_id"`
+			ScientificName string `json:"scientific_name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		answer := req.ScientificName
+		if *label != "" {
+			answer = *label
+		}
+
+		log.Printf("Classified catch %s as %s (%.2f)", req.CatchID, answer, *confidence)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"label":      answer,
+			"confidence": *confidence,
+		})
+	})
+
+	log.Printf("Classifier stub listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		log.Fatal("Failed to start classifier stub:", err)
+	}
+}
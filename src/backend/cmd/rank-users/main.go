@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/services"
+)
+
+// Writes every user's home region and global, country and city ranks by
+// all-time points to their stats. Meant to run on a schedule, such as hourly
+// from cron.
+func main() {
+	batchSize := flag.Int("batch", 500, "Number of users to update per transaction")
+	flag.Parse()
+
+	if *batchSize < 1 {
+		log.Fatal("-batch must be at least 1")
+	}
+
+	config.LoadConfig()
+	config.ConnectDatabase()
+
+	leaderboardService := services.NewLeaderboardService(repositories.NewLeaderboardRepository(), repositories.NewUserStatsRepository(), config.AppConfig.StreakGrace)
+
+	started := time.Now()
+	result, err := leaderboardService.RankUsers(*batchSize)
+	if err != nil {
+		log.Fatalf("Failed to rank users: %v", err)
+	}
+	fmt.Printf("Updated the ranks of %d users in %s.\n", result.Updated, time.Since(started).Round(time.Millisecond))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/services"
+	"github.com/google/uuid"
+)
+
+// Rebuilds every user's stats and badges by replaying their catch history, so
+// that new badges and changed rules credit catches made before them
+func main() {
+	var (
+		batchSize = flag.Int("batch", 100, "Number of users to load per batch")
+		pageSize  = flag.Int("page", 1000, "Number of a user's catches to load at a time")
+		dryRun    = flag.Bool("dry-run", false, "Report changes without writing them")
+		diff      = flag.Bool("diff", false, "Print each changed user's stats and badge changes")
+		revoke    = flag.Bool("revoke-badges", false, "Take back earned badges that are no longer complete")
+		userIDs   []uuid.UUID
+	)
+	flag.Func("user", "Only recompute this user; repeat or separate IDs with commas", func(value string) error {
+		for _, part := range strings.Split(value, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(part))
+			if err != nil {
+				return fmt.Errorf("invalid user ID %q", part)
+			}
+			userIDs = append(userIDs, id)
+		}
+		return nil
+	})
+	flag.Parse()
+
+	if *batchSize < 1 || *pageSize < 1 {
+		log.Fatal("-batch and -page must be at least 1")
+	}
+
+	config.LoadConfig()
+	config.ConnectDatabase()
+
+	userRepo := repositories.NewUserRepository()
+	catchRepo := repositories.NewAnimalCatchRepository()
+	badgeRepo := repositories.NewBadgeRepository()
+	statsRepo := repositories.NewUserStatsRepository()
+	// Badges are awarded silently; nobody is listening for events here
+	silent := events.NewLocalBus()
+	badgeEngine := services.NewBadgeEngine(catchRepo, badgeRepo, statsRepo, repositories.NewSpeciesRepository(), silent)
+	streakService := services.NewStreakService(userRepo, catchRepo, statsRepo, silent, services.StreakConfig{
+		Grace:      config.AppConfig.StreakGrace,
+		MaxFreezes: config.AppConfig.MaxStreakFreezes,
+	})
+	recomputeService := services.NewRecomputeService(catchRepo, repositories.NewCatchInteractionRepository(), repositories.NewFollowRepository(), statsRepo, badgeRepo, badgeEngine, streakService)
+
+	options := services.RecomputeOptions{
+		DryRun:       *dryRun,
+		RevokeBadges: *revoke,
+		PageSize:     *pageSize,
+	}
+
+	var users, changed, catches, awarded, revoked int
+	recompute := func(userID uuid.UUID) {
+		result, err := recomputeService.RecomputeUser(userID, options)
+		if err != nil {
+			log.Fatalf("Failed to recompute user %s: %v", userID, err)
+		}
+		users++
+		catches += result.Catches
+		awarded += len(result.Awarded)
+		revoked += len(result.Revoked)
+		if !result.Changed() {
+			return
+		}
+		changed++
+		if *diff {
+			printResult(result)
+		}
+	}
+
+	if len(userIDs) > 0 {
+		for _, userID := range userIDs {
+			if _, err := userRepo.FindByID(userID); err != nil {
+				log.Fatalf("Failed to load user %s: %v", userID, err)
+			}
+			recompute(userID)
+		}
+	} else {
+		lastID := uuid.Nil
+		for {
+			batch, err := userRepo.GetIDsAfter(lastID, *batchSize)
+			if err != nil {
+				log.Fatalf("Failed to load users: %v", err)
+			}
+			if len(batch) == 0 {
+				break
+			}
+			for _, userID := range batch {
+				recompute(userID)
+			}
+			lastID = batch[len(batch)-1]
+			log.Printf("Recomputed %d users so far", users)
+		}
+	}
+
+	mode := "Updated"
+	if *dryRun {
+		mode = "Would update"
+	}
+	fmt.Printf("Replayed %d catches of %d users. %s %d users, awarding %d badges and revoking %d.\n", catches, users, mode, changed, awarded, revoked)
+}
+
+func printResult(result *services.RecomputeResult) {
+	fmt.Printf("%s:\n", result.UserID)
+	for _, change := range result.StatChanges {
+		fmt.Printf("  %s: %v -> %v\n", change.Field, change.Before, change.After)
+	}
+	for _, badge := range result.Awarded {
+		fmt.Printf("  + %s\n", badge.Name)
+	}
+	for _, badge := range result.Revoked {
+		fmt.Printf("  - %s\n", badge.Name)
+	}
+	if result.ProgressChanged > 0 {
+		fmt.Printf("  progress changed on %d badges\n", result.ProgressChanged)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/services"
+)
+
+// Covers the days users missed with their streak freezes, or ends their
+// streaks, once those days are over in the users' time zones. Meant to run on
+// a schedule, such as hourly from cron, so each time zone is reached soon after
+// its grace period ends.
+func main() {
+	batchSize := flag.Int("batch", 500, "Number of users to load per batch")
+	flag.Parse()
+
+	if *batchSize < 1 {
+		log.Fatal("-batch must be at least 1")
+	}
+
+	config.LoadConfig()
+	config.ConnectDatabase()
+
+	// Milestones are only reached by catching something; nothing is announced here
+	streakService := services.NewStreakService(repositories.NewUserRepository(), repositories.NewAnimalCatchRepository(), repositories.NewUserStatsRepository(), events.NewLocalBus(), services.StreakConfig{
+		Grace:      config.AppConfig.StreakGrace,
+		MaxFreezes: config.AppConfig.MaxStreakFreezes,
+	})
+
+	started := time.Now()
+	result, err := streakService.ResetBroken(*batchSize)
+	if err != nil {
+		log.Fatalf("Failed to reset streaks: %v", err)
+	}
+	fmt.Printf("Checked %d streaks: %d kept by freezes, %d ended, in %s.\n", result.Checked, result.Frozen, result.Broken, time.Since(started).Round(time.Millisecond))
+}
@@ -23,16 +23,31 @@ func ConnectDatabase() {
 		&models.Location{},
 		&models.Hotspot{},
 		&models.UserLocationHistory{},
+		&models.Photo{},
 		&models.AnimalCatch{},
 		&models.CatchComment{},
 		&models.CatchLike{},
 		&models.Badge{},
 		&models.UserBadge{},
 		&models.UserStats{},
+		&models.Follow{},
+		&models.FeedItem{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.DeviceToken{},
+		&models.IdempotencyKey{},
+		&models.StreakFreeze{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	// Retried on the next start; the badges keep their old columns until then
+	if err := migrateLegacyBadgeRules(DB); err != nil {
+		log.Println("Failed to migrate badge rules:", err)
+	}
+	if err := migrateStreakDates(DB); err != nil {
+		log.Fatal("Failed to migrate streak dates:", err)
+	}
 
 	log.Println("Database connected and migrated successfully")
 }
\ No newline at end of file
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/seeds"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// legacyBadgeColumns held what it took to earn a badge before badges had rules
+var legacyBadgeColumns = []string{
+	"required_count", "required_species", "required_location_type", "required_rarity", "required_days",
+}
+
+// legacyBadge is a badge as stored before badges had rules
+type legacyBadge struct {
+	ID                   uuid.UUID
+	Name                 string
+	BadgeType            models.BadgeType
+	RequiredCount        *int
+	RequiredSpecies      *string // JSON array of species IDs
+	RequiredLocationType *string
+	RequiredRarity       *string
+	RequiredDays         *int
+}
+
+// migrateLegacyBadgeRules gives badges stored with the old requirement columns
+// the rule that earns them the same way, then drops those columns. Seeded
+// badges take the rule they are seeded with now, which reads them as they were
+// meant; the columns alone can't say what makes a Night Owl. Badges no rule
+// can express are deactivated and logged with their old requirements, so an
+// admin can give them a rule and turn them back on. It does nothing once the
+// columns are gone.
+func migrateLegacyBadgeRules(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Badge{}, "required_count") {
+		return nil
+	}
+
+	seeded := make(map[string]*models.BadgeRule)
+	for _, badge := range seeds.GetBadgeSeeds() {
+		seeded[badge.Name] = badge.Rule
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var badges []legacyBadge
+		err := tx.Raw("SELECT id, name, badge_type, required_count, array_to_json(required_species)::text AS required_species, " +
+			"required_location_type, required_rarity, required_days FROM badges WHERE rule IS NULL").
+			Scan(&badges).Error
+		if err != nil {
+			return err
+		}
+
+		converted := 0
+		for _, badge := range badges {
+			rule := seeded[badge.Name]
+			if rule == nil {
+				if rule, err = badge.rule(); err != nil {
+					log.Printf("Failed to read the requirements of badge %s: %v", badge.Name, err)
+				}
+			}
+			if rule == nil {
+				log.Printf("No rule can express the requirements of badge %s (%s); deactivating it until it is given one",
+					badge.Name, badge.requirements())
+				err = tx.Model(&models.Badge{ID: badge.ID}).Update("is_active", false).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err = tx.Model(&models.Badge{ID: badge.ID}).Select("rule").Updates(&models.Badge{Rule: rule}).Error
+			if err != nil {
+				return err
+			}
+			converted++
+		}
+
+		for _, column := range legacyBadgeColumns {
+			if err := tx.Migrator().DropColumn(&models.Badge{}, column); err != nil {
+				return err
+			}
+		}
+		log.Printf("Converted the requirements of %d of %d badges into rules", converted, len(badges))
+		return nil
+	})
+}
+
+// rule returns the rule equivalent to the badge's requirement columns, or nil
+// if they don't say what earns it
+func (b *legacyBadge) rule() (*models.BadgeRule, error) {
+	var speciesIDs []string
+	if b.RequiredSpecies != nil {
+		if err := json.Unmarshal([]byte(*b.RequiredSpecies), &speciesIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	// The target was the same for every type
+	target := 1
+	switch {
+	case b.BadgeType == models.BadgeTypeStreak && b.RequiredDays != nil:
+		target = *b.RequiredDays
+	case b.RequiredCount != nil:
+		target = *b.RequiredCount
+	case len(speciesIDs) > 0:
+		target = len(speciesIDs)
+	case b.BadgeType == models.BadgeTypeSeasonal:
+		target = 4
+	}
+	target = max(target, 1)
+
+	require := func(measure models.RuleMeasure, where *models.RuleCondition) *models.BadgeRule {
+		return &models.BadgeRule{Requirements: []models.RuleRequirement{{Count: measure, Where: where, Target: target}}}
+	}
+
+	switch b.BadgeType {
+	case models.BadgeTypeSpecies:
+		if len(speciesIDs) > 0 {
+			return require(models.MeasureSpecies, fieldIn(models.FieldSpeciesID, speciesIDs...)), nil
+		}
+		return require(models.MeasureSpecies, nil), nil
+
+	case models.BadgeTypeCount:
+		return require(models.MeasureCatches, nil), nil
+
+	case models.BadgeTypeRarity:
+		if !notEmpty(b.RequiredRarity) {
+			return nil, nil
+		}
+		return require(models.MeasureCatches, fieldIs(models.FieldSpeciesRarity, *b.RequiredRarity)), nil
+
+	case models.BadgeTypeLocation:
+		if notEmpty(b.RequiredLocationType) {
+			return require(models.MeasureCatches, fieldIs(models.FieldLocationType, *b.RequiredLocationType)), nil
+		}
+		return require(models.MeasureCountries, nil), nil
+
+	case models.BadgeTypeStreak:
+		return require(models.MeasureLongestStreak, nil), nil
+
+	case models.BadgeTypeSeasonal:
+		return require(models.MeasureSeasons, nil), nil
+	}
+	return nil, nil
+}
+
+// requirements describes the badge's requirement columns for the log
+func (b *legacyBadge) requirements() string {
+	parts := []string{"type " + string(b.BadgeType)}
+	if b.RequiredCount != nil {
+		parts = append(parts, fmt.Sprintf("count %d", *b.RequiredCount))
+	}
+	if notEmpty(b.RequiredSpecies) {
+		parts = append(parts, "species "+*b.RequiredSpecies)
+	}
+	if notEmpty(b.RequiredLocationType) {
+		parts = append(parts, "location type "+*b.RequiredLocationType)
+	}
+	if notEmpty(b.RequiredRarity) {
+		parts = append(parts, "rarity "+*b.RequiredRarity)
+	}
+	if b.RequiredDays != nil {
+		parts = append(parts, fmt.Sprintf("days %d", *b.RequiredDays))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func notEmpty(value *string) bool {
+	return value != nil && *value != ""
+}
+
+func fieldIs(field models.RuleField, value string) *models.RuleCondition {
+	return &models.RuleCondition{Field: field, Op: models.OpEq, Value: value}
+}
+
+func fieldIn(field models.RuleField, values ...string) *models.RuleCondition {
+	list := make([]interface{}, len(values))
+	for i, value := range values {
+		list[i] = value
+	}
+	return &models.RuleCondition{Field: field, Op: models.OpIn, Value: list}
+}
+
+// migrateStreakDates starts the streak day of stats kept before freezes from
+// the last catch day, so running streaks carry on. It does nothing once every
+// user who has caught something has one.
+func migrateStreakDates(db *gorm.DB) error {
+	return db.Model(&models.UserStats{}).
+		Where("last_streak_date IS NULL AND last_catch_date IS NOT NULL").
+		UpdateColumn("last_streak_date", gorm.Expr("last_catch_date")).Error
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type BadgeAdminController struct {
+	badgeAdminService services.BadgeAdminService
+}
+
+func NewBadgeAdminController(badgeAdminService services.BadgeAdminService) *BadgeAdminController {
+	return &BadgeAdminController{
+		badgeAdminService: badgeAdminService,
+	}
+}
+
+// ListBadges godoc
+// @Summary List every badge
+// @Description All badges with their rules, including inactive and secret ones
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/admin/badges [get]
+func (bc *BadgeAdminController) ListBadges(c *gin.Context) {
+	badges, err := bc.badgeAdminService.List()
+	if err != nil {
+		respondBadgeAdminError(c, "Failed to fetch badges", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    badges,
+	})
+}
+
+// CreateBadge godoc
+// @Summary Create a badge
+// @Description Create a badge earned by its rule. Users' earlier catches count once recompute-stats runs.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.BadgeRequest true "Badge"
+// @Success 201 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/admin/badges [post]
+func (bc *BadgeAdminController) CreateBadge(c *gin.Context) {
+	var req models.BadgeRequest
+	if !bindBadgeRequest(c, &req) {
+		return
+	}
+
+	badge, err := bc.badgeAdminService.Create(&req)
+	if err != nil {
+		respondBadgeAdminError(c, "Failed to create badge", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    badge,
+	})
+}
+
+// UpdateBadge godoc
+// @Summary Update a badge
+// @Description Replace everything about a badge, including its rule
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Badge ID"
+// @Param request body models.BadgeRequest true "Badge"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/admin/badges/{id} [put]
+func (bc *BadgeAdminController) UpdateBadge(c *gin.Context) {
+	badgeID, ok := parseUUIDParam(c, "id", "Invalid badge ID format")
+	if !ok {
+		return
+	}
+
+	var req models.BadgeRequest
+	if !bindBadgeRequest(c, &req) {
+		return
+	}
+
+	badge, err := bc.badgeAdminService.Update(badgeID, &req)
+	if err != nil {
+		respondBadgeAdminError(c, "Failed to update badge", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    badge,
+	})
+}
+
+// DeactivateBadge godoc
+// @Summary Deactivate a badge
+// @Description Stop a badge from being listed or earned. Users who earned it keep it.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Badge ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/admin/badges/{id} [delete]
+func (bc *BadgeAdminController) DeactivateBadge(c *gin.Context) {
+	badgeID, ok := parseUUIDParam(c, "id", "Invalid badge ID format")
+	if !ok {
+		return
+	}
+
+	badge, err := bc.badgeAdminService.Deactivate(badgeID)
+	if err != nil {
+		respondBadgeAdminError(c, "Failed to deactivate badge", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    badge,
+	})
+}
+
+// ValidateRule godoc
+// @Summary Validate a badge rule
+// @Description Check a badge rule without saving it. An invalid rule is reported with the path of the first problem.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.BadgeRuleRequest true "Rule"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Router /api/admin/badges/rules/validate [post]
+func (bc *BadgeAdminController) ValidateRule(c *gin.Context) {
+	var req models.BadgeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{"valid": true}
+	if err := bc.badgeAdminService.ValidateRule(req.Rule); err != nil {
+		data = gin.H{"valid": false, "error": err}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// EvaluateRule godoc
+// @Summary Evaluate a badge rule for a user
+// @Description Measure where a user stands on each requirement of a rule, without saving anything
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.EvaluateBadgeRuleRequest true "Rule and user"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/admin/badges/rules/evaluate [post]
+func (bc *BadgeAdminController) EvaluateRule(c *gin.Context) {
+	var req models.EvaluateBadgeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	progress, err := bc.badgeAdminService.EvaluateRule(req.UserID, req.Rule)
+	if err != nil {
+		respondBadgeAdminError(c, "Failed to evaluate badge rule", err)
+		return
+	}
+
+	met := true
+	for _, requirement := range progress {
+		met = met && requirement.Met
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"met":          met,
+			"requirements": progress,
+		},
+	})
+}
+
+func bindBadgeRequest(c *gin.Context, req *models.BadgeRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func respondBadgeAdminError(c *gin.Context, message string, err error) {
+	var ruleErr *models.RuleError
+	switch {
+	case errors.As(err, &ruleErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid badge rule",
+			"details": ruleErr,
+		})
+	case errors.Is(err, services.ErrBadgeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Badge not found"})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrBadgeNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anidex/backend/internal/services"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BadgeController struct {
+	badgeService services.BadgeService
+}
+
+func NewBadgeController(badgeService services.BadgeService) *BadgeController {
+	return &BadgeController{
+		badgeService: badgeService,
+	}
+}
+
+type ShowcaseRequest struct {
+	BadgeIDs []uuid.UUID `json:"badge_ids" binding:"max=10"`
+}
+
+// GetBadges godoc
+// @Summary List badges
+// @Description All badges that can be earned. Secret badges are only listed once you have earned them.
+// @Tags badges
+// @Produce json
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/badges [get]
+func (bc *BadgeController) GetBadges(c *gin.Context) {
+	viewerID, _ := utils.GetUserIDFromContext(c)
+
+	badges, err := bc.badgeService.Catalog(viewerID)
+	if err != nil {
+		respondBadgeError(c, "Failed to fetch badges", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    badges,
+	})
+}
+
+// GetMyBadges godoc
+// @Summary Get my badge progress
+// @Description Your progress towards every badge, including the ones you have earned and whether they are shown on your profile
+// @Tags badges
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/me/badges [get]
+func (bc *BadgeController) GetMyBadges(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	progress, err := bc.badgeService.Progress(userID)
+	if err != nil {
+		respondBadgeError(c, "Failed to fetch badge progress", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// UpdateShowcase godoc
+// @Summary Choose the badges shown on my profile
+// @Description Show the given earned badges on your profile, in this order, and hide the others. Send an empty list to hide them all.
+// @Tags badges
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ShowcaseRequest true "Badges to show"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/me/badges/showcase [put]
+func (bc *BadgeController) UpdateShowcase(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req ShowcaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	showcase, err := bc.badgeService.SetShowcase(userID, req.BadgeIDs)
+	if err != nil {
+		respondBadgeError(c, "Failed to update badge showcase", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    showcase,
+	})
+}
+
+// GetUserShowcase godoc
+// @Summary Get a user's badge showcase
+// @Description The earned badges a user shows on their profile, in their chosen order
+// @Tags badges
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/{id}/badges [get]
+func (bc *BadgeController) GetUserShowcase(c *gin.Context) {
+	userID, ok := parseUUIDParam(c, "id", "Invalid user ID format")
+	if !ok {
+		return
+	}
+
+	showcase, err := bc.badgeService.Showcase(userID)
+	if err != nil {
+		respondBadgeError(c, "Failed to fetch badge showcase", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    showcase,
+	})
+}
+
+func respondBadgeError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrBadgeNotEarned),
+		errors.Is(err, services.ErrShowcaseTooLarge),
+		errors.Is(err, services.ErrShowcaseDuplicateIDs):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
@@ -1,53 +1,120 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/anidex/backend/internal/models"
 	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/services"
 	"github.com/anidex/backend/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type CatchController struct {
+	userRepo     repositories.UserRepository
 	catchRepo    *repositories.AnimalCatchRepository
-	speciesRepo  *repositories.SpeciesRepository
-	locationRepo *repositories.LocationRepository
+	catchService  services.CatchService
+	followService services.FollowService
 }
 
-func NewCatchController(catchRepo *repositories.AnimalCatchRepository, speciesRepo *repositories.SpeciesRepository, locationRepo *repositories.LocationRepository) *CatchController {
+func NewCatchController(userRepo repositories.UserRepository, catchRepo *repositories.AnimalCatchRepository, catchService services.CatchService, followService services.FollowService) *CatchController {
 	return &CatchController{
-		catchRepo:    catchRepo,
-		speciesRepo:  speciesRepo,
-		locationRepo: locationRepo,
+		userRepo:      userRepo,
+		catchRepo:     catchRepo,
+		catchService:  catchService,
+		followService: followService,
 	}
 }
 
 type CreateCatchRequest struct {
-	SpeciesID    string  `json:"species_id" binding:"required"`
-	Latitude     float64 `json:"latitude" binding:"required"`
-	Longitude    float64 `json:"longitude" binding:"required"`
-	UserPhotoURL string  `json:"user_photo_url" binding:"required"`
-	UserNotes    string  `json:"user_notes"`
-	UserRating   *int    `json:"user_rating"`
-	Weather      string  `json:"weather"`
-	Temperature  *float64 `json:"temperature"`
+	ID           string     `json:"id"` // Optional client-generated UUID; retries with the same ID are not duplicated
+	SpeciesID    string     `json:"species_id" binding:"required"`
+	Latitude     float64    `json:"latitude" binding:"required"`
+	Longitude    float64    `json:"longitude" binding:"required"`
+	PhotoID      string     `json:"photo_id"`       // Photo uploaded through /api/photos
+	UserPhotoURL string     `json:"user_photo_url"` // Alternatively the URL of such a photo
+	UserNotes    string     `json:"user_notes"`
+	UserRating   *int       `json:"user_rating"`
+	Weather      string     `json:"weather"`
+	Temperature  *float64   `json:"temperature"`
+	CaughtAt     *time.Time `json:"caught_at"` // When the catch happened, if recorded on the device
+	Visibility   string     `json:"visibility" binding:"omitempty,oneof=public location_hidden followers private"` // Defaults to the user's profile setting
+}
+
+// toInput validates the IDs in the request and converts it for the catch service
+func (req *CreateCatchRequest) toInput() (services.CreateCatchInput, error) {
+	input := services.CreateCatchInput{
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		PhotoURL:    req.UserPhotoURL,
+		UserNotes:   req.UserNotes,
+		UserRating:  req.UserRating,
+		Weather:     req.Weather,
+		Temperature: req.Temperature,
+		CaughtAt:    req.CaughtAt,
+		Visibility:  models.CatchVisibility(req.Visibility),
+	}
+
+	speciesID, err := uuid.Parse(req.SpeciesID)
+	if err != nil {
+		return input, errors.New("invalid species ID format")
+	}
+	input.SpeciesID = speciesID
+
+	if req.ID != "" {
+		id, err := uuid.Parse(req.ID)
+		if err != nil {
+			return input, errors.New("invalid catch ID format")
+		}
+		input.ID = &id
+	}
+
+	if req.PhotoID != "" {
+		photoID, err := uuid.Parse(req.PhotoID)
+		if err != nil {
+			return input, errors.New("invalid photo ID format")
+		}
+		input.PhotoID = &photoID
+	}
+
+	return input, nil
+}
+
+// catchErrorStatus maps catch service errors onto HTTP status codes
+func catchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrSpeciesNotFound), errors.Is(err, services.ErrCaughtAtInFuture):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrCatchNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCatchNotOwned):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrDuplicatePhoto), errors.Is(err, services.ErrCatchIDConflict), errors.Is(err, services.ErrCatchNotEditable):
+		return http.StatusConflict
+	default:
+		return photoErrorStatus(err)
+	}
 }
 
 // CreateCatch godoc
 // @Summary Create a new animal catch
-// @Description Create a new animal catch record with photo and location
+// @Description Create a new animal catch record with photo and location. The capture time, altitude and GPS accuracy are read from the photo's EXIF data when present, and the catch is flagged if the photo GPS disagrees with the submitted coordinates. Send a client-generated id (and optionally an Idempotency-Key header) to make retries safe.
 // @Tags catches
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param Idempotency-Key header string false "Replays the stored response for a repeated request"
 // @Param catch body CreateCatchRequest true "Catch data"
+// @Success 200 {object} map[string]interface{} "already created"
 // @Success 201 {object} map[string]interface{} "success"
 // @Failure 400 {object} map[string]interface{} "error"
 // @Failure 401 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
 // @Failure 500 {object} map[string]interface{} "error"
 // @Router /api/catches [post]
 func (cc *CatchController) CreateCatch(c *gin.Context) {
@@ -69,95 +136,133 @@ func (cc *CatchController) CreateCatch(c *gin.Context) {
 		return
 	}
 
-	// Parse species ID
-	speciesID, err := uuid.Parse(req.SpeciesID)
+	input, err := req.toInput()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid species ID format",
+			"error": "Invalid request data",
+			"details": err.Error(),
 		})
 		return
 	}
 
-	// Verify species exists
-	species, err := cc.speciesRepo.GetByID(speciesID)
+	animalCatch, created, err := cc.catchService.Create(userID, input)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Species not found",
+		c.JSON(catchErrorStatus(err), gin.H{
+			"error": "Failed to create catch",
+			"details": err.Error(),
 		})
 		return
 	}
 
-	// Create or find location
-	location := &models.Location{
-		Latitude:  req.Latitude,
-		Longitude: req.Longitude,
-	}
-	
-	// Try to find existing nearby location (within 100m)
-	existingLocation, err := cc.locationRepo.FindNearby(req.Latitude, req.Longitude, 0.1)
-	if err == nil && existingLocation != nil {
-		location = existingLocation
-	} else {
-		// Create new location
-		if err := cc.locationRepo.Create(location); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to create location",
-			})
-			return
-		}
+	if !created {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": animalCatch,
+			"message": "Animal catch already exists",
+		})
+		return
 	}
 
-	// Determine time of day
-	now := time.Now()
-	timeOfDay := models.GetTimeOfDayFromHour(now.Hour())
-
-	// Create animal catch
-	animalCatch := &models.AnimalCatch{
-		UserID:            userID,
-		SpeciesID:         speciesID,
-		LocationID:        location.ID,
-		UserPhotoURL:      req.UserPhotoURL,
-		UserNotes:         req.UserNotes,
-		UserRating:        req.UserRating,
-		Weather:           models.WeatherCondition(req.Weather),
-		TimeOfDay:         timeOfDay,
-		Temperature:       req.Temperature,
-		CaughtAt:          now,
-		PointsAwarded:     species.CalculatePoints(),
-		IsPublic:          true,
-	}
-
-	// Check if this is user's first catch of this species
-	isFirstCatch, err := cc.catchRepo.IsFirstCatchForUser(userID, speciesID)
-	if err == nil {
-		animalCatch.IsFirstCatch = isFirstCatch
-		if isFirstCatch {
-			animalCatch.ComboMultiplier = 1.5 // Bonus for first catch
-		}
-	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data": animalCatch,
+		"message": "Animal catch created successfully",
+	})
+}
 
-	if err := cc.catchRepo.Create(animalCatch); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create catch",
+type SyncCatchesRequest struct {
+	Catches []CreateCatchRequest `json:"catches" binding:"required,min=1,max=100,dive"`
+}
+
+// SyncCatchResult is the outcome of one catch in a sync batch
+type SyncCatchResult struct {
+	ID     string              `json:"id"`
+	Status string              `json:"status"` // created, exists or error
+	Catch  *models.AnimalCatch `json:"catch,omitempty"`
+	Error  string              `json:"error,omitempty"`
+	Code   int                 `json:"code,omitempty"` // HTTP status the item would have had on its own
+}
+
+// SyncCatches godoc
+// @Summary Sync catches recorded offline
+// @Description Create many catches at once. Every catch needs a client-generated id, so replaying a batch never creates duplicates or awards points twice. Each item gets its own result; a failing item does not affect the others.
+// @Tags catches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Replays the stored response for a repeated request"
+// @Param request body SyncCatchesRequest true "Catches to sync"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Failure 422 {object} map[string]interface{} "error"
+// @Router /api/catches/sync [post]
+func (cc *CatchController) SyncCatches(c *gin.Context) {
+	var req SyncCatchesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	// Load relationships for response
-	animalCatch.Species = *species
-	animalCatch.Location = *location
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	results := make([]SyncCatchResult, 0, len(req.Catches))
+	counts := map[string]int{"created": 0, "exists": 0, "error": 0}
+
+	for i := range req.Catches {
+		item := &req.Catches[i]
+		result := SyncCatchResult{ID: item.ID}
+
+		input, err := item.toInput()
+		if err == nil && input.ID == nil {
+			err = errors.New("id is required when syncing")
+		}
+
+		var animalCatch *models.AnimalCatch
+		created := false
+		if err != nil {
+			err = fmt.Errorf("invalid request data: %w", err)
+			result.Code = http.StatusBadRequest
+		} else if animalCatch, created, err = cc.catchService.Create(userID, input); err != nil {
+			result.Code = catchErrorStatus(err)
+		}
+
+		switch {
+		case err != nil:
+			result.Status = "error"
+			result.Error = err.Error()
+		case created:
+			result.Status = "created"
+			result.Catch = animalCatch
+		default:
+			result.Status = "exists"
+			result.Catch = animalCatch
+		}
+
+		counts[result.Status]++
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": animalCatch,
-		"message": "Animal catch created successfully",
+		"data":    results,
+		"summary": counts,
 	})
 }
 
 // GetUserCatches godoc
 // @Summary Get user's animal catches
-// @Description Retrieve all animal catches for the authenticated user
+// @Description Retrieve all animal catches for the authenticated user, including photo renditions
 // @Tags catches
 // @Accept json
 // @Produce json
@@ -212,10 +317,11 @@ func (cc *CatchController) GetUserCatches(c *gin.Context) {
 
 // GetCatchById godoc
 // @Summary Get catch by ID
-// @Description Retrieve a specific animal catch by its ID
+// @Description Retrieve a specific animal catch by its ID, including the photo renditions and blurhash. Catches the caller may not see are reported as not found. The location is left out of catches whose owner hid it, and sightings of threatened or sensitive species only show a generalized point, except to their owner and moderators.
 // @Tags catches
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "Catch ID (UUID)"
 // @Success 200 {object} map[string]interface{} "success"
 // @Failure 400 {object} map[string]interface{} "error"
@@ -231,6 +337,8 @@ func (cc *CatchController) GetCatchById(c *gin.Context) {
 		return
 	}
 
+	viewer := currentViewer(c, cc.userRepo)
+
 	catch, err := cc.catchRepo.GetByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -239,8 +347,190 @@ func (cc *CatchController) GetCatchById(c *gin.Context) {
 		return
 	}
 
+	visible, err := cc.followService.CanViewCatch(viewer, catch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch catch",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Catch not found",
+		})
+		return
+	}
+	catch.RedactFor(viewer)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": catch,
 	})
 }
+
+type UpdateCatchRequest struct {
+	PhotoID      *string  `json:"photo_id"`
+	UserNotes    *string  `json:"user_notes"`
+	UserRating   *int     `json:"user_rating" binding:"omitempty,min=1,max=5"`
+	Weather      *string  `json:"weather"`
+	Temperature  *float64 `json:"temperature"`
+	Visibility   *string  `json:"visibility" binding:"omitempty,oneof=public location_hidden followers private"` // Can be changed at any time
+}
+
+// toInput validates the photo ID in the request and converts it for the catch service
+func (req *UpdateCatchRequest) toInput() (services.UpdateCatchInput, error) {
+	input := services.UpdateCatchInput{
+		UserNotes:   req.UserNotes,
+		UserRating:  req.UserRating,
+		Weather:     req.Weather,
+		Temperature: req.Temperature,
+	}
+	if req.Visibility != nil {
+		visibility := models.CatchVisibility(*req.Visibility)
+		input.Visibility = &visibility
+	}
+	if req.PhotoID != nil {
+		photoID, err := uuid.Parse(*req.PhotoID)
+		if err != nil {
+			return input, errors.New("invalid photo ID format")
+		}
+		input.PhotoID = &photoID
+	}
+	return input, nil
+}
+
+// UpdateCatch godoc
+// @Summary Update an animal catch
+// @Description Update your own animal catch while it is still pending verification. A new photo sends the catch through verification again. The visibility can also be changed afterwards.
+// @Tags catches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Catch ID (UUID)"
+// @Param catch body UpdateCatchRequest true "Fields to update"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/catches/{id} [put]
+// @Router /api/catches/{id} [patch]
+func (cc *CatchController) UpdateCatch(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid catch ID format",
+		})
+		return
+	}
+
+	var req UpdateCatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	input, err := req.toInput()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updated, err := cc.catchService.Update(userID, id, input)
+	if err != nil {
+		c.JSON(catchErrorStatus(err), gin.H{
+			"error": "Failed to update catch",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": updated,
+		"message": "Animal catch updated successfully",
+	})
+}
+
+// DeleteCatch godoc
+// @Summary Delete an animal catch
+// @Description Delete your own animal catch and roll back the points and location statistics it contributed
+// @Tags catches
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Catch ID (UUID)"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/catches/{id} [delete]
+func (cc *CatchController) DeleteCatch(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid catch ID format",
+		})
+		return
+	}
+
+	catch, err := cc.catchRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Catch not found",
+		})
+		return
+	}
+
+	if catch.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You can only delete your own catches",
+		})
+		return
+	}
+
+	if err := cc.catchService.Delete(catch.ID); err != nil {
+		if errors.Is(err, services.ErrCatchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Catch not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete catch",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Animal catch deleted successfully",
+	})
+}
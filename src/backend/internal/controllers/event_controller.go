@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultEventRadiusKm = 10.0
+	maxEventRadiusKm     = 50.0
+	// eventHeartbeat keeps idle streams from being closed by proxies
+	eventHeartbeat = 25 * time.Second
+)
+
+type EventController struct {
+	bus events.Bus
+}
+
+func NewEventController(bus events.Bus) *EventController {
+	return &EventController{
+		bus: bus,
+	}
+}
+
+// Stream godoc
+// @Summary Stream real-time events
+// @Description Server-sent events for the authenticated user: likes and comments on their catches, verification results, badges earned and follows. Send lat and lng to also receive public catches created within radius km. Each event's name is its type and its data is the JSON event. A comment line is sent every 25 seconds to keep the connection open.
+// @Tags events
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param lat query number false "Latitude"
+// @Param lng query number false "Longitude"
+// @Param radius query number false "Radius in km for nearby catches (max 50)" default(10)
+// @Success 200 {string} string "event stream"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Router /api/events/stream [get]
+func (ec *EventController) Stream(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	filter := events.Filter{UserID: userID}
+	if c.Query("lat") != "" || c.Query("lng") != "" {
+		lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+		lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+		if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "lat and lng must be given together as valid coordinates",
+			})
+			return
+		}
+		radius, err := strconv.ParseFloat(c.DefaultQuery("radius", "10"), 64)
+		if err != nil || radius <= 0 {
+			radius = defaultEventRadiusKm
+		}
+		filter.Position = &events.Position{Latitude: lat, Longitude: lng}
+		filter.RadiusKm = min(radius, maxEventRadiusKm)
+	}
+
+	sub := ec.bus.Subscribe(filter)
+	defer sub.Close()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
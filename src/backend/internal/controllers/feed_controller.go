@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/anidex/backend/internal/services"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type FeedController struct {
+	feedService services.FeedService
+}
+
+func NewFeedController(feedService services.FeedService) *FeedController {
+	return &FeedController{
+		feedService: feedService,
+	}
+}
+
+// GetFeed godoc
+// @Summary Get the home feed
+// @Description Catches by the accounts you follow, rare catches and notable catches near you, ranked by recency and how interesting they are. Send lat and lng to use your current position; otherwise your last known one is used.
+// @Tags feed
+// @Produce json
+// @Security BearerAuth
+// @Param lat query number false "Latitude"
+// @Param lng query number false "Longitude"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Number of items per page" default(20)
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/feed [get]
+func (fc *FeedController) GetFeed(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	cursor, limit, ok := parseCursorPage(c)
+	if !ok {
+		return
+	}
+
+	var position *services.FeedPosition
+	if c.Query("lat") != "" || c.Query("lng") != "" {
+		lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+		lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+		if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "lat and lng must be given together as valid coordinates",
+			})
+			return
+		}
+		position = &services.FeedPosition{Latitude: lat, Longitude: lng}
+	}
+
+	entries, next, err := fc.feedService.Get(userID, position, cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch feed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       entries,
+		"pagination": cursorPagination(limit, next),
+	})
+}
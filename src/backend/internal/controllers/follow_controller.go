@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/services"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type FollowController struct {
+	userRepo      repositories.UserRepository
+	followService services.FollowService
+}
+
+func NewFollowController(userRepo repositories.UserRepository, followService services.FollowService) *FollowController {
+	return &FollowController{
+		userRepo:      userRepo,
+		followService: followService,
+	}
+}
+
+// FollowUser godoc
+// @Summary Follow a user
+// @Description Follow a user. Following a private account sends a request that they have to approve; the returned status is then "pending". Following someone you already follow changes nothing.
+// @Tags follows
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/{id}/follow [post]
+func (fc *FollowController) FollowUser(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	followeeID, ok := parseUUIDParam(c, "id", "Invalid user ID format")
+	if !ok {
+		return
+	}
+
+	follow, err := fc.followService.Follow(userID, followeeID)
+	if err != nil {
+		respondFollowError(c, "Failed to follow user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    follow,
+	})
+}
+
+// UnfollowUser godoc
+// @Summary Unfollow a user
+// @Description Stop following a user, or cancel a pending follow request
+// @Tags follows
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/{id}/follow [delete]
+func (fc *FollowController) UnfollowUser(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	followeeID, ok := parseUUIDParam(c, "id", "Invalid user ID format")
+	if !ok {
+		return
+	}
+
+	if err := fc.followService.Unfollow(userID, followeeID); err != nil {
+		respondFollowError(c, "Failed to unfollow user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "User unfollowed successfully",
+	})
+}
+
+// GetRelationship godoc
+// @Summary Get your relationship with a user
+// @Description Whether you follow the user (or have requested to), whether they follow you, and whether the follow is mutual
+// @Tags follows
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/{id}/relationship [get]
+func (fc *FollowController) GetRelationship(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	otherID, ok := parseUUIDParam(c, "id", "Invalid user ID format")
+	if !ok {
+		return
+	}
+
+	relationship, err := fc.followService.Relationship(userID, otherID)
+	if err != nil {
+		respondFollowError(c, "Failed to fetch relationship", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    relationship,
+	})
+}
+
+// GetFollowers godoc
+// @Summary Get a user's followers
+// @Description List the followers of a user, most recent first. Each entry tells whether the user follows them back. The lists of private accounts are only shown to their followers.
+// @Tags follows
+// @Produce json
+// @Param id path string true "User ID"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Number of items per page" default(20)
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/{id}/followers [get]
+func (fc *FollowController) GetFollowers(c *gin.Context) {
+	fc.listFollows(c, fc.followService.Followers)
+}
+
+// GetFollowing godoc
+// @Summary Get the accounts a user follows
+// @Description List the accounts a user follows, most recent first. Each entry tells whether they follow the user back. The lists of private accounts are only shown to their followers.
+// @Tags follows
+// @Produce json
+// @Param id path string true "User ID"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Number of items per page" default(20)
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/{id}/following [get]
+func (fc *FollowController) GetFollowing(c *gin.Context) {
+	fc.listFollows(c, fc.followService.Following)
+}
+
+func (fc *FollowController) listFollows(c *gin.Context, list func(viewer models.Viewer, userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]services.FollowEntry, *pagination.Cursor, error)) {
+	userID, ok := parseUUIDParam(c, "id", "Invalid user ID format")
+	if !ok {
+		return
+	}
+	cursor, limit, ok := parseCursorPage(c)
+	if !ok {
+		return
+	}
+
+	entries, next, err := list(currentViewer(c, fc.userRepo), userID, cursor, limit)
+	if err != nil {
+		respondFollowError(c, "Failed to fetch follows", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       entries,
+		"pagination": cursorPagination(limit, next),
+	})
+}
+
+// GetFollowRequests godoc
+// @Summary Get pending follow requests
+// @Description List the follow requests awaiting your approval, most recent first
+// @Tags follows
+// @Produce json
+// @Security BearerAuth
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Number of items per page" default(20)
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/me/follow-requests [get]
+func (fc *FollowController) GetFollowRequests(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	cursor, limit, ok := parseCursorPage(c)
+	if !ok {
+		return
+	}
+
+	entries, next, err := fc.followService.PendingRequests(userID, cursor, limit)
+	if err != nil {
+		respondFollowError(c, "Failed to fetch follow requests", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       entries,
+		"pagination": cursorPagination(limit, next),
+	})
+}
+
+// ApproveFollowRequest godoc
+// @Summary Approve a follow request
+// @Tags follows
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "ID of the user who requested to follow you"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/me/follow-requests/{userId}/approve [post]
+func (fc *FollowController) ApproveFollowRequest(c *gin.Context) {
+	fc.actOnFollower(c, fc.followService.ApproveRequest, "Failed to approve follow request", "Follow request approved")
+}
+
+// RejectFollowRequest godoc
+// @Summary Reject a follow request
+// @Tags follows
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "ID of the user who requested to follow you"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/me/follow-requests/{userId} [delete]
+func (fc *FollowController) RejectFollowRequest(c *gin.Context) {
+	fc.actOnFollower(c, fc.followService.RejectRequest, "Failed to reject follow request", "Follow request rejected")
+}
+
+// RemoveFollower godoc
+// @Summary Remove a follower
+// @Description Make a user stop following you
+// @Tags follows
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "ID of the follower"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/me/followers/{userId} [delete]
+func (fc *FollowController) RemoveFollower(c *gin.Context) {
+	fc.actOnFollower(c, fc.followService.RemoveFollower, "Failed to remove follower", "Follower removed")
+}
+
+// actOnFollower runs action for the authenticated user and the follower in the
+// userId path parameter
+func (fc *FollowController) actOnFollower(c *gin.Context, action func(userID, followerID uuid.UUID) error, failure, success string) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	followerID, ok := parseUUIDParam(c, "userId", "Invalid user ID format")
+	if !ok {
+		return
+	}
+
+	if err := action(userID, followerID); err != nil {
+		respondFollowError(c, failure, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": success,
+	})
+}
+
+// parseCursorPage reads the cursor and limit query parameters, responding with
+// 400 if the cursor is malformed
+func parseCursorPage(c *gin.Context) (*pagination.Cursor, int, bool) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	cursor, err := pagination.Decode(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid cursor",
+		})
+		return nil, 0, false
+	}
+	return cursor, limit, true
+}
+
+func cursorPagination(limit int, next *pagination.Cursor) gin.H {
+	page := gin.H{
+		"limit":    limit,
+		"has_more": next != nil,
+	}
+	if next != nil {
+		page["next_cursor"] = next.Encode()
+	}
+	return page
+}
+
+func respondFollowError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrFollowRequestNotFound),
+		errors.Is(err, services.ErrFollowerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotFollowSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFollowListHidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type InteractionController struct {
+	userRepo           repositories.UserRepository
+	interactionService services.InteractionService
+}
+
+func NewInteractionController(userRepo repositories.UserRepository, interactionService services.InteractionService) *InteractionController {
+	return &InteractionController{
+		userRepo:           userRepo,
+		interactionService: interactionService,
+	}
+}
+
+type CommentRequest struct {
+	Content string `json:"content" binding:"required,max=1000"`
+}
+
+// LikeCatch godoc
+// @Summary Like a catch
+// @Description Like a catch. Liking a catch you already like changes nothing.
+// @Tags interactions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Catch ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/catches/{id}/like [post]
+func (ic *InteractionController) LikeCatch(c *gin.Context) {
+	catchID, ok := parseUUIDParam(c, "id", "Invalid catch ID format")
+	if !ok {
+		return
+	}
+
+	state, err := ic.interactionService.Like(currentViewer(c, ic.userRepo), catchID)
+	if err != nil {
+		respondInteractionError(c, "Failed to like catch", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    state,
+	})
+}
+
+// UnlikeCatch godoc
+// @Summary Unlike a catch
+// @Description Remove your like from a catch. Unliking a catch you don't like changes nothing.
+// @Tags interactions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Catch ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/catches/{id}/like [delete]
+func (ic *InteractionController) UnlikeCatch(c *gin.Context) {
+	catchID, ok := parseUUIDParam(c, "id", "Invalid catch ID format")
+	if !ok {
+		return
+	}
+
+	state, err := ic.interactionService.Unlike(currentViewer(c, ic.userRepo), catchID)
+	if err != nil {
+		respondInteractionError(c, "Failed to unlike catch", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    state,
+	})
+}
+
+// GetComments godoc
+// @Summary Get comments on a catch
+// @Description Retrieve the comments on a catch, oldest first
+// @Tags interactions
+// @Produce json
+// @Param id path string true "Catch ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(20)
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/catches/{id}/comments [get]
+func (ic *InteractionController) GetComments(c *gin.Context) {
+	catchID, ok := parseUUIDParam(c, "id", "Invalid catch ID format")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	comments, total, err := ic.interactionService.ListComments(currentViewer(c, ic.userRepo), catchID, limit, offset)
+	if err != nil {
+		respondInteractionError(c, "Failed to fetch comments", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    comments,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// CreateComment godoc
+// @Summary Comment on a catch
+// @Description Add a comment to a catch
+// @Tags interactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Catch ID"
+// @Param comment body CommentRequest true "Comment"
+// @Success 201 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/catches/{id}/comments [post]
+func (ic *InteractionController) CreateComment(c *gin.Context) {
+	catchID, ok := parseUUIDParam(c, "id", "Invalid catch ID format")
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	comment, err := ic.interactionService.AddComment(currentViewer(c, ic.userRepo), catchID, req.Content)
+	if err != nil {
+		respondInteractionError(c, "Failed to create comment", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    comment,
+	})
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Change the content of your comment
+// @Tags interactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Catch ID"
+// @Param commentId path string true "Comment ID"
+// @Param comment body CommentRequest true "Comment"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/catches/{id}/comments/{commentId} [patch]
+func (ic *InteractionController) UpdateComment(c *gin.Context) {
+	catchID, ok := parseUUIDParam(c, "id", "Invalid catch ID format")
+	if !ok {
+		return
+	}
+	commentID, ok := parseUUIDParam(c, "commentId", "Invalid comment ID format")
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	comment, err := ic.interactionService.EditComment(currentViewer(c, ic.userRepo), catchID, commentID, req.Content)
+	if err != nil {
+		respondInteractionError(c, "Failed to update comment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    comment,
+	})
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Delete a comment. Comment authors and the owner of the catch can delete it.
+// @Tags interactions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Catch ID"
+// @Param commentId path string true "Comment ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/catches/{id}/comments/{commentId} [delete]
+func (ic *InteractionController) DeleteComment(c *gin.Context) {
+	catchID, ok := parseUUIDParam(c, "id", "Invalid catch ID format")
+	if !ok {
+		return
+	}
+	commentID, ok := parseUUIDParam(c, "commentId", "Invalid comment ID format")
+	if !ok {
+		return
+	}
+
+	if err := ic.interactionService.DeleteComment(currentViewer(c, ic.userRepo), catchID, commentID); err != nil {
+		respondInteractionError(c, "Failed to delete comment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Comment deleted successfully",
+	})
+}
+
+// parseUUIDParam reads a UUID path parameter, responding with 400 if it's malformed
+func parseUUIDParam(c *gin.Context, name, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": message,
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func respondInteractionError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrCatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Catch not found"})
+	case errors.Is(err, services.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+	case errors.Is(err, services.ErrCommentEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment cannot be empty"})
+	case errors.Is(err, services.ErrCommentEditForbidden),
+		errors.Is(err, services.ErrCommentDelForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/services"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type LeaderboardController struct {
+	leaderboardService services.LeaderboardService
+}
+
+func NewLeaderboardController(leaderboardService services.LeaderboardService) *LeaderboardController {
+	return &LeaderboardController{
+		leaderboardService: leaderboardService,
+	}
+}
+
+type LeaderboardRequest struct {
+	Metric  string `form:"metric" binding:"omitempty,oneof=points species streak"`
+	Scope   string `form:"scope" binding:"omitempty,oneof=global country city"`
+	Window  string `form:"window" binding:"omitempty,oneof=all_time month week"`
+	Country string `form:"country"`
+	City    string `form:"city"`
+}
+
+func (r *LeaderboardRequest) options() services.LeaderboardOptions {
+	return services.LeaderboardOptions{
+		Metric:  models.LeaderboardMetric(r.Metric),
+		Scope:   models.LeaderboardScope(r.Scope),
+		Window:  models.LeaderboardWindow(r.Window),
+		Country: r.Country,
+		City:    r.City,
+	}
+}
+
+// GetLeaderboard godoc
+// @Summary Get a leaderboard
+// @Description Users ranked by points, unique species or longest streak, over all time or the current month or week (UTC), globally or among users from a country or city. Users from a region are those who made most of their catches there. Country and city default to your own home region.
+// @Tags leaderboards
+// @Produce json
+// @Param metric query string false "points, species or streak" default(points)
+// @Param scope query string false "global, country or city" default(global)
+// @Param window query string false "all_time, month or week" default(all_time)
+// @Param country query string false "Country, for the country and city scopes"
+// @Param city query string false "City, for the city scope"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(20)
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/leaderboards [get]
+func (lc *LeaderboardController) GetLeaderboard(c *gin.Context) {
+	var req LeaderboardRequest
+	if !bindLeaderboardQuery(c, &req) {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	viewerID, _ := utils.GetUserIDFromContext(c)
+	board, err := lc.leaderboardService.Leaderboard(viewerID, req.options(), page, limit)
+	if err != nil {
+		respondLeaderboardError(c, "Failed to fetch leaderboard", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    board,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       board.Total,
+			"total_pages": (board.Total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetLeaderboardAroundMe godoc
+// @Summary Get my neighbors on a leaderboard
+// @Description Your entry on a leaderboard with the users ranked just above and below you. Entries is empty if you aren't on the board.
+// @Tags leaderboards
+// @Produce json
+// @Security BearerAuth
+// @Param metric query string false "points, species or streak" default(points)
+// @Param scope query string false "global, country or city" default(global)
+// @Param window query string false "all_time, month or week" default(all_time)
+// @Param country query string false "Country, for the country and city scopes"
+// @Param city query string false "City, for the city scope"
+// @Param radius query int false "Users to show on either side" default(5)
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/leaderboards/around-me [get]
+func (lc *LeaderboardController) GetLeaderboardAroundMe(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req LeaderboardRequest
+	if !bindLeaderboardQuery(c, &req) {
+		return
+	}
+	radius, _ := strconv.Atoi(c.DefaultQuery("radius", "5"))
+	if radius < 1 || radius > 25 {
+		radius = 5
+	}
+
+	board, err := lc.leaderboardService.AroundUser(userID, req.options(), radius)
+	if err != nil {
+		respondLeaderboardError(c, "Failed to fetch leaderboard", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    board,
+	})
+}
+
+func bindLeaderboardQuery(c *gin.Context, req *LeaderboardRequest) bool {
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func respondLeaderboardError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrLeaderboardRegionRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
@@ -9,12 +9,14 @@ import (
 )
 
 type LocationController struct {
+	userRepo     repositories.UserRepository
 	locationRepo *repositories.LocationRepository
 	catchRepo    *repositories.AnimalCatchRepository
 }
 
-func NewLocationController(locationRepo *repositories.LocationRepository, catchRepo *repositories.AnimalCatchRepository) *LocationController {
+func NewLocationController(userRepo repositories.UserRepository, locationRepo *repositories.LocationRepository, catchRepo *repositories.AnimalCatchRepository) *LocationController {
 	return &LocationController{
+		userRepo:     userRepo,
 		locationRepo: locationRepo,
 		catchRepo:    catchRepo,
 	}
@@ -81,10 +83,11 @@ func (lc *LocationController) GetNearbyLocations(c *gin.Context) {
 
 // GetLocationCatches godoc
 // @Summary Get catches at a specific location
-// @Description Retrieve all animal catches at a specific location
+// @Description Retrieve the animal catches at a specific location that the caller may see. Catches whose owner hid their location and sightings of threatened or sensitive species are not listed, except to their owner and moderators.
 // @Tags locations
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param lat query number true "Latitude"
 // @Param lng query number true "Longitude"
 // @Param page query int false "Page number" default(1)
@@ -141,7 +144,7 @@ func (lc *LocationController) GetLocationCatches(c *gin.Context) {
 		return
 	}
 
-	catches, total, err := lc.catchRepo.GetByLocationID(location.ID, limit, offset)
+	catches, total, err := lc.catchRepo.GetByLocationID(location.ID, currentViewer(c, lc.userRepo), limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch catches at location",
@@ -150,6 +153,15 @@ func (lc *LocationController) GetLocationCatches(c *gin.Context) {
 		return
 	}
 
+	// A location only used by catches the caller can't see must not give away
+	// that something was found there
+	if total == 0 && location.CatchCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No location found at these coordinates",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": catches,
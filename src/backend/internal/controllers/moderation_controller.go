@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/services"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ModerationController struct {
+	catchRepo    *repositories.AnimalCatchRepository
+	catchService services.CatchService
+	publisher    events.Publisher
+	badgeEngine  services.BadgeEngine
+}
+
+func NewModerationController(catchRepo *repositories.AnimalCatchRepository, catchService services.CatchService, publisher events.Publisher, badgeEngine services.BadgeEngine) *ModerationController {
+	return &ModerationController{
+		catchRepo:    catchRepo,
+		catchService: catchService,
+		publisher:    publisher,
+		badgeEngine:  badgeEngine,
+	}
+}
+
+type ReviewCatchesRequest struct {
+	CatchIDs []string `json:"catch_ids" binding:"required,min=1,max=100"`
+	Action   string   `json:"action" binding:"required,oneof=approve reject"`
+	Notes    string   `json:"notes"`
+}
+
+// GetPendingCatches godoc
+// @Summary Get the verification queue
+// @Description Retrieve catches awaiting verification, oldest first, with species and photo
+// @Tags moderation
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(20)
+// @Param reason query string false "Only catches routed to moderation for this reason (e.g. possible_duplicate)"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/moderation/catches/pending [get]
+func (mc *ModerationController) GetPendingCatches(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	catches, total, err := mc.catchRepo.GetPendingCatches(models.ModerationReason(c.Query("reason")), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch pending catches",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    catches,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// ReviewCatches godoc
+// @Summary Approve or reject catches
+// @Description Approve or reject pending catches in bulk. Catches that are no longer pending or belong to the reviewer are skipped.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param review body ReviewCatchesRequest true "Review decision"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/moderation/catches/review [post]
+func (mc *ModerationController) ReviewCatches(c *gin.Context) {
+	var req ReviewCatchesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	reviewerID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	status := models.VerificationApproved
+	if req.Action == "reject" {
+		status = models.VerificationRejected
+		if req.Notes == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Notes are required when rejecting catches",
+			})
+			return
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.CatchIDs))
+	for _, raw := range req.CatchIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid catch ID format",
+				"details": raw,
+			})
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	updated, err := mc.catchService.Review(ids, status, reviewerID, req.Notes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to review catches",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	reviewedIDs := make([]uuid.UUID, 0, len(updated))
+	reviewed := make(map[uuid.UUID]bool, len(updated))
+	for _, catch := range updated {
+		reviewedIDs = append(reviewedIDs, catch.ID)
+		reviewed[catch.ID] = true
+		mc.publisher.Publish(events.New(events.TypeCatchVerified, catch.UserID, reviewerID, events.CatchVerifiedData{
+			CatchID: catch.ID,
+			Status:  string(status),
+		}))
+	}
+	mc.updateBadges(reviewerID, updated)
+
+	skipped := []uuid.UUID{}
+	for _, id := range ids {
+		if !reviewed[id] {
+			skipped = append(skipped, id)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"status":   status,
+			"reviewed": reviewedIDs,
+			"skipped":  skipped,
+		},
+		"message": "Catches reviewed successfully",
+	})
+}
+
+// updateBadges re-evaluates the badges of the owners of reviewed catches, whose
+// progress drops with rejections, and of the reviewer, for reviewing badges.
+// Badges are derived data, so failures are only logged.
+func (mc *ModerationController) updateBadges(reviewerID uuid.UUID, reviewed []repositories.ReviewedCatch) {
+	if len(reviewed) == 0 {
+		return
+	}
+
+	lastCatch := make(map[uuid.UUID]uuid.UUID, len(reviewed))
+	for _, catch := range reviewed {
+		lastCatch[catch.UserID] = catch.ID
+	}
+	for userID, catchID := range lastCatch {
+		if _, err := mc.badgeEngine.EvaluateUser(userID, &catchID); err != nil {
+			log.Printf("Failed to update badges of user %s: %v", userID, err)
+		}
+	}
+	if _, err := mc.badgeEngine.EvaluateUser(reviewerID, nil); err != nil {
+		log.Printf("Failed to update badges of reviewer %s: %v", reviewerID, err)
+	}
+}
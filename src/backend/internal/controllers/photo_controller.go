@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/services"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead is the room left for the form framing around an uploaded file
+const multipartOverhead = 64 << 10
+
+type PhotoController struct {
+	photoService services.PhotoService
+}
+
+func NewPhotoController(photoService services.PhotoService) *PhotoController {
+	return &PhotoController{
+		photoService: photoService,
+	}
+}
+
+type SignedUploadRequest struct {
+	ContentType string `json:"content_type" binding:"required"`
+}
+
+// photoErrorStatus maps photo service errors onto HTTP status codes
+func photoErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrPhotoNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPhotoNotOwned), errors.Is(err, services.ErrInvalidSignature):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrPhotoTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupportedImage):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrPhotoNotUploaded), errors.Is(err, services.ErrPhotoRequired):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// UploadPhoto godoc
+// @Summary Upload a photo
+// @Description Upload a JPEG or PNG photo as multipart form data. The returned photo ID can be used to create a catch.
+// @Tags photos
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param photo formData file true "Photo file"
+// @Success 201 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 413 {object} map[string]interface{} "error"
+// @Failure 415 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/photos [post]
+func (pc *PhotoController) UploadPhoto(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.AppConfig.MaxUploadSize+multipartOverhead)
+	fileHeader, err := c.FormFile("photo")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "Failed to upload photo",
+				"details": services.ErrPhotoTooLarge.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Photo file is required",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read photo",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	photo, err := pc.photoService.Upload(c.Request.Context(), userID, file, fileHeader.Size)
+	if err != nil {
+		c.JSON(photoErrorStatus(err), gin.H{
+			"error":   "Failed to upload photo",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    photo,
+		"message": "Photo uploaded successfully",
+	})
+}
+
+// CreateSignedUpload godoc
+// @Summary Get a signed upload URL
+// @Description Reserve a photo and get a URL to PUT the image to directly. Call the complete endpoint afterwards if the URL points at external storage.
+// @Tags photos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SignedUploadRequest true "Content type of the photo"
+// @Success 201 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 415 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/photos/upload-url [post]
+func (pc *PhotoController) CreateSignedUpload(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req SignedUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	upload, err := pc.photoService.CreateSignedUpload(userID, req.ContentType)
+	if err != nil {
+		c.JSON(photoErrorStatus(err), gin.H{
+			"error":   "Failed to create upload URL",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    upload,
+	})
+}
+
+// CompleteUpload godoc
+// @Summary Confirm a signed upload
+// @Description Confirm that a photo uploaded through a signed URL is present in storage
+// @Tags photos
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Photo ID (UUID)"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 401 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/photos/{id}/complete [post]
+func (pc *PhotoController) CompleteUpload(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	photoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid photo ID format",
+		})
+		return
+	}
+
+	photo, err := pc.photoService.CompleteUpload(c.Request.Context(), userID, photoID)
+	if err != nil {
+		c.JSON(photoErrorStatus(err), gin.H{
+			"error":   "Failed to complete upload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    photo,
+	})
+}
+
+// ReceiveSignedUpload accepts a PUT to a signed URL issued by local storage.
+// The signature in the query string stands in for authentication.
+func (pc *PhotoController) ReceiveSignedUpload(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("filepath"), "/")
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid upload URL",
+		})
+		return
+	}
+
+	contentType := c.Query("content_type")
+	if c.ContentType() != contentType {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Content-Type does not match the signed upload",
+		})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, config.AppConfig.MaxUploadSize)
+	photo, err := pc.photoService.ReceiveSignedUpload(c.Request.Context(), key, contentType, expires, c.Query("signature"), body, c.Request.ContentLength)
+	if err != nil {
+		c.JSON(photoErrorStatus(err), gin.H{
+			"error":   "Failed to upload photo",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    photo,
+	})
+}
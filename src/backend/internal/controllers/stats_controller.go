@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anidex/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StatsController struct {
+	statsService services.StatsService
+}
+
+func NewStatsController(statsService services.StatsService) *StatsController {
+	return &StatsController{
+		statsService: statsService,
+	}
+}
+
+// GetUserStats godoc
+// @Summary Get a user's stats
+// @Description A user's level, points, catches by rarity and category, streaks, places visited, farthest distance from where they made their first catch, social counts and ranks. Rejected catches don't count.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 500 {object} map[string]interface{} "error"
+// @Router /api/users/{id}/stats [get]
+func (sc *StatsController) GetUserStats(c *gin.Context) {
+	userID, ok := parseUUIDParam(c, "id", "Invalid user ID format")
+	if !ok {
+		return
+	}
+
+	stats, err := sc.statsService.Get(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch user stats",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// currentViewer identifies who a response is for on routes where authentication
+// is optional. Anonymous requests get the zero Viewer. The role is read from the
+// database, like ModeratorMiddleware does.
+func currentViewer(c *gin.Context, userRepo repositories.UserRepository) models.Viewer {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		return models.Viewer{}
+	}
+
+	viewer := models.Viewer{UserID: userID}
+	if user, err := userRepo.FindByID(userID); err == nil {
+		viewer.Moderator = user.CanModerate()
+	}
+	return viewer
+}
@@ -0,0 +1,51 @@
+package events
+
+import "github.com/google/uuid"
+
+// Payloads of each event type, sent as Event.Data
+
+type CatchCreatedData struct {
+	CatchID     uuid.UUID `json:"catch_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	SpeciesID   uuid.UUID `json:"species_id"`
+	SpeciesName string    `json:"species_name"`
+	Rarity      string    `json:"rarity"`
+}
+
+type CatchVerifiedData struct {
+	CatchID uuid.UUID `json:"catch_id"`
+	Status  string    `json:"status"` // approved, auto_approved or rejected
+}
+
+type LikeData struct {
+	CatchID    uuid.UUID `json:"catch_id"`
+	UserID     uuid.UUID `json:"user_id"` // Who liked it
+	LikesCount int       `json:"likes_count"`
+}
+
+type CommentData struct {
+	CatchID   uuid.UUID `json:"catch_id"`
+	CommentID uuid.UUID `json:"comment_id"`
+	UserID    uuid.UUID `json:"user_id"` // The author
+	Content   string    `json:"content"`
+}
+
+type BadgeEarnedData struct {
+	BadgeID       uuid.UUID  `json:"badge_id"`
+	Name          string     `json:"name"`
+	BadgeRarity   string     `json:"badge_rarity"`
+	PointsAwarded int        `json:"points_awarded"`
+	CatchID       *uuid.UUID `json:"catch_id,omitempty"` // The catch that earned it
+}
+
+type FollowData struct {
+	UserID   uuid.UUID `json:"user_id"`            // The other account
+	Status   string    `json:"status"`             // accepted, or pending for a request
+	Approved bool      `json:"approved,omitempty"` // The other account approved the subscriber's request
+}
+
+type StreakMilestoneData struct {
+	Days          int  `json:"days"`          // The milestone, in consecutive days
+	FreezeEarned  bool `json:"freeze_earned"` // A streak freeze was awarded for it
+	StreakFreezes int  `json:"streak_freezes"`
+}
@@ -0,0 +1,196 @@
+// Package events carries real-time events to connected clients. Services
+// publish events to a Bus, and every open stream subscribes to the events meant
+// for its user. The local bus delivers within the process; the Postgres bus
+// relays events through LISTEN/NOTIFY so every instance sees them.
+package events
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	TypeCatchCreated    Type = "catch_created"  // A public catch near the subscriber
+	TypeCatchVerified   Type = "catch_verified" // One of the subscriber's catches was approved or rejected
+	TypeLike            Type = "like"           // Someone liked the subscriber's catch
+	TypeComment         Type = "comment"        // Someone commented on the subscriber's catch
+	TypeBadgeEarned     Type = "badge_earned"
+	TypeFollow          Type = "follow"           // Someone followed the subscriber, requested to, or approved their request
+	TypeStreakMilestone Type = "streak_milestone" // The subscriber's daily streak reached a milestone
+)
+
+// subscriptionBuffer is how many events may wait for a slow client before
+// further ones are dropped
+const subscriptionBuffer = 64
+
+// Position is where an area event happened
+type Position struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Event is delivered to its recipient, or for area events (a nil RecipientID
+// and a Position) to everyone subscribed nearby other than the actor
+type Event struct {
+	ID          uuid.UUID       `json:"id"`
+	Type        Type            `json:"type"`
+	RecipientID uuid.UUID       `json:"recipient_id,omitempty"`
+	ActorID     uuid.UUID       `json:"actor_id,omitempty"`
+	Position    *Position       `json:"position,omitempty"`
+	Data        json.RawMessage `json:"data"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// New returns an event for a single recipient. data is marshalled to JSON.
+func New(eventType Type, recipientID, actorID uuid.UUID, data interface{}) Event {
+	return newEvent(eventType, recipientID, actorID, nil, data)
+}
+
+// NewNearby returns an area event delivered to subscribers near position
+func NewNearby(eventType Type, actorID uuid.UUID, position Position, data interface{}) Event {
+	return newEvent(eventType, uuid.Nil, actorID, &position, data)
+}
+
+func newEvent(eventType Type, recipientID, actorID uuid.UUID, position *Position, data interface{}) Event {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", eventType, err)
+		payload = json.RawMessage("null")
+	}
+	return Event{
+		ID:          uuid.New(),
+		Type:        eventType,
+		RecipientID: recipientID,
+		ActorID:     actorID,
+		Position:    position,
+		Data:        payload,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// Publisher sends events. Publishing never blocks on subscribers and never
+// fails the caller; events that can't be delivered are dropped.
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Bus is a Publisher that clients can subscribe to
+type Bus interface {
+	Publisher
+	Subscribe(filter Filter) *Subscription
+}
+
+// Filter selects the events for a subscriber: those addressed to UserID, and
+// area events within RadiusKm of Position if it is set
+type Filter struct {
+	UserID   uuid.UUID
+	Position *Position
+	RadiusKm float64
+}
+
+func (f Filter) matches(event *Event) bool {
+	if event.RecipientID != uuid.Nil {
+		return event.RecipientID == f.UserID
+	}
+	if event.Position == nil || f.Position == nil || event.ActorID == f.UserID {
+		return false
+	}
+	return distanceKm(*f.Position, *event.Position) <= f.RadiusKm
+}
+
+// Subscription receives the events matching its filter on C until Close
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	filter Filter
+	hub    *hub
+	once   sync.Once
+}
+
+// Close stops the subscription and closes C
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.remove(s)
+	})
+}
+
+// hub tracks the subscriptions of this process and fans events out to them
+type hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subs: make(map[*Subscription]struct{})}
+}
+
+func (h *hub) subscribe(filter Filter) *Subscription {
+	ch := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, hub: h}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	close(sub.ch)
+}
+
+func (h *hub) dispatch(event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if !sub.filter.matches(&event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			log.Printf("Dropping %s event for slow subscriber %s", event.Type, sub.filter.UserID)
+		}
+	}
+}
+
+// LocalBus delivers events to subscribers in this process only
+type LocalBus struct {
+	hub *hub
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{hub: newHub()}
+}
+
+func (b *LocalBus) Publish(event Event) {
+	b.hub.dispatch(event)
+}
+
+func (b *LocalBus) Subscribe(filter Filter) *Subscription {
+	return b.hub.subscribe(filter)
+}
+
+// distanceKm is the haversine distance between two positions
+func distanceKm(a, b Position) float64 {
+	const earthRadius = 6371.0
+	toRad := math.Pi / 180
+
+	dLat := (b.Latitude - a.Latitude) * toRad
+	dLng := (b.Longitude - a.Longitude) * toRad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Latitude*toRad)*math.Cos(b.Latitude*toRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return earthRadius * 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+}
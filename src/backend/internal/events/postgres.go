@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	notifyChannel = "anidex_events"
+	// Postgres rejects NOTIFY payloads of 8000 bytes or more
+	maxNotifyPayload = 7900
+
+	minListenBackoff = time.Second
+	maxListenBackoff = 30 * time.Second
+)
+
+// PostgresBus relays events through Postgres LISTEN/NOTIFY so that subscribers
+// connected to any instance receive them. Events published while the listener
+// is reconnecting are missed by this instance's subscribers.
+type PostgresBus struct {
+	db  *gorm.DB
+	dsn string
+	hub *hub
+}
+
+// NewPostgresBus starts listening for events on a dedicated connection to dsn.
+// Events are published through db.
+func NewPostgresBus(db *gorm.DB, dsn string) *PostgresBus {
+	b := &PostgresBus{
+		db:  db,
+		dsn: dsn,
+		hub: newHub(),
+	}
+	go b.listen()
+	return b
+}
+
+func (b *PostgresBus) Publish(event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", event.Type, err)
+		return
+	}
+	if len(payload) > maxNotifyPayload {
+		log.Printf("%s event is too large to relay, delivering it on this instance only", event.Type)
+		b.hub.dispatch(event)
+		return
+	}
+	if err := b.db.Exec("SELECT pg_notify(?, ?)", notifyChannel, string(payload)).Error; err != nil {
+		log.Printf("Failed to relay %s event, delivering it on this instance only: %v", event.Type, err)
+		b.hub.dispatch(event)
+	}
+}
+
+func (b *PostgresBus) Subscribe(filter Filter) *Subscription {
+	return b.hub.subscribe(filter)
+}
+
+// listen delivers notifications to local subscribers, reconnecting with
+// backoff whenever the connection is lost
+func (b *PostgresBus) listen() {
+	backoff := minListenBackoff
+	for {
+		if err := b.listenOnce(func() { backoff = minListenBackoff }); err != nil {
+			log.Printf("Event listener disconnected, retrying in %s: %v", backoff, err)
+		}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxListenBackoff)
+	}
+}
+
+func (b *PostgresBus) listenOnce(connected func()) error {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Printf("Ignoring malformed event notification: %v", err)
+			continue
+		}
+		b.hub.dispatch(event)
+	}
+}
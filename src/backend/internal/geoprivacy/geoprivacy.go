@@ -0,0 +1,36 @@
+// Package geoprivacy generalizes the coordinates of sensitive sightings so they
+// can be shown publicly without revealing where the animal actually is.
+package geoprivacy
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+)
+
+// CellSize is the size of the grid cells in degrees, about 22km north-south
+const CellSize = 0.2
+
+// Cell returns the south-west corner of the grid cell containing lat, lng
+func Cell(lat, lng float64) (float64, float64) {
+	return math.Floor(lat/CellSize) * CellSize, math.Floor(lng/CellSize) * CellSize
+}
+
+// Obscure returns a random point within the grid cell containing lat, lng. The
+// point is derived from seed rather than drawn anew, so the same sighting always
+// shows the same point and repeated requests can't be averaged out. Since it is
+// uniform over the cell, it says nothing about where in the cell the sighting was.
+func Obscure(lat, lng float64, seed []byte) (float64, float64) {
+	cellLat, cellLng := Cell(lat, lng)
+
+	sum := sha256.Sum256(seed)
+	latOffset := unitFloat(sum[0:8]) * CellSize
+	lngOffset := unitFloat(sum[8:16]) * CellSize
+
+	return math.Min(cellLat+latOffset, 90), math.Min(cellLng+lngOffset, 180)
+}
+
+// unitFloat maps 8 bytes onto [0, 1)
+func unitFloat(b []byte) float64 {
+	return float64(binary.BigEndian.Uint64(b)>>11) / (1 << 53)
+}
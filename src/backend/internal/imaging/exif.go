@@ -0,0 +1,316 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// EXIF tag IDs we care about
+const (
+	tagOrientation        = 0x0112
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	tagGPSLatitudeRef       = 0x0001
+	tagGPSLatitude          = 0x0002
+	tagGPSLongitudeRef      = 0x0003
+	tagGPSLongitude         = 0x0004
+	tagGPSAltitudeRef       = 0x0005
+	tagGPSAltitude          = 0x0006
+	tagGPSTimeStamp         = 0x0007
+	tagGPSDateStamp         = 0x001D
+	tagGPSHPositioningError = 0x001F
+)
+
+const exifDateTimeLayout = "2006:01:02 15:04:05"
+
+var ErrNoExif = errors.New("no EXIF data")
+
+// exifValue is the raw value of a single IFD entry
+type exifValue struct {
+	format uint16
+	count  uint32
+	data   []byte
+}
+
+// Exif holds the tags read from a JPEG's APP1 segment, keyed by IFD
+type Exif struct {
+	order binary.ByteOrder
+	main  map[uint16]exifValue // IFD0 and the Exif sub-IFD
+	gps   map[uint16]exifValue
+}
+
+// exifFormatSizes is the byte size of one component of each TIFF field type
+var exifFormatSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// ParseExif extracts EXIF metadata from JPEG data
+func ParseExif(data []byte) (*Exif, error) {
+	tiff, err := findExifSegment(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(tiff) < 8 {
+		return nil, ErrNoExif
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, ErrNoExif
+	}
+
+	exif := &Exif{
+		order: order,
+		main:  map[uint16]exifValue{},
+		gps:   map[uint16]exifValue{},
+	}
+
+	if err := exif.readIFD(tiff, order.Uint32(tiff[4:8]), exif.main); err != nil {
+		return nil, err
+	}
+	if offset, ok := exif.uint32Tag(exif.main, tagExifIFD); ok {
+		exif.readIFD(tiff, offset, exif.main)
+	}
+	if offset, ok := exif.uint32Tag(exif.main, tagGPSIFD); ok {
+		exif.readIFD(tiff, offset, exif.gps)
+	}
+
+	return exif, nil
+}
+
+// findExifSegment walks the JPEG markers and returns the TIFF payload of the EXIF APP1 segment
+func findExifSegment(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrNoExif
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, ErrNoExif
+		}
+		marker := data[pos+1]
+		if marker == 0xD9 || marker == 0xDA {
+			// End of image or start of scan: no more metadata segments
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrNoExif
+		}
+
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+		pos = end
+	}
+
+	return nil, ErrNoExif
+}
+
+func (e *Exif) readIFD(tiff []byte, offset uint32, into map[uint16]exifValue) error {
+	if int(offset)+2 > len(tiff) {
+		return ErrNoExif
+	}
+	count := int(e.order.Uint16(tiff[offset:]))
+	pos := int(offset) + 2
+
+	for i := 0; i < count; i++ {
+		if pos+12 > len(tiff) {
+			return ErrNoExif
+		}
+		entry := tiff[pos : pos+12]
+		pos += 12
+
+		tag := e.order.Uint16(entry[0:2])
+		format := e.order.Uint16(entry[2:4])
+		components := e.order.Uint32(entry[4:8])
+
+		size, ok := exifFormatSizes[format]
+		if !ok {
+			continue
+		}
+		total := size * components
+		if components > 0 && total/components != size {
+			continue // overflow
+		}
+
+		// Values up to 4 bytes are stored inline, larger ones at an offset
+		var value []byte
+		if total <= 4 {
+			value = entry[8 : 8+total]
+		} else {
+			valueOffset := e.order.Uint32(entry[8:12])
+			if uint64(valueOffset)+uint64(total) > uint64(len(tiff)) {
+				continue
+			}
+			value = tiff[valueOffset : valueOffset+total]
+		}
+
+		into[tag] = exifValue{format: format, count: components, data: value}
+	}
+
+	return nil
+}
+
+func (e *Exif) uint32Tag(ifd map[uint16]exifValue, tag uint16) (uint32, bool) {
+	value, ok := ifd[tag]
+	if !ok || value.count == 0 {
+		return 0, false
+	}
+	switch value.format {
+	case 3:
+		return uint32(e.order.Uint16(value.data)), true
+	case 4:
+		return e.order.Uint32(value.data), true
+	}
+	return 0, false
+}
+
+// Orientation returns the EXIF orientation (1-8), defaulting to 1
+func (e *Exif) Orientation() int {
+	orientation, ok := e.uint32Tag(e.main, tagOrientation)
+	if !ok || orientation < 1 || orientation > 8 {
+		return 1
+	}
+	return int(orientation)
+}
+
+func (e *Exif) stringTag(ifd map[uint16]exifValue, tag uint16) (string, bool) {
+	value, ok := ifd[tag]
+	if !ok || value.format != 2 {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimRight(string(value.data), "\x00")), true
+}
+
+// rationalsTag returns the unsigned rational values of a tag as floats
+func (e *Exif) rationalsTag(ifd map[uint16]exifValue, tag uint16) ([]float64, bool) {
+	value, ok := ifd[tag]
+	if !ok || value.format != 5 || value.count == 0 {
+		return nil, false
+	}
+
+	values := make([]float64, value.count)
+	for i := range values {
+		numerator := e.order.Uint32(value.data[i*8:])
+		denominator := e.order.Uint32(value.data[i*8+4:])
+		if denominator == 0 {
+			return nil, false
+		}
+		values[i] = float64(numerator) / float64(denominator)
+	}
+	return values, true
+}
+
+// DateTimeOriginal returns when the photo was taken as recorded by the camera.
+// The camera clock has no time zone, so the result uses the OffsetTimeOriginal
+// tag when present; otherwise hasZone is false and the wall clock is returned in UTC.
+func (e *Exif) DateTimeOriginal() (taken time.Time, hasZone bool, ok bool) {
+	raw, ok := e.stringTag(e.main, tagDateTimeOriginal)
+	if !ok {
+		return time.Time{}, false, false
+	}
+
+	location := time.UTC
+	if offset, ok := e.stringTag(e.main, tagOffsetTimeOriginal); ok {
+		if parsed, err := time.Parse("-07:00", offset); err == nil {
+			_, seconds := parsed.Zone()
+			location = time.FixedZone(offset, seconds)
+			hasZone = true
+		}
+	}
+
+	taken, err := time.ParseInLocation(exifDateTimeLayout, raw, location)
+	if err != nil {
+		return time.Time{}, false, false
+	}
+	return taken, hasZone, true
+}
+
+// GPSTime returns the UTC time of the GPS fix, if recorded
+func (e *Exif) GPSTime() (time.Time, bool) {
+	date, ok := e.stringTag(e.gps, tagGPSDateStamp)
+	if !ok {
+		return time.Time{}, false
+	}
+	clock, ok := e.rationalsTag(e.gps, tagGPSTimeStamp)
+	if !ok || len(clock) < 3 {
+		return time.Time{}, false
+	}
+
+	day, err := time.ParseInLocation("2006:01:02", date, time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+	seconds := clock[0]*3600 + clock[1]*60 + clock[2]
+	return day.Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+// GPSPosition returns the latitude and longitude in decimal degrees
+func (e *Exif) GPSPosition() (lat, lng float64, ok bool) {
+	lat, ok = e.gpsCoordinate(tagGPSLatitude, tagGPSLatitudeRef, "S")
+	if !ok {
+		return 0, 0, false
+	}
+	lng, ok = e.gpsCoordinate(tagGPSLongitude, tagGPSLongitudeRef, "W")
+	if !ok {
+		return 0, 0, false
+	}
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
+
+func (e *Exif) gpsCoordinate(tag, refTag uint16, negativeRef string) (float64, bool) {
+	parts, ok := e.rationalsTag(e.gps, tag)
+	if !ok || len(parts) < 3 {
+		return 0, false
+	}
+	ref, ok := e.stringTag(e.gps, refTag)
+	if !ok {
+		return 0, false
+	}
+
+	degrees := parts[0] + parts[1]/60 + parts[2]/3600
+	if strings.EqualFold(ref, negativeRef) {
+		degrees = -degrees
+	}
+	return degrees, true
+}
+
+// GPSAltitude returns the altitude in meters relative to sea level
+func (e *Exif) GPSAltitude() (float64, bool) {
+	altitude, ok := e.rationalsTag(e.gps, tagGPSAltitude)
+	if !ok {
+		return 0, false
+	}
+	if ref, ok := e.gps[tagGPSAltitudeRef]; ok && len(ref.data) > 0 && ref.data[0] == 1 {
+		return -altitude[0], true
+	}
+	return altitude[0], true
+}
+
+// GPSAccuracy returns the horizontal positioning error in meters
+func (e *Exif) GPSAccuracy() (float64, bool) {
+	accuracy, ok := e.rationalsTag(e.gps, tagGPSHPositioningError)
+	if !ok {
+		return 0, false
+	}
+	return accuracy[0], true
+}
@@ -0,0 +1,362 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// byteOrder reads and appends in one byte order
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// exifEntry is an IFD entry for building test EXIF data. Values longer than 4
+// bytes are stored after the IFDs unless offset is set, which points the entry
+// at that offset instead.
+type exifEntry struct {
+	tag    uint16
+	format uint16
+	count  uint32
+	value  []byte
+	offset *uint32
+}
+
+func asciiEntry(tag uint16, text string) exifEntry {
+	return exifEntry{tag: tag, format: 2, count: uint32(len(text) + 1), value: append([]byte(text), 0)}
+}
+
+func shortEntry(order byteOrder, tag uint16, value uint16) exifEntry {
+	return exifEntry{tag: tag, format: 3, count: 1, value: order.AppendUint16(nil, value)}
+}
+
+func byteEntry(tag uint16, value byte) exifEntry {
+	return exifEntry{tag: tag, format: 1, count: 1, value: []byte{value}}
+}
+
+// rationalEntry encodes each value as a fraction over denominator
+func rationalEntry(order byteOrder, tag uint16, denominator uint32, values ...float64) exifEntry {
+	var data []byte
+	for _, value := range values {
+		data = order.AppendUint32(data, uint32(math.Round(value*float64(denominator))))
+		data = order.AppendUint32(data, denominator)
+	}
+	return exifEntry{tag: tag, format: 5, count: uint32(len(values)), value: data}
+}
+
+// buildTIFF lays out IFD0, then the Exif and GPS sub-IFDs if they have entries,
+// then the values that don't fit in their entries
+func buildTIFF(order byteOrder, ifd0, exifIFD, gpsIFD []exifEntry) []byte {
+	ifd0 = slices.Clone(ifd0)
+	for _, sub := range []struct {
+		tag     uint16
+		entries []exifEntry
+	}{{tagExifIFD, exifIFD}, {tagGPSIFD, gpsIFD}} {
+		if len(sub.entries) > 0 {
+			// The offset is filled in once the layout is known
+			ifd0 = append(ifd0, exifEntry{tag: sub.tag, format: 4, count: 1})
+		}
+	}
+	ifds := [][]exifEntry{ifd0, exifIFD, gpsIFD}
+
+	ifdSize := func(entries []exifEntry) uint32 { return uint32(2 + 12*len(entries) + 4) }
+	offsets := make([]uint32, len(ifds))
+	end := uint32(8)
+	for i, entries := range ifds {
+		offsets[i] = end
+		if len(entries) > 0 || i == 0 {
+			end += ifdSize(entries)
+		}
+	}
+	for i := range ifd0 {
+		switch ifd0[i].tag {
+		case tagExifIFD:
+			ifd0[i].value = order.AppendUint32(nil, offsets[1])
+		case tagGPSIFD:
+			ifd0[i].value = order.AppendUint32(nil, offsets[2])
+		}
+	}
+
+	var tiff, data []byte
+	if order == binary.LittleEndian {
+		tiff = append(tiff, "II"...)
+	} else {
+		tiff = append(tiff, "MM"...)
+	}
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+	for i, entries := range ifds {
+		if len(entries) == 0 && i > 0 {
+			continue
+		}
+		tiff = order.AppendUint16(tiff, uint16(len(entries)))
+		for _, entry := range entries {
+			tiff = order.AppendUint16(tiff, entry.tag)
+			tiff = order.AppendUint16(tiff, entry.format)
+			tiff = order.AppendUint32(tiff, entry.count)
+			switch {
+			case entry.offset != nil:
+				tiff = order.AppendUint32(tiff, *entry.offset)
+			case len(entry.value) <= 4:
+				inline := make([]byte, 4)
+				copy(inline, entry.value)
+				tiff = append(tiff, inline...)
+			default:
+				tiff = order.AppendUint32(tiff, end+uint32(len(data)))
+				data = append(data, entry.value...)
+			}
+		}
+		tiff = order.AppendUint32(tiff, 0) // No next IFD
+	}
+	return append(tiff, data...)
+}
+
+// wrapJPEG puts a TIFF payload in the APP1 segment of a minimal JPEG
+func wrapJPEG(tiff []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(payload)+2))
+	data = append(data, payload...)
+	return append(data, 0xFF, 0xD9)
+}
+
+func samplePhoto(order byteOrder, belowSeaLevel byte) []byte {
+	return wrapJPEG(buildTIFF(order,
+		[]exifEntry{shortEntry(order, tagOrientation, 6)},
+		[]exifEntry{
+			asciiEntry(tagDateTimeOriginal, "2024:06:15 14:30:05"),
+			asciiEntry(tagOffsetTimeOriginal, "+02:00"),
+		},
+		[]exifEntry{
+			asciiEntry(tagGPSLatitudeRef, "N"),
+			rationalEntry(order, tagGPSLatitude, 100, 51, 30, 26.64),
+			asciiEntry(tagGPSLongitudeRef, "W"),
+			rationalEntry(order, tagGPSLongitude, 100, 0, 7, 40.08),
+			byteEntry(tagGPSAltitudeRef, belowSeaLevel),
+			rationalEntry(order, tagGPSAltitude, 10, 35.5),
+			rationalEntry(order, tagGPSHPositioningError, 1, 5),
+			asciiEntry(tagGPSDateStamp, "2024:06:15"),
+			rationalEntry(order, tagGPSTimeStamp, 1, 12, 30, 4),
+		},
+	))
+}
+
+func TestParseExifByteOrders(t *testing.T) {
+	for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			exif, err := ParseExif(samplePhoto(order, 0))
+			if err != nil {
+				t.Fatalf("ParseExif() error = %v", err)
+			}
+
+			if got := exif.Orientation(); got != 6 {
+				t.Errorf("Orientation() = %d, want 6", got)
+			}
+
+			taken, hasZone, ok := exif.DateTimeOriginal()
+			want := time.Date(2024, 6, 15, 12, 30, 5, 0, time.UTC)
+			if !ok || !hasZone || !taken.Equal(want) {
+				t.Errorf("DateTimeOriginal() = %v, %v, %v, want %v with its zone", taken, hasZone, ok, want)
+			}
+
+			lat, lng, ok := exif.GPSPosition()
+			if !ok || math.Abs(lat-51.5074) > 1e-6 || math.Abs(lng+0.1278) > 1e-6 {
+				t.Errorf("GPSPosition() = %v, %v, %v, want 51.5074, -0.1278", lat, lng, ok)
+			}
+			if altitude, ok := exif.GPSAltitude(); !ok || altitude != 35.5 {
+				t.Errorf("GPSAltitude() = %v, %v, want 35.5", altitude, ok)
+			}
+			if accuracy, ok := exif.GPSAccuracy(); !ok || accuracy != 5 {
+				t.Errorf("GPSAccuracy() = %v, %v, want 5", accuracy, ok)
+			}
+			fix, ok := exif.GPSTime()
+			if want := time.Date(2024, 6, 15, 12, 30, 4, 0, time.UTC); !ok || !fix.Equal(want) {
+				t.Errorf("GPSTime() = %v, %v, want %v", fix, ok, want)
+			}
+		})
+	}
+}
+
+func TestParseExifBelowSeaLevel(t *testing.T) {
+	exif, err := ParseExif(samplePhoto(binary.BigEndian, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if altitude, ok := exif.GPSAltitude(); !ok || altitude != -35.5 {
+		t.Errorf("GPSAltitude() = %v, %v, want -35.5", altitude, ok)
+	}
+}
+
+func TestParseExifWithoutZone(t *testing.T) {
+	order := binary.LittleEndian
+	exif, err := ParseExif(wrapJPEG(buildTIFF(order, []exifEntry{asciiEntry(tagDateTimeOriginal, "2024:06:15 14:30:05")}, nil, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken, hasZone, ok := exif.DateTimeOriginal()
+	if want := time.Date(2024, 6, 15, 14, 30, 5, 0, time.UTC); !ok || hasZone || !taken.Equal(want) {
+		t.Errorf("DateTimeOriginal() = %v, %v, %v, want the wall clock %v without a zone", taken, hasZone, ok, want)
+	}
+}
+
+func TestParseExifRejects(t *testing.T) {
+	order := binary.LittleEndian
+	valid := buildTIFF(order, []exifEntry{shortEntry(order, tagOrientation, 6)}, nil, nil)
+
+	// An IFD0 claiming more entries than there are
+	truncatedIFD := slices.Clone(valid)
+	order.PutUint16(truncatedIFD[8:], 40)
+
+	// IFD0 past the end
+	ifdPastEnd := slices.Clone(valid)
+	order.PutUint32(ifdPastEnd[4:], uint32(len(valid)))
+
+	badOrder := slices.Clone(valid)
+	copy(badOrder, "XX")
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not a JPEG", []byte("GIF89a and some more")},
+		{"JPEG without EXIF", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9}},
+		{"EXIF after the scan", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xE1, 0x00, 0x08, 'E', 'x', 'i', 'f', 0, 0}},
+		{"segment past the end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x10, 0x00, 'E', 'x', 'i', 'f', 0, 0}},
+		{"segment length too short", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xD9}},
+		{"garbage between segments", []byte{0xFF, 0xD8, 0x00, 0xE1, 0x00, 0x02, 0xFF, 0xD9}},
+		{"TIFF header truncated", wrapJPEG([]byte("II*\x00"))},
+		{"unknown byte order", wrapJPEG(badOrder)},
+		{"IFD past the end", wrapJPEG(ifdPastEnd)},
+		{"IFD truncated", wrapJPEG(truncatedIFD)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exif, err := ParseExif(tt.data)
+			if !errors.Is(err, ErrNoExif) {
+				t.Errorf("ParseExif() = %v, %v, want ErrNoExif", exif, err)
+			}
+		})
+	}
+}
+
+func TestParseExifSkipsBadEntries(t *testing.T) {
+	for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+		pastEnd := uint32(1 << 20)
+		wraps := uint32(math.MaxUint32 - 4)
+		zeroDenominator := rationalEntry(order, tagGPSLatitude, 1, 51, 30, 0)
+		order.PutUint32(zeroDenominator.value[20:], 0)
+
+		tests := []struct {
+			name  string
+			ifd0  []exifEntry
+			gps   []exifEntry
+			check func(t *testing.T, exif *Exif)
+		}{
+			{"value offset past the end",
+				[]exifEntry{{tag: tagDateTimeOriginal, format: 2, count: 20, offset: &pastEnd}}, nil,
+				func(t *testing.T, exif *Exif) {
+					if _, _, ok := exif.DateTimeOriginal(); ok {
+						t.Error("DateTimeOriginal() found a value past the end")
+					}
+				}},
+			{"value offset wraps around",
+				[]exifEntry{{tag: tagDateTimeOriginal, format: 2, count: 20, offset: &wraps}}, nil,
+				func(t *testing.T, exif *Exif) {
+					if _, _, ok := exif.DateTimeOriginal(); ok {
+						t.Error("DateTimeOriginal() found a value at a wrapped offset")
+					}
+				}},
+			{"value size overflows",
+				[]exifEntry{{tag: tagOrientation, format: 5, count: 1<<29 + 1, value: make([]byte, 8)}}, nil,
+				func(t *testing.T, exif *Exif) {
+					if got := exif.Orientation(); got != 1 {
+						t.Errorf("Orientation() = %d, want the default 1", got)
+					}
+				}},
+			{"unknown format",
+				[]exifEntry{{tag: tagOrientation, format: 99, count: 1, value: order.AppendUint16(nil, 6)}}, nil,
+				func(t *testing.T, exif *Exif) {
+					if got := exif.Orientation(); got != 1 {
+						t.Errorf("Orientation() = %d, want the default 1", got)
+					}
+				}},
+			{"orientation out of range",
+				[]exifEntry{shortEntry(order, tagOrientation, 9)}, nil,
+				func(t *testing.T, exif *Exif) {
+					if got := exif.Orientation(); got != 1 {
+						t.Errorf("Orientation() = %d, want the default 1", got)
+					}
+				}},
+			{"GPS IFD past the end",
+				[]exifEntry{{tag: tagGPSIFD, format: 4, count: 1, value: order.AppendUint32(nil, pastEnd)}}, nil,
+				func(t *testing.T, exif *Exif) {
+					if _, _, ok := exif.GPSPosition(); ok {
+						t.Error("GPSPosition() found a position")
+					}
+				}},
+			{"zero denominator",
+				nil, []exifEntry{
+					asciiEntry(tagGPSLatitudeRef, "N"), zeroDenominator,
+					asciiEntry(tagGPSLongitudeRef, "E"), rationalEntry(order, tagGPSLongitude, 1, 0, 7, 0),
+				},
+				func(t *testing.T, exif *Exif) {
+					if _, _, ok := exif.GPSPosition(); ok {
+						t.Error("GPSPosition() divided by zero")
+					}
+				}},
+			{"latitude out of range",
+				nil, []exifEntry{
+					asciiEntry(tagGPSLatitudeRef, "N"), rationalEntry(order, tagGPSLatitude, 1, 95, 0, 0),
+					asciiEntry(tagGPSLongitudeRef, "E"), rationalEntry(order, tagGPSLongitude, 1, 0, 7, 0),
+				},
+				func(t *testing.T, exif *Exif) {
+					if _, _, ok := exif.GPSPosition(); ok {
+						t.Error("GPSPosition() accepted a latitude of 95")
+					}
+				}},
+			{"coordinate too short",
+				nil, []exifEntry{
+					asciiEntry(tagGPSLatitudeRef, "N"), rationalEntry(order, tagGPSLatitude, 1, 51, 30),
+					asciiEntry(tagGPSLongitudeRef, "E"), rationalEntry(order, tagGPSLongitude, 1, 0, 7, 0),
+				},
+				func(t *testing.T, exif *Exif) {
+					if _, _, ok := exif.GPSPosition(); ok {
+						t.Error("GPSPosition() accepted a latitude without seconds")
+					}
+				}},
+		}
+		for _, tt := range tests {
+			t.Run(order.String()+"/"+tt.name, func(t *testing.T) {
+				exif, err := ParseExif(wrapJPEG(buildTIFF(order, tt.ifd0, nil, tt.gps)))
+				if err != nil {
+					t.Fatalf("ParseExif() error = %v", err)
+				}
+				tt.check(t, exif)
+			})
+		}
+	}
+}
+
+func TestParseExifTruncated(t *testing.T) {
+	// Cutting the photo short anywhere must not panic, however little is left
+	for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+		data := samplePhoto(order, 0)
+		for n := range data {
+			exif, err := ParseExif(data[:n])
+			if err != nil {
+				continue
+			}
+			exif.Orientation()
+			exif.DateTimeOriginal()
+			exif.GPSPosition()
+			exif.GPSAltitude()
+			exif.GPSAccuracy()
+			exif.GPSTime()
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package imaging decodes uploaded photos and derives the renditions, placeholders
+// and hashes we store for them. It only relies on the standard library codecs,
+// so every output is re-encoded without any of the source metadata.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // Register the PNG decoder
+)
+
+const jpegQuality = 85
+
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// Decode decodes JPEG or PNG data and applies the EXIF orientation, so the
+// returned image is upright even once the metadata has been stripped. Images
+// of more than maxPixels pixels are rejected from their header, before any
+// memory is allocated for them.
+func Decode(data []byte, maxPixels int64) (*image.RGBA, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	img := toRGBA(src)
+	if exif, err := ParseExif(data); err == nil {
+		img = applyOrientation(img, exif.Orientation())
+	}
+	return img, nil
+}
+
+// EncodeJPEG encodes the image as a metadata-free JPEG
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Fit scales the image down to fit within maxWidth x maxHeight, keeping the aspect
+// ratio. Images that already fit are returned unchanged.
+func Fit(img *image.RGBA, maxWidth, maxHeight int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w <= maxWidth && h <= maxHeight {
+		return img
+	}
+
+	scale := float64(maxWidth) / float64(w)
+	if s := float64(maxHeight) / float64(h); s < scale {
+		scale = s
+	}
+
+	return Resize(img, max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5)))
+}
+
+// Fill center-crops the image to the aspect ratio of width x height and scales it to exactly that size
+func Fill(img *image.RGBA, width, height int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+
+	cropW, cropH := w, w*height/width
+	if cropH > h {
+		cropW, cropH = h*width/height, h
+	}
+	x0 := img.Bounds().Min.X + (w-cropW)/2
+	y0 := img.Bounds().Min.Y + (h-cropH)/2
+
+	cropped := img.SubImage(image.Rect(x0, y0, x0+cropW, y0+cropH)).(*image.RGBA)
+	if cropW <= width && cropH <= height {
+		return toRGBA(cropped)
+	}
+	return Resize(cropped, width, height)
+}
+
+// Resize scales the image to width x height by averaging the source pixels that
+// fall into each destination pixel, which gives clean results when shrinking
+func Resize(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+
+	for y := 0; y < height; y++ {
+		y0 := y * sh / height
+		y1 := max((y+1)*sh/height, y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := x * sw / width
+			x1 := max((x+1)*sw/width, x0+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(bounds.Min.X+x0, bounds.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[offset])
+					g += uint32(src.Pix[offset+1])
+					b += uint32(src.Pix[offset+2])
+					a += uint32(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			d := dst.PixOffset(x, y)
+			dst.Pix[d] = uint8(r / n)
+			dst.Pix[d+1] = uint8(g / n)
+			dst.Pix[d+2] = uint8(b / n)
+			dst.Pix[d+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	return dst
+}
+
+// applyOrientation rotates/flips the image according to an EXIF orientation value
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// Orientations 5-8 swap the axes
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirror horizontal
+				dx, dy = w-1-x, y
+			case 3: // Rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // Mirror vertical
+				dx, dy = x, h-1-y
+			case 5: // Transpose
+				dx, dy = y, x
+			case 6: // Rotate 90 CW
+				dx, dy = h-1-y, x
+			case 7: // Transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotate 90 CCW
+				dx, dy = y, w-1-x
+			}
+			s := src.PixOffset(x, y)
+			d := dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,49 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodePixelLimit(t *testing.T) {
+	data := encodePNG(t, 100, 50)
+
+	tests := []struct {
+		name      string
+		maxPixels int64
+		wantErr   error
+	}{
+		{"under the limit", 10000, nil},
+		{"at the limit", 5000, nil},
+		{"over the limit", 4999, ErrTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Decode(data, tt.maxPixels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (img.Bounds().Dx() != 100 || img.Bounds().Dy() != 50) {
+				t.Errorf("Decode() size = %v, want 100x50", img.Bounds().Size())
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsGarbage(t *testing.T) {
+	if _, err := Decode([]byte("not an image"), 1<<20); err == nil {
+		t.Fatal("Decode() of garbage succeeded")
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	idempotencyKeyTTL    = 24 * time.Hour
+	// A key still processing after this long is taken to belong to a request
+	// that died with the server, and is handed to the next retry
+	idempotencyLease     = 5 * time.Minute
+	maxIdempotencyKeyLen = 255
+	// Bodies are read whole to hash them; a full offline sync fits easily
+	maxIdempotentBodySize = 1 << 20
+)
+
+// responseRecorder keeps a copy of everything written to the response
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes requests carrying an Idempotency-Key header safe
+// to retry: the first response is stored and replayed for any repeat with the
+// same key and body. Must run after AuthMiddleware, keys are scoped per user.
+func IdempotencyMiddleware(idempotencyRepo *repositories.IdempotencyRepository) gin.HandlerFunc {
+	// Clear out old keys once in a while; Begin also drops a stale key on reuse
+	go func() {
+		for range time.Tick(time.Hour) {
+			if _, err := idempotencyRepo.DeleteExpired(idempotencyKeyTTL); err != nil {
+				log.Printf("Failed to delete expired idempotency keys: %v", err)
+			}
+		}
+	}()
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record, claimed, err := idempotencyRepo.Begin(userID, key, requestHash, idempotencyKeyTTL, idempotencyLease)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key", "details": err.Error()})
+			c.Abort()
+			return
+		}
+
+		if !claimed {
+			switch {
+			case record.RequestHash != requestHash:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case record.Status == models.IdempotencyProcessing:
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.ResponseStatus, "application/json; charset=utf-8", record.ResponseBody)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		// A panicking handler never finishes the request; free the key for a
+		// retry before gin.Recovery answers it
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if err := idempotencyRepo.Release(record.ID); err != nil {
+					log.Printf("Failed to release idempotency key %s: %v", key, err)
+				}
+				panic(recovered)
+			}
+		}()
+		c.Next()
+
+		// Server errors are not remembered so the client can retry them
+		if recorder.Status() >= http.StatusInternalServerError {
+			if err := idempotencyRepo.Release(record.ID); err != nil {
+				log.Printf("Failed to release idempotency key %s: %v", key, err)
+			}
+			return
+		}
+		if err := idempotencyRepo.Complete(record.ID, recorder.Status(), recorder.body.Bytes()); err != nil {
+			log.Printf("Failed to store response for idempotency key %s: %v", key, err)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/anidex/backend/internal/solar"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -17,6 +19,32 @@ const (
 	VerificationAuto     VerificationStatus = "auto_approved" // AI/ML auto-verification
 )
 
+// ModerationReason explains why a catch was routed to human moderators
+type ModerationReason string
+
+const (
+	ModerationPossibleDuplicate ModerationReason = "possible_duplicate" // Photo resembles another user's catch
+)
+
+// CatchVisibility controls who can see a catch
+type CatchVisibility string
+
+const (
+	VisibilityPublic         CatchVisibility = "public"          // Everyone
+	VisibilityLocationHidden CatchVisibility = "location_hidden" // Everyone, but only the owner sees where it was made
+	VisibilityFollowers      CatchVisibility = "followers"       // The owner's followers
+	VisibilityPrivate        CatchVisibility = "private"         // Only the owner
+)
+
+// IsValid returns true for the known visibility levels
+func (v CatchVisibility) IsValid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityLocationHidden, VisibilityFollowers, VisibilityPrivate:
+		return true
+	}
+	return false
+}
+
 // WeatherCondition represents weather during the catch
 type WeatherCondition string
 
@@ -34,13 +62,13 @@ const (
 type TimeOfDay string
 
 const (
-	TimeDawn      TimeOfDay = "dawn"      // 5-7 AM
-	TimeMorning   TimeOfDay = "morning"   // 7-11 AM
-	TimeNoon      TimeOfDay = "noon"      // 11 AM - 1 PM
-	TimeAfternoon TimeOfDay = "afternoon" // 1-5 PM
-	TimeEvening   TimeOfDay = "evening"   // 5-8 PM
-	TimeDusk      TimeOfDay = "dusk"      // 8-9 PM
-	TimeNight     TimeOfDay = "night"     // 9 PM - 5 AM
+	TimeDawn      TimeOfDay = "dawn"      // Civil dawn until an hour after sunrise
+	TimeMorning   TimeOfDay = "morning"   // Until an hour before solar noon
+	TimeNoon      TimeOfDay = "noon"      // Within an hour of solar noon
+	TimeAfternoon TimeOfDay = "afternoon" // Until two hours before sunset
+	TimeEvening   TimeOfDay = "evening"   // The two hours before sunset
+	TimeDusk      TimeOfDay = "dusk"      // Sunset until the end of civil twilight
+	TimeNight     TimeOfDay = "night"     // Sun more than 6 degrees below the horizon
 )
 
 // AnimalCatch represents an individual animal encounter/catch by a user
@@ -51,7 +79,9 @@ type AnimalCatch struct {
 	LocationID        uuid.UUID          `gorm:"type:uuid;not null" json:"location_id"`
 	
 	// User-generated content
+	PhotoID           *uuid.UUID         `gorm:"type:uuid;index" json:"photo_id"` // Uploaded photo backing UserPhotoURL
 	UserPhotoURL      string             `gorm:"not null" json:"user_photo_url"`
+	PerceptualHash    *int64             `gorm:"index" json:"-"` // dHash of the photo, for duplicate detection
 	UserNotes         string             `gorm:"type:text" json:"user_notes"`
 	UserRating        *int               `gorm:"check:user_rating >= 1 AND user_rating <= 5" json:"user_rating"` // 1-5 stars
 	
@@ -60,6 +90,11 @@ type AnimalCatch struct {
 	VerifiedBy        *uuid.UUID         `gorm:"type:uuid" json:"verified_by"` // Admin/Moderator who verified
 	VerifiedAt        *time.Time         `json:"verified_at"`
 	VerificationNotes string             `gorm:"type:text" json:"verification_notes"`
+	VerificationScore *float64           `json:"verification_score"` // Confidence from auto-verification (0-1)
+	LocationMismatch  bool               `gorm:"default:false;index" json:"location_mismatch"` // Photo GPS disagrees with the submitted location
+	ExifDistance      *float64           `json:"exif_distance"` // Distance in km between photo GPS and submitted location
+	ModerationReason  ModerationReason   `gorm:"type:varchar(50);index" json:"moderation_reason,omitempty"` // Set when auto-verification must not approve
+	DuplicateOf       *uuid.UUID         `gorm:"type:uuid" json:"duplicate_of,omitempty"` // Catch whose photo this one resembles
 	
 	// Environmental conditions
 	Weather           WeatherCondition   `gorm:"type:varchar(20)" json:"weather"`
@@ -69,10 +104,14 @@ type AnimalCatch struct {
 	// Game mechanics
 	PointsAwarded     int                `gorm:"default:0" json:"points_awarded"`
 	IsFirstCatch      bool               `gorm:"default:false" json:"is_first_catch"` // First time user caught this species
-	ComboMultiplier   float64            `gorm:"default:1.0" json:"combo_multiplier"` // Streak bonus
+	ComboMultiplier   float64            `gorm:"default:1.0" json:"combo_multiplier"` // First-catch and streak multipliers combined
+	PointsBreakdown   *PointsBreakdown   `gorm:"type:jsonb;serializer:json" json:"points_breakdown,omitempty"` // How PointsAwarded was computed
 	
 	// Social features
-	IsPublic          bool               `gorm:"default:true" json:"is_public"`
+	Visibility        CatchVisibility    `gorm:"type:varchar(20);default:'public';index" json:"visibility"`
+	IsPublic          bool               `gorm:"default:true" json:"is_public"` // Public and not obscured: listed at its location for everyone
+	LocationHidden    bool               `gorm:"-" json:"location_hidden,omitempty"` // Location was removed for the viewer
+	CoordinatesObscured bool             `gorm:"default:false;index" json:"coordinates_obscured"` // Sensitive species: the public only sees a generalized point
 	LikesCount        int                `gorm:"default:0" json:"likes_count"`
 	CommentsCount     int                `gorm:"default:0" json:"comments_count"`
 	SharesCount       int                `gorm:"default:0" json:"shares_count"`
@@ -87,12 +126,15 @@ type AnimalCatch struct {
 	Species           Species            `gorm:"foreignKey:SpeciesID" json:"species,omitempty"`
 	Location          Location           `gorm:"foreignKey:LocationID" json:"location,omitempty"`
 	VerifiedByUser    *User              `gorm:"foreignKey:VerifiedBy" json:"verified_by_user,omitempty"`
+	Photo             *Photo             `gorm:"foreignKey:PhotoID" json:"photo,omitempty"`
 	Comments          []CatchComment     `gorm:"foreignKey:CatchID" json:"comments,omitempty"`
 	Likes             []CatchLike        `gorm:"foreignKey:CatchID" json:"likes,omitempty"`
 }
 
 func (ac *AnimalCatch) BeforeCreate(tx *gorm.DB) error {
-	ac.ID = uuid.New()
+	if ac.ID == uuid.Nil { // Clients may supply their own ID for offline sync
+		ac.ID = uuid.New()
+	}
 	if ac.CaughtAt.IsZero() {
 		ac.CaughtAt = time.Now()
 	}
@@ -102,15 +144,16 @@ func (ac *AnimalCatch) BeforeCreate(tx *gorm.DB) error {
 // CatchComment represents comments on animal catches (social feature)
 type CatchComment struct {
 	ID        uuid.UUID   `gorm:"type:uuid;primary_key" json:"id"`
-	CatchID   uuid.UUID   `gorm:"type:uuid;not null;index" json:"catch_id"`
+	CatchID   uuid.UUID   `gorm:"type:uuid;not null;index:idx_catch_comments_catch_created" json:"catch_id"`
 	UserID    uuid.UUID   `gorm:"type:uuid;not null;index" json:"user_id"`
 	Content   string      `gorm:"type:text;not null" json:"content"`
-	CreatedAt time.Time   `json:"created_at"`
+	EditedAt  *time.Time  `json:"edited_at"` // Set when the author changed the content
+	CreatedAt time.Time   `gorm:"index:idx_catch_comments_catch_created" json:"created_at"`
 	UpdatedAt time.Time   `json:"updated_at"`
 	
 	// Relationships
 	User      User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Catch     AnimalCatch `gorm:"foreignKey:CatchID" json:"catch,omitempty"`
+	Catch     *AnimalCatch `gorm:"foreignKey:CatchID" json:"catch,omitempty"`
 }
 
 func (cc *CatchComment) BeforeCreate(tx *gorm.DB) error {
@@ -121,8 +164,8 @@ func (cc *CatchComment) BeforeCreate(tx *gorm.DB) error {
 // CatchLike represents likes on animal catches (social feature)
 type CatchLike struct {
 	ID        uuid.UUID   `gorm:"type:uuid;primary_key" json:"id"`
-	CatchID   uuid.UUID   `gorm:"type:uuid;not null;index" json:"catch_id"`
-	UserID    uuid.UUID   `gorm:"type:uuid;not null;index" json:"user_id"`
+	CatchID   uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_catch_likes_catch_user" json:"catch_id"`
+	UserID    uuid.UUID   `gorm:"type:uuid;not null;index;uniqueIndex:idx_catch_likes_catch_user" json:"user_id"`
 	CreatedAt time.Time   `json:"created_at"`
 	
 	// Relationships
@@ -135,28 +178,56 @@ func (cl *CatchLike) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// Unique constraint to prevent duplicate likes
+// TableName pins the table name; (catch_id, user_id) is unique, one like per user
 func (CatchLike) TableName() string {
 	return "catch_likes"
 }
 
-// GetTimeOfDayFromHour determines TimeOfDay from hour (0-23)
-func GetTimeOfDayFromHour(hour int) TimeOfDay {
+// GetTimeOfDay determines TimeOfDay from the position of the sun at the given
+// coordinates, so dawn and dusk follow the actual sunrise, sunset and civil
+// twilight there rather than fixed clock hours
+func GetTimeOfDay(caughtAt time.Time, lat, lng float64) TimeOfDay {
+	day := solar.ForInstant(caughtAt, lat, lng)
+	fromNoon := caughtAt.Sub(day.Noon)
+
+	// Polar night: at most a twilight glow around solar noon
+	if day.AlwaysDown {
+		if day.AlwaysDark || caughtAt.Before(day.CivilDawn) || caughtAt.After(day.CivilDusk) {
+			return TimeNight
+		}
+		if fromNoon < 0 {
+			return TimeDawn
+		}
+		return TimeDusk
+	}
+
+	if !day.AlwaysUp {
+		// Twilight can last all night in summer at high latitudes
+		if !day.AlwaysLight && (caughtAt.Before(day.CivilDawn) || caughtAt.After(day.CivilDusk)) {
+			return TimeNight
+		}
+		switch {
+		case caughtAt.Before(day.Sunrise.Add(time.Hour)):
+			return TimeDawn
+		case caughtAt.After(day.Sunset):
+			return TimeDusk
+		case fromNoon.Abs() <= time.Hour:
+			return TimeNoon
+		case caughtAt.After(day.Sunset.Add(-2 * time.Hour)):
+			return TimeEvening
+		}
+	}
+
+	// Midnight sun, or daytime between the bands above
 	switch {
-	case hour >= 5 && hour < 7:
-		return TimeDawn
-	case hour >= 7 && hour < 11:
-		return TimeMorning
-	case hour >= 11 && hour < 13:
+	case fromNoon.Abs() <= time.Hour:
 		return TimeNoon
-	case hour >= 13 && hour < 17:
-		return TimeAfternoon
-	case hour >= 17 && hour < 20:
+	case fromNoon < 0:
+		return TimeMorning
+	case day.AlwaysUp && fromNoon > 5*time.Hour:
 		return TimeEvening
-	case hour >= 20 && hour < 21:
-		return TimeDusk
 	default:
-		return TimeNight
+		return TimeAfternoon
 	}
 }
 
@@ -166,7 +237,99 @@ func (ac *AnimalCatch) IsVerified() bool {
 		   ac.VerificationStatus == VerificationAuto
 }
 
+// RequiresModeration returns true if only a moderator may verify the catch
+func (ac *AnimalCatch) RequiresModeration() bool {
+	return ac.ModerationReason != ""
+}
+
 // CanEdit returns true if the catch can still be edited (not yet verified)
 func (ac *AnimalCatch) CanEdit() bool {
 	return ac.VerificationStatus == VerificationPending
 }
+
+// Viewer is who a catch is being shown to. The zero value is an anonymous viewer.
+type Viewer struct {
+	UserID    uuid.UUID
+	Moderator bool
+}
+
+// Owns returns true if the viewer made the catch
+func (v Viewer) Owns(ac *AnimalCatch) bool {
+	return v.UserID != uuid.Nil && v.UserID == ac.UserID
+}
+
+// SetVisibility changes who can see the catch, keeping IsPublic in sync
+func (ac *AnimalCatch) SetVisibility(visibility CatchVisibility) {
+	ac.Visibility = visibility
+	ac.IsPublic = visibility == VisibilityPublic && !ac.CoordinatesObscured
+}
+
+// ObscureCoordinates marks the catch as a sighting whose exact location is only
+// shown to its owner and moderators
+func (ac *AnimalCatch) ObscureCoordinates() {
+	ac.CoordinatesObscured = true
+	ac.SetVisibility(ac.Visibility)
+}
+
+// VisibleTo returns true if the viewer may see the catch; isFollower tells
+// whether they follow its owner
+func (ac *AnimalCatch) VisibleTo(viewer Viewer, isFollower bool) bool {
+	switch {
+	case viewer.Owns(ac), viewer.Moderator:
+		return true
+	case ac.Visibility == VisibilityFollowers:
+		return isFollower
+	case ac.Visibility == VisibilityPrivate:
+		return false
+	default:
+		return true
+	}
+}
+
+// RedactFor removes what the viewer may not see from a catch they can see: the
+// location if the owner hid it, or the exact coordinates of a sensitive sighting.
+// Owners and moderators see everything.
+func (ac *AnimalCatch) RedactFor(viewer Viewer) {
+	if viewer.Owns(ac) || viewer.Moderator {
+		return
+	}
+
+	if ac.Visibility == VisibilityLocationHidden {
+		ac.LocationID = uuid.Nil
+		ac.Location = Location{}
+		ac.ExifDistance = nil
+		ac.LocationHidden = true
+		return
+	}
+
+	// Species may have been flagged after the catch was made
+	sensitive := ac.CoordinatesObscured || (ac.Species.ID != uuid.Nil && ac.Species.RequiresGeoprivacy())
+	if sensitive {
+		ac.CoordinatesObscured = true
+		ac.ExifDistance = nil
+		ac.LocationID = uuid.Nil
+		if ac.Location.ID != uuid.Nil {
+			ac.Location = ac.Location.Generalized(ac.ID[:])
+		}
+	}
+}
+
+// MarshalJSON leaves out the location fields once they have been redacted
+func (ac AnimalCatch) MarshalJSON() ([]byte, error) {
+	type plain AnimalCatch
+	switch {
+	case ac.LocationHidden:
+		return json.Marshal(struct {
+			plain
+			LocationID *uuid.UUID `json:"location_id,omitempty"`
+			Location   *Location  `json:"location,omitempty"`
+		}{plain: plain(ac)})
+	case ac.CoordinatesObscured && ac.LocationID == uuid.Nil:
+		return json.Marshal(struct {
+			plain
+			LocationID *uuid.UUID `json:"location_id,omitempty"`
+		}{plain: plain(ac)})
+	default:
+		return json.Marshal(plain(ac))
+	}
+}
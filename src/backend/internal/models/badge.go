@@ -52,12 +52,8 @@ type Badge struct {
 	IconURL       string      `json:"icon_url"`
 	Color         string      `gorm:"type:varchar(7)" json:"color"` // Hex color code
 	
-	// Requirements (stored as JSON or specific fields)
-	RequiredCount *int        `json:"required_count"` // For count-based badges
-	RequiredSpecies []string  `gorm:"type:text[]" json:"required_species"` // Species IDs
-	RequiredLocationType *LocationType `gorm:"type:varchar(20)" json:"required_location_type"`
-	RequiredRarity *Rarity   `gorm:"type:varchar(20)" json:"required_rarity"`
-	RequiredDays  *int        `json:"required_days"` // For streak badges
+	// Requirements
+	Rule          *BadgeRule  `gorm:"type:jsonb;serializer:json" json:"rule"` // What earns the badge; badges without one can't be earned
 	
 	// Points and rewards
 	PointsAwarded int         `gorm:"default:0" json:"points_awarded"`
@@ -77,6 +73,33 @@ func (b *Badge) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BadgeRequest creates a badge, or replaces everything about one, including
+// its rule
+type BadgeRequest struct {
+	Name          string      `json:"name" binding:"required,max=100"`
+	Description   string      `json:"description" binding:"required"`
+	ShortDesc     string      `json:"short_desc" binding:"required,max=255"`
+	BadgeType     BadgeType   `json:"badge_type" binding:"required,oneof=species location count rarity streak social conservation time seasonal special"`
+	BadgeRarity   BadgeRarity `json:"badge_rarity" binding:"omitempty,oneof=bronze silver gold platinum diamond"`
+	IconURL       string      `json:"icon_url" binding:"omitempty,url"`
+	Color         string      `json:"color" binding:"omitempty,hexcolor"`
+	Rule          *BadgeRule  `json:"rule" binding:"required"`
+	PointsAwarded int         `json:"points_awarded" binding:"min=0"`
+	IsSecret      bool        `json:"is_secret"`
+	IsActive      *bool       `json:"is_active"` // Defaults to true
+}
+
+// BadgeRuleRequest checks a badge rule
+type BadgeRuleRequest struct {
+	Rule *BadgeRule `json:"rule" binding:"required"`
+}
+
+// EvaluateBadgeRuleRequest measures a user against a badge rule
+type EvaluateBadgeRuleRequest struct {
+	Rule   *BadgeRule `json:"rule" binding:"required"`
+	UserID uuid.UUID  `json:"user_id" binding:"required"`
+}
+
 // UserBadge represents a badge earned by a user
 type UserBadge struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
@@ -148,6 +171,8 @@ type UserStats struct {
 	CurrentStreak     int       `gorm:"default:0" json:"current_streak"`
 	LongestStreak     int       `gorm:"default:0" json:"longest_streak"`
 	LastCatchDate     *time.Time `json:"last_catch_date"`
+	LastStreakDate    *time.Time `json:"last_streak_date"` // Last day the streak covers, by a catch or a freeze
+	StreakFreezes     int       `gorm:"default:0" json:"streak_freezes"` // Held, each covers a missed day
 	
 	// Rarity catches
 	CommonCatches     int       `gorm:"default:0" json:"common_catches"`
@@ -168,7 +193,7 @@ type UserStats struct {
 	// Location statistics
 	CountriesVisited  int       `gorm:"default:0" json:"countries_visited"`
 	CitiesVisited     int       `gorm:"default:0" json:"cities_visited"`
-	FarthestDistance  float64   `gorm:"default:0" json:"farthest_distance"` // km from home
+	FarthestDistance  float64   `gorm:"default:0" json:"farthest_distance"` // km from home, where the user's first catch was made
 	
 	// Social statistics
 	BadgesEarned      int       `gorm:"default:0" json:"badges_earned"`
@@ -177,10 +202,13 @@ type UserStats struct {
 	TotalLikes        int       `gorm:"default:0" json:"total_likes"` // Likes received
 	TotalComments     int       `gorm:"default:0" json:"total_comments"` // Comments received
 	
-	// Rankings
+	// Rankings by all-time points, set by the ranking job. Users without points
+	// aren't ranked.
 	GlobalRank        *int      `json:"global_rank"`
 	CountryRank       *int      `json:"country_rank"`
 	CityRank          *int      `json:"city_rank"`
+	HomeCountry       string    `gorm:"index:idx_user_stats_home" json:"home_country"` // Where most of the user's catches were made
+	HomeCity          string    `gorm:"index:idx_user_stats_home" json:"home_city"`    // Where most of their catches in HomeCountry were made
 	
 	// Metadata
 	LastUpdated       time.Time `json:"last_updated"`
@@ -212,3 +240,169 @@ func (us *UserStats) GetPointsToNextLevel() int {
 	nextLevelPoints := (currentLevel * currentLevel) * 100
 	return nextLevelPoints - us.TotalPoints
 }
+
+// RecordCatch adds a new catch to the statistics. day is the streak day of the
+// catch, from StreakDay, used to advance the daily streak.
+func (us *UserStats) RecordCatch(species *Species, points int, newSpecies bool, day time.Time) {
+	us.TotalCatches++
+	us.TotalPoints += points
+	if newSpecies {
+		us.UniqueSpecies++
+	}
+	*us.rarityCatches(species.Rarity)++
+	*us.categoryCatches(species.Category)++
+
+	us.recordCatchDay(day)
+}
+
+// RemoveCatch takes a catch that no longer counts, deleted or rejected, out of
+// the statistics. lastOfSpecies is whether it was the user's only catch of its
+// species. Streaks are left alone; ReplayStreak recomputes them.
+func (us *UserStats) RemoveCatch(species *Species, points int, lastOfSpecies bool) {
+	us.TotalCatches = max(us.TotalCatches-1, 0)
+	us.TotalPoints = max(us.TotalPoints-points, 0)
+	if lastOfSpecies {
+		us.UniqueSpecies = max(us.UniqueSpecies-1, 0)
+	}
+	rarity := us.rarityCatches(species.Rarity)
+	*rarity = max(*rarity-1, 0)
+	category := us.categoryCatches(species.Category)
+	*category = max(*category-1, 0)
+}
+
+// ReplayStreak recomputes the streaks from scratch from the streak days the
+// user caught something on and the missed days freezes covered, each oldest
+// first. Freezes held are left alone.
+func (us *UserStats) ReplayStreak(days, frozen []time.Time) {
+	us.CurrentStreak = 0
+	us.LongestStreak = 0
+	us.LastCatchDate = nil
+	us.LastStreakDate = nil
+	for len(days) > 0 || len(frozen) > 0 {
+		if len(frozen) > 0 && (len(days) == 0 || frozen[0].Before(days[0])) {
+			us.recordFrozenDay(frozen[0])
+			frozen = frozen[1:]
+		} else {
+			us.recordCatchDay(days[0])
+			days = days[1:]
+		}
+	}
+}
+
+// CoverMissedDays covers the days after the last one of the streak, up to and
+// including through, with freezes, one a day, and returns them. If there
+// aren't enough freezes the streak ends instead.
+func (us *UserStats) CoverMissedDays(through time.Time) []time.Time {
+	if us.CurrentStreak == 0 || us.LastStreakDate == nil {
+		return nil
+	}
+	through = calendarDay(through)
+	last := calendarDay(us.LastStreakDate.UTC())
+
+	var missed []time.Time
+	for day := last.AddDate(0, 0, 1); !day.After(through); day = day.AddDate(0, 0, 1) {
+		missed = append(missed, day)
+		if len(missed) > us.StreakFreezes {
+			us.CurrentStreak = 0
+			return nil
+		}
+	}
+	if len(missed) > 0 {
+		us.StreakFreezes -= len(missed)
+		us.LastStreakDate = &through
+	}
+	return missed
+}
+
+// StreakEndingOn returns the length of the current streak if its last day is
+// day, a streak day, or 0
+func (us *UserStats) StreakEndingOn(day time.Time) int {
+	if us.LastStreakDate == nil || !calendarDay(us.LastStreakDate.UTC()).Equal(calendarDay(day)) {
+		return 0
+	}
+	return us.CurrentStreak
+}
+
+// RecordDistance raises FarthestDistance to a catch made km from home
+func (us *UserStats) RecordDistance(km float64) {
+	if km > us.FarthestDistance {
+		us.FarthestDistance = km
+	}
+}
+
+// rarityCatches returns the counter of catches of the given rarity
+func (us *UserStats) rarityCatches(rarity Rarity) *int {
+	switch rarity {
+	case RarityUncommon:
+		return &us.UncommonCatches
+	case RarityRare:
+		return &us.RareCatches
+	case RarityEpic:
+		return &us.EpicCatches
+	case RarityLegendary:
+		return &us.LegendaryCatches
+	default:
+		return &us.CommonCatches
+	}
+}
+
+// categoryCatches returns the counter of catches of the given category
+func (us *UserStats) categoryCatches(category AnimalCategory) *int {
+	switch category {
+	case CategoryMammal:
+		return &us.MammalCatches
+	case CategoryBird:
+		return &us.BirdCatches
+	case CategoryReptile:
+		return &us.ReptileCatches
+	case CategoryAmphibian:
+		return &us.AmphibianCatches
+	case CategoryFish:
+		return &us.FishCatches
+	case CategoryInsect:
+		return &us.InsectCatches
+	default:
+		return &us.OtherCatches
+	}
+}
+
+// recordCatchDay advances the streak for a catch on the given day. A catch on
+// an earlier day than the last one (synced late from offline) leaves it alone.
+// Missed days must have been covered with CoverMissedDays first.
+func (us *UserStats) recordCatchDay(day time.Time) {
+	day = calendarDay(day)
+	if us.LastCatchDate == nil || day.After(calendarDay(us.LastCatchDate.UTC())) {
+		lastCatch := day
+		us.LastCatchDate = &lastCatch
+	}
+
+	if us.LastStreakDate != nil {
+		last := calendarDay(us.LastStreakDate.UTC())
+		switch {
+		case !day.After(last):
+			return
+		case us.CurrentStreak > 0 && day.Equal(last.AddDate(0, 0, 1)):
+			us.CurrentStreak++
+		default:
+			us.CurrentStreak = 1
+		}
+	} else {
+		us.CurrentStreak = 1
+	}
+
+	us.LastStreakDate = &day
+	if us.CurrentStreak > us.LongestStreak {
+		us.LongestStreak = us.CurrentStreak
+	}
+}
+
+// recordFrozenDay extends the streak over a missed day a freeze covered
+func (us *UserStats) recordFrozenDay(day time.Time) {
+	if us.CurrentStreak == 0 || us.LastStreakDate == nil {
+		return
+	}
+	day = calendarDay(day)
+	if day.Equal(calendarDay(us.LastStreakDate.UTC()).AddDate(0, 0, 1)) {
+		us.LastStreakDate = &day
+	}
+}
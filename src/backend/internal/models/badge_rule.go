@@ -0,0 +1,429 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Limits on the size of a badge rule, so that every rule stays cheap to evaluate
+const (
+	maxRuleRequirements   = 5
+	maxRuleConditionDepth = 5
+	maxRuleConditions     = 50
+	maxRuleListValues     = 100
+)
+
+// BadgeRule is what it takes to earn a badge: every one of its requirements.
+// Rules are stored as JSON, for example "catch 3 owls at night in winter":
+//
+//	{"requirements": [{
+//	  "count": "catches",
+//	  "where": {"all": [
+//	    {"field": "species.family", "op": "eq", "value": "Strigidae"},
+//	    {"field": "catch.time_of_day", "op": "eq", "value": "night"},
+//	    {"field": "catch.season", "op": "eq", "value": "winter"}
+//	  ]},
+//	  "target": 3
+//	}]}
+//
+// and "5 endangered species on 3 continents" is two requirements counting
+// species and continents over the same condition.
+type BadgeRule struct {
+	Requirements []RuleRequirement `json:"requirements"`
+}
+
+// RuleRequirement counts something about the user's catches, those matching
+// Where if it is set, and is met once the count reaches Target. With TargetAll,
+// only for species, the target is every active species matching Where.
+type RuleRequirement struct {
+	Count     RuleMeasure    `json:"count"`
+	Where     *RuleCondition `json:"where,omitempty"`
+	Target    int            `json:"target,omitempty"`
+	TargetAll bool           `json:"target_all,omitempty"`
+}
+
+// RuleCondition is a test on one field of a catch, its species or its location,
+// or a combination of other conditions. Exactly one of All, Any, Not or Field is set.
+type RuleCondition struct {
+	All   []RuleCondition `json:"all,omitempty"`
+	Any   []RuleCondition `json:"any,omitempty"`
+	Not   *RuleCondition  `json:"not,omitempty"`
+	Field RuleField       `json:"field,omitempty"`
+	Op    RuleOp          `json:"op,omitempty"`
+	Value interface{}     `json:"value,omitempty"` // A list for in and not_in
+}
+
+// RuleMeasure is what a requirement counts
+type RuleMeasure string
+
+const (
+	// Counted over the user's catches that aren't rejected, filtered by Where
+	MeasureCatches       RuleMeasure = "catches"        // Catches
+	MeasureSpecies       RuleMeasure = "species"        // Distinct species
+	MeasureFamilies      RuleMeasure = "families"       // Distinct species families
+	MeasureCategories    RuleMeasure = "categories"     // Distinct animal categories
+	MeasureLocations     RuleMeasure = "locations"      // Distinct locations
+	MeasureCountries     RuleMeasure = "countries"      // Distinct countries
+	MeasureCities        RuleMeasure = "cities"         // Distinct cities
+	MeasureContinents    RuleMeasure = "continents"     // Distinct continents
+	MeasureLocationTypes RuleMeasure = "location_types" // Distinct kinds of places
+	MeasureWeatherTypes  RuleMeasure = "weather_types"  // Distinct weather conditions
+	MeasureSeasons       RuleMeasure = "seasons"        // Distinct seasons
+	MeasureDays          RuleMeasure = "days"           // Distinct local calendar days
+	MeasureFirstCatch    RuleMeasure = "first_catch"    // 1 if the user's first catch matches Where
+
+	// Taken from the user's stats and activity; Where doesn't apply
+	MeasureLongestStreak    RuleMeasure = "longest_streak"    // Longest run of days with a catch
+	MeasureLikesReceived    RuleMeasure = "likes_received"    // Likes on the user's catches by others
+	MeasureCommentsReceived RuleMeasure = "comments_received" // Comments on the user's catches by others
+	MeasureFollowers        RuleMeasure = "followers"         // Accepted followers
+	MeasureCatchesReviewed  RuleMeasure = "catches_reviewed"  // Other users' catches the user verified
+)
+
+// catchMeasures are the measures counted over catches, which Where filters
+var catchMeasures = map[RuleMeasure]bool{
+	MeasureCatches: true, MeasureSpecies: true, MeasureFamilies: true, MeasureCategories: true,
+	MeasureLocations: true, MeasureCountries: true, MeasureCities: true, MeasureContinents: true,
+	MeasureLocationTypes: true, MeasureWeatherTypes: true, MeasureSeasons: true, MeasureDays: true,
+	MeasureFirstCatch: true,
+}
+
+var statMeasures = map[RuleMeasure]bool{
+	MeasureLongestStreak: true, MeasureLikesReceived: true, MeasureCommentsReceived: true,
+	MeasureFollowers: true, MeasureCatchesReviewed: true,
+}
+
+// IsCatchMeasure returns true for the measures counted over the user's catches
+func (m RuleMeasure) IsCatchMeasure() bool {
+	return catchMeasures[m]
+}
+
+// RuleOp compares a field with a condition's value
+type RuleOp string
+
+const (
+	OpEq    RuleOp = "eq"
+	OpNe    RuleOp = "ne"
+	OpIn    RuleOp = "in"
+	OpNotIn RuleOp = "not_in"
+	OpGt    RuleOp = "gt"
+	OpGte   RuleOp = "gte"
+	OpLt    RuleOp = "lt"
+	OpLte   RuleOp = "lte"
+)
+
+// RuleField is an attribute of a catch, its species or its location that
+// conditions can test. Times are local to where the catch was made.
+type RuleField string
+
+const (
+	FieldCatchTimeOfDay           RuleField = "catch.time_of_day"
+	FieldCatchWeather             RuleField = "catch.weather"
+	FieldCatchSeason              RuleField = "catch.season" // Meteorological, flipped south of the equator
+	FieldCatchMonth               RuleField = "catch.month"  // 1 to 12
+	FieldCatchHour                RuleField = "catch.hour"   // 0 to 23
+	FieldCatchMinutesFromMidnight RuleField = "catch.minutes_from_midnight"
+	FieldCatchTemperature         RuleField = "catch.temperature" // Celsius
+	FieldCatchVisibility          RuleField = "catch.visibility"
+	FieldCatchIsFirstCatch        RuleField = "catch.is_first_catch" // The user's first of the species
+	FieldCatchVerificationStatus  RuleField = "catch.verification_status"
+	FieldSpeciesID                RuleField = "species.id"
+	FieldSpeciesCategory          RuleField = "species.category"
+	FieldSpeciesFamily            RuleField = "species.family"
+	FieldSpeciesGenus             RuleField = "species.genus"
+	FieldSpeciesRarity            RuleField = "species.rarity"
+	FieldSpeciesConservation      RuleField = "species.conservation_status"
+	FieldLocationType             RuleField = "location.type"
+	FieldLocationCountry          RuleField = "location.country"
+	FieldLocationCountryCode      RuleField = "location.country_code"
+	FieldLocationCity             RuleField = "location.city"
+	FieldLocationContinent        RuleField = "location.continent"
+)
+
+// Continents are the values of location.continent, worked out from the time
+// zone of the location
+const (
+	ContinentAfrica       = "africa"
+	ContinentAntarctica   = "antarctica"
+	ContinentAsia         = "asia"
+	ContinentEurope       = "europe"
+	ContinentNorthAmerica = "north_america"
+	ContinentOceania      = "oceania"
+	ContinentSouthAmerica = "south_america"
+)
+
+// Seasons are the values of catch.season
+const (
+	SeasonWinter = "winter"
+	SeasonSpring = "spring"
+	SeasonSummer = "summer"
+	SeasonAutumn = "autumn"
+)
+
+type ruleValueKind int
+
+const (
+	ruleString ruleValueKind = iota
+	ruleNumber
+	ruleBool
+	ruleUUID
+)
+
+// ruleFieldSpec is the kind of value a field holds and, for enumerations, the
+// values it can take
+type ruleFieldSpec struct {
+	kind   ruleValueKind
+	values []string
+}
+
+var ruleFields = map[RuleField]ruleFieldSpec{
+	FieldCatchTimeOfDay: {ruleString, []string{
+		string(TimeDawn), string(TimeMorning), string(TimeNoon), string(TimeAfternoon),
+		string(TimeEvening), string(TimeDusk), string(TimeNight),
+	}},
+	FieldCatchWeather: {ruleString, []string{
+		string(WeatherSunny), string(WeatherCloudy), string(WeatherRainy), string(WeatherSnowy),
+		string(WeatherFoggy), string(WeatherStorm), string(WeatherUnknown),
+	}},
+	FieldCatchSeason:              {ruleString, []string{SeasonWinter, SeasonSpring, SeasonSummer, SeasonAutumn}},
+	FieldCatchMonth:               {kind: ruleNumber},
+	FieldCatchHour:                {kind: ruleNumber},
+	FieldCatchMinutesFromMidnight: {kind: ruleNumber},
+	FieldCatchTemperature:         {kind: ruleNumber},
+	FieldCatchVisibility: {ruleString, []string{
+		string(VisibilityPublic), string(VisibilityLocationHidden), string(VisibilityFollowers), string(VisibilityPrivate),
+	}},
+	FieldCatchIsFirstCatch: {kind: ruleBool},
+	FieldCatchVerificationStatus: {ruleString, []string{
+		string(VerificationPending), string(VerificationApproved), string(VerificationAuto),
+	}},
+	FieldSpeciesID: {kind: ruleUUID},
+	FieldSpeciesCategory: {ruleString, []string{
+		string(CategoryMammal), string(CategoryBird), string(CategoryReptile), string(CategoryAmphibian),
+		string(CategoryFish), string(CategoryInsect), string(CategoryArachnid), string(CategoryMollusk),
+		string(CategoryCrustacean), string(CategoryOther),
+	}},
+	FieldSpeciesFamily: {kind: ruleString},
+	FieldSpeciesGenus:  {kind: ruleString},
+	FieldSpeciesRarity: {ruleString, []string{
+		string(RarityCommon), string(RarityUncommon), string(RarityRare), string(RarityEpic), string(RarityLegendary),
+	}},
+	FieldSpeciesConservation: {ruleString, []string{
+		string(StatusNotEvaluated), string(StatusDataDeficient), string(StatusLeastConcern), string(StatusNearThreatened),
+		string(StatusVulnerable), string(StatusEndangered), string(StatusCriticallyEndangered),
+		string(StatusExtinctInWild), string(StatusExtinct),
+	}},
+	FieldLocationType: {ruleString, []string{
+		string(LocationWilderness), string(LocationUrban), string(LocationSuburban), string(LocationZoo),
+		string(LocationAquarium), string(LocationSanctuary), string(LocationFarm), string(LocationBeach),
+		string(LocationMountain), string(LocationDesert), string(LocationForest), string(LocationWetland),
+		string(LocationOcean), string(LocationRiver), string(LocationLake), string(LocationOther),
+	}},
+	FieldLocationCountry:     {kind: ruleString},
+	FieldLocationCountryCode: {kind: ruleString},
+	FieldLocationCity:        {kind: ruleString},
+	FieldLocationContinent: {ruleString, []string{
+		ContinentAfrica, ContinentAntarctica, ContinentAsia, ContinentEurope,
+		ContinentNorthAmerica, ContinentOceania, ContinentSouthAmerica,
+	}},
+}
+
+// IsSpeciesField returns true for the fields of the species rather than the
+// catch or location
+func (f RuleField) IsSpeciesField() bool {
+	return strings.HasPrefix(string(f), "species.")
+}
+
+// RuleError describes what is wrong with a badge rule and where
+type RuleError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *RuleError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+func ruleErrorf(path, format string, args ...interface{}) *RuleError {
+	return &RuleError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validate checks that the rule can be evaluated, returning a *RuleError
+// pointing at the first problem found
+func (r *BadgeRule) Validate() error {
+	if len(r.Requirements) == 0 {
+		return ruleErrorf("requirements", "at least one requirement is needed")
+	}
+	if len(r.Requirements) > maxRuleRequirements {
+		return ruleErrorf("requirements", "at most %d requirements are allowed", maxRuleRequirements)
+	}
+	for i := range r.Requirements {
+		if err := r.Requirements[i].validate(fmt.Sprintf("requirements[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *RuleRequirement) validate(path string) error {
+	switch {
+	case catchMeasures[q.Count]:
+	case statMeasures[q.Count]:
+		if q.Where != nil {
+			return ruleErrorf(path+".where", "%s can't be filtered", q.Count)
+		}
+	case q.Count == "":
+		return ruleErrorf(path+".count", "a measure is needed")
+	default:
+		return ruleErrorf(path+".count", "unknown measure %q", q.Count)
+	}
+
+	switch {
+	case q.TargetAll && q.Target != 0:
+		return ruleErrorf(path+".target", "can't be set with target_all")
+	case q.TargetAll && q.Count != MeasureSpecies:
+		return ruleErrorf(path+".target_all", "only applies to species")
+	case q.Count == MeasureFirstCatch && q.Target != 1:
+		return ruleErrorf(path+".target", "must be 1 for first_catch")
+	case !q.TargetAll && q.Target < 1:
+		return ruleErrorf(path+".target", "must be at least 1")
+	}
+
+	if q.Where == nil {
+		return nil
+	}
+	count := 0
+	if err := q.Where.validate(path+".where", 1, &count); err != nil {
+		return err
+	}
+	if q.TargetAll {
+		return q.Where.checkSpeciesOnly(path + ".where")
+	}
+	return nil
+}
+
+func (c *RuleCondition) validate(path string, depth int, count *int) error {
+	*count++
+	if *count > maxRuleConditions {
+		return ruleErrorf(path, "at most %d conditions are allowed", maxRuleConditions)
+	}
+	if depth > maxRuleConditionDepth {
+		return ruleErrorf(path, "conditions can be nested at most %d deep", maxRuleConditionDepth)
+	}
+
+	set := 0
+	for _, present := range []bool{c.All != nil, c.Any != nil, c.Not != nil, c.Field != ""} {
+		if present {
+			set++
+		}
+	}
+	if set != 1 {
+		return ruleErrorf(path, "exactly one of all, any, not or field is needed")
+	}
+
+	switch {
+	case c.All != nil || c.Any != nil:
+		children, key := c.All, "all"
+		if c.Any != nil {
+			children, key = c.Any, "any"
+		}
+		if len(children) == 0 {
+			return ruleErrorf(path+"."+key, "at least one condition is needed")
+		}
+		for i := range children {
+			if err := children[i].validate(fmt.Sprintf("%s.%s[%d]", path, key, i), depth+1, count); err != nil {
+				return err
+			}
+		}
+		return nil
+	case c.Not != nil:
+		return c.Not.validate(path+".not", depth+1, count)
+	}
+
+	spec, ok := ruleFields[c.Field]
+	if !ok {
+		return ruleErrorf(path+".field", "unknown field %q", c.Field)
+	}
+	switch c.Op {
+	case OpEq, OpNe:
+		return spec.check(path+".value", c.Value)
+	case OpIn, OpNotIn:
+		values, ok := c.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return ruleErrorf(path+".value", "%s needs a non-empty list", c.Op)
+		}
+		if len(values) > maxRuleListValues {
+			return ruleErrorf(path+".value", "at most %d values are allowed", maxRuleListValues)
+		}
+		for i, value := range values {
+			if err := spec.check(fmt.Sprintf("%s.value[%d]", path, i), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OpGt, OpGte, OpLt, OpLte:
+		if spec.kind != ruleNumber {
+			return ruleErrorf(path+".op", "%s only compares numbers", c.Op)
+		}
+		return spec.check(path+".value", c.Value)
+	case "":
+		return ruleErrorf(path+".op", "an operator is needed")
+	}
+	return ruleErrorf(path+".op", "unknown operator %q", c.Op)
+}
+
+// check validates a single value against the field
+func (s ruleFieldSpec) check(path string, value interface{}) error {
+	switch s.kind {
+	case ruleNumber:
+		if _, ok := value.(float64); !ok {
+			return ruleErrorf(path, "must be a number")
+		}
+	case ruleBool:
+		if _, ok := value.(bool); !ok {
+			return ruleErrorf(path, "must be true or false")
+		}
+	case ruleUUID:
+		text, ok := value.(string)
+		if !ok {
+			return ruleErrorf(path, "must be an ID")
+		}
+		if _, err := uuid.Parse(text); err != nil {
+			return ruleErrorf(path, "must be an ID")
+		}
+	default:
+		text, ok := value.(string)
+		if !ok {
+			return ruleErrorf(path, "must be a string")
+		}
+		if len(s.values) > 0 && !slices.Contains(s.values, text) {
+			return ruleErrorf(path, "must be one of %s", strings.Join(s.values, ", "))
+		}
+	}
+	return nil
+}
+
+// checkSpeciesOnly rejects conditions on anything but the species, which is
+// all a target of every species can be narrowed by
+func (c *RuleCondition) checkSpeciesOnly(path string) error {
+	for i := range c.All {
+		if err := c.All[i].checkSpeciesOnly(fmt.Sprintf("%s.all[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	for i := range c.Any {
+		if err := c.Any[i].checkSpeciesOnly(fmt.Sprintf("%s.any[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	if c.Not != nil {
+		return c.Not.checkSpeciesOnly(path + ".not")
+	}
+	if c.Field != "" && !c.Field.IsSpeciesField() {
+		return ruleErrorf(path+".field", "target_all can only be narrowed by species fields")
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// nestedNot returns a condition on a field wrapped in depth-1 nots
+func nestedNot(depth int) string {
+	condition := `{"field": "species.rarity", "op": "eq", "value": "rare"}`
+	for i := 1; i < depth; i++ {
+		condition = `{"not": ` + condition + `}`
+	}
+	return condition
+}
+
+// anyOf returns an any condition of n conditions on a field
+func anyOf(n int) string {
+	conditions := make([]string, n)
+	for i := range conditions {
+		conditions[i] = `{"field": "catch.hour", "op": "gte", "value": 1}`
+	}
+	return `{"any": [` + strings.Join(conditions, ", ") + `]}`
+}
+
+// requirements returns n copies of a valid requirement
+func requirements(n int) string {
+	copies := make([]string, n)
+	for i := range copies {
+		copies[i] = `{"count": "catches", "target": 1}`
+	}
+	return strings.Join(copies, ", ")
+}
+
+// where returns a rule counting catches that match the condition
+func where(condition string) string {
+	return `{"requirements": [{"count": "catches", "where": ` + condition + `, "target": 1}]}`
+}
+
+func TestBadgeRuleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		wantPath string // Empty if the rule is valid
+	}{
+		{"example rule", `{"requirements": [{"count": "catches", "where": {"all": [
+			{"field": "species.family", "op": "eq", "value": "Strigidae"},
+			{"field": "catch.time_of_day", "op": "eq", "value": "night"},
+			{"field": "catch.season", "op": "eq", "value": "winter"}
+		]}, "target": 3}]}`, ""},
+		{"stat measure", `{"requirements": [{"count": "followers", "target": 10}]}`, ""},
+
+		{"no requirements", `{"requirements": []}`, "requirements"},
+		{"most requirements", `{"requirements": [` + requirements(maxRuleRequirements) + `]}`, ""},
+		{"too many requirements", `{"requirements": [` + requirements(maxRuleRequirements+1) + `]}`, "requirements"},
+
+		{"no measure", `{"requirements": [{"target": 1}]}`, "requirements[0].count"},
+		{"unknown measure", `{"requirements": [{"count": "owls", "target": 1}]}`, "requirements[0].count"},
+		{"filtered stat measure", `{"requirements": [{"count": "followers", "where": ` + nestedNot(1) + `, "target": 1}]}`, "requirements[0].where"},
+		{"no target", `{"requirements": [{"count": "catches"}]}`, "requirements[0].target"},
+		{"first catch target", `{"requirements": [{"count": "first_catch", "target": 2}]}`, "requirements[0].target"},
+
+		{"target all species", `{"requirements": [{"count": "species", "target_all": true, "where": {"all": [
+			{"field": "species.category", "op": "eq", "value": "bird"},
+			{"not": {"field": "species.rarity", "op": "in", "value": ["common", "uncommon"]}}
+		]}}]}`, ""},
+		{"target all with target", `{"requirements": [{"count": "species", "target_all": true, "target": 3}]}`, "requirements[0].target"},
+		{"target all catches", `{"requirements": [{"count": "catches", "target_all": true}]}`, "requirements[0].target_all"},
+		{"target all by location", `{"requirements": [{"count": "species", "target_all": true, "where": {"all": [
+			{"field": "species.category", "op": "eq", "value": "bird"},
+			{"not": {"field": "location.continent", "op": "eq", "value": "europe"}}
+		]}}]}`, "requirements[0].where.all[1].not.field"},
+
+		{"deepest nesting", where(nestedNot(maxRuleConditionDepth)), ""},
+		{"nested too deep", where(nestedNot(maxRuleConditionDepth + 1)), "requirements[0].where" + strings.Repeat(".not", maxRuleConditionDepth)},
+		{"most conditions", where(anyOf(maxRuleConditions - 1)), ""},
+		{"too many conditions", where(anyOf(maxRuleConditions)), fmt.Sprintf("requirements[0].where.any[%d]", maxRuleConditions-1)},
+		{"empty condition", where(`{}`), "requirements[0].where"},
+		{"two kinds of condition", where(`{"all": [` + nestedNot(1) + `], "field": "catch.hour", "op": "eq", "value": 1}`), "requirements[0].where"},
+		{"empty all", where(`{"all": []}`), "requirements[0].where.all"},
+
+		{"unknown field", where(`{"field": "catch.mood", "op": "eq", "value": "happy"}`), "requirements[0].where.field"},
+		{"no operator", where(`{"field": "catch.hour", "value": 1}`), "requirements[0].where.op"},
+		{"unknown operator", where(`{"field": "catch.hour", "op": "like", "value": 1}`), "requirements[0].where.op"},
+		{"number compared", where(`{"field": "catch.temperature", "op": "lt", "value": -10}`), ""},
+		{"string compared", where(`{"field": "species.family", "op": "gt", "value": "A"}`), "requirements[0].where.op"},
+		{"string for number", where(`{"field": "catch.hour", "op": "eq", "value": "5"}`), "requirements[0].where.value"},
+		{"number for string", where(`{"field": "species.family", "op": "eq", "value": 5}`), "requirements[0].where.value"},
+		{"bool", where(`{"field": "catch.is_first_catch", "op": "eq", "value": true}`), ""},
+		{"string for bool", where(`{"field": "catch.is_first_catch", "op": "eq", "value": "true"}`), "requirements[0].where.value"},
+		{"species ID", where(`{"field": "species.id", "op": "eq", "value": "0b0e8d0c-6a4f-4b8e-9a4e-2f6f4c1d7a11"}`), ""},
+		{"bad species ID", where(`{"field": "species.id", "op": "eq", "value": "owl"}`), "requirements[0].where.value"},
+		{"value out of enum", where(`{"field": "catch.season", "op": "eq", "value": "monsoon"}`), "requirements[0].where.value"},
+		{"rejected status", where(`{"field": "catch.verification_status", "op": "eq", "value": "rejected"}`), "requirements[0].where.value"},
+		{"in list", where(`{"field": "location.continent", "op": "in", "value": ["asia", "oceania"]}`), ""},
+		{"in scalar", where(`{"field": "location.continent", "op": "in", "value": "asia"}`), "requirements[0].where.value"},
+		{"in empty list", where(`{"field": "location.continent", "op": "not_in", "value": []}`), "requirements[0].where.value"},
+		{"in list bad value", where(`{"field": "location.continent", "op": "in", "value": ["asia", "atlantis"]}`), "requirements[0].where.value[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rule BadgeRule
+			if err := json.Unmarshal([]byte(tt.rule), &rule); err != nil {
+				t.Fatal(err)
+			}
+			err := rule.Validate()
+			if tt.wantPath == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			var ruleErr *RuleError
+			if !errors.As(err, &ruleErr) {
+				t.Fatalf("Validate() error = %v, want a *RuleError at %s", err, tt.wantPath)
+			}
+			if ruleErr.Path != tt.wantPath {
+				t.Errorf("Validate() error = %v, want it at %s", err, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FeedReason is why a catch is in someone's feed
+type FeedReason string
+
+const (
+	FeedReasonFollowing FeedReason = "following" // Caught by someone the reader follows
+	FeedReasonNearby    FeedReason = "nearby"    // Notable catch near the reader
+	FeedReasonRare      FeedReason = "rare"      // Rare, epic or legendary species, shown to everyone
+)
+
+// FeedItem is a materialized feed entry. Following items are fanned out to each
+// follower when the catch is created. Nearby and rare items are written once,
+// with a nil RecipientID, and shared by every reader.
+//
+// RankedAt is the catch's creation time moved forward by how interesting it is,
+// so ordering by it ranks the feed and stays stable for cursor pagination.
+type FeedItem struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	RecipientID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_feed_items_recipient_catch;index:idx_feed_items_recipient_rank,priority:1" json:"recipient_id"`
+	CatchID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_feed_items_recipient_catch;index" json:"catch_id"`
+	AuthorID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"author_id"`
+	Reason      FeedReason `gorm:"type:varchar(20);not null;index:idx_feed_items_recipient_rank,priority:2" json:"reason"`
+	RankedAt    time.Time  `gorm:"not null;index:idx_feed_items_recipient_rank,priority:3" json:"ranked_at"`
+	Latitude    float64    `json:"-"` // Where the catch was made, for nearby items
+	Longitude   float64    `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (fi *FeedItem) BeforeCreate(tx *gorm.DB) error {
+	fi.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FollowStatus is the state of a follow relationship
+type FollowStatus string
+
+const (
+	FollowPending  FollowStatus = "pending" // Awaiting approval by a private account
+	FollowAccepted FollowStatus = "accepted"
+)
+
+// Follow is a directed edge in the follow graph: FollowerID follows FolloweeID.
+// Only accepted follows count towards UserStats.FollowersCount/FollowingCount.
+type Follow struct {
+	ID         uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	FollowerID uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_follows_pair;index:idx_follows_follower_list" json:"follower_id"`
+	FolloweeID uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_follows_pair;index:idx_follows_followee_list" json:"followee_id"`
+	Status     FollowStatus `gorm:"type:varchar(20);not null;default:'accepted';index:idx_follows_follower_list;index:idx_follows_followee_list" json:"status"`
+	AcceptedAt *time.Time   `json:"accepted_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+
+	// Mutual is set by list queries: the followee follows the follower back
+	Mutual bool `gorm:"->;-:migration" json:"mutual"`
+
+	// Relationships
+	Follower *User `gorm:"foreignKey:FollowerID" json:"follower,omitempty"`
+	Followee *User `gorm:"foreignKey:FolloweeID" json:"followee,omitempty"`
+}
+
+func (f *Follow) BeforeCreate(tx *gorm.DB) error {
+	f.ID = uuid.New()
+	return nil
+}
+
+// IsAccepted returns true if the follow is in effect
+func (f *Follow) IsAccepted() bool {
+	return f.Status == FollowAccepted
+}
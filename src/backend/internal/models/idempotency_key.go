@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdempotencyStatus represents the state of a request made with an Idempotency-Key
+type IdempotencyStatus string
+
+const (
+	IdempotencyProcessing IdempotencyStatus = "processing" // First request still running
+	IdempotencyCompleted  IdempotencyStatus = "completed"  // Response stored for replay
+)
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header so retries get the same answer instead of redoing the work
+type IdempotencyKey struct {
+	ID             uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	UserID         uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	Key            string            `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	RequestHash    string            `gorm:"type:varchar(64);not null" json:"-"` // SHA-256 of method, path and body
+	Status         IdempotencyStatus `gorm:"type:varchar(20);not null" json:"status"`
+	ResponseStatus int               `json:"response_status"`
+	ResponseBody   []byte            `gorm:"type:bytea" json:"-"`
+	CreatedAt      time.Time         `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+func (k *IdempotencyKey) BeforeCreate(tx *gorm.DB) error {
+	k.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+// LeaderboardMetric is what a leaderboard ranks users by
+type LeaderboardMetric string
+
+const (
+	LeaderboardPoints  LeaderboardMetric = "points"  // Points, from catches and, all time, badges
+	LeaderboardSpecies LeaderboardMetric = "species" // Distinct species caught
+	LeaderboardStreak  LeaderboardMetric = "streak"  // Longest run of local days with a catch
+)
+
+// LeaderboardScope is which users a leaderboard ranks, by their home region
+type LeaderboardScope string
+
+const (
+	LeaderboardGlobal  LeaderboardScope = "global"
+	LeaderboardCountry LeaderboardScope = "country"
+	LeaderboardCity    LeaderboardScope = "city"
+)
+
+// LeaderboardWindow is the period a leaderboard counts catches over. Months and
+// weeks are calendar ones in UTC; weeks start on Monday.
+type LeaderboardWindow string
+
+const (
+	LeaderboardAllTime LeaderboardWindow = "all_time"
+	LeaderboardMonth   LeaderboardWindow = "month"
+	LeaderboardWeek    LeaderboardWindow = "week"
+)
+
+// LeaderboardEntry is a user's place on a leaderboard. Users with the same
+// value share a rank.
+type LeaderboardEntry struct {
+	Rank  int   `json:"rank"`
+	Value int   `json:"value"`
+	User  *User `json:"user"`
+}
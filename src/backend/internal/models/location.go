@@ -5,6 +5,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/anidex/backend/internal/geoprivacy"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -40,6 +41,7 @@ type Location struct {
 	Longitude     float64      `gorm:"not null;index:idx_lat_lng" json:"longitude"`
 	Altitude      *float64     `json:"altitude"` // in meters above sea level
 	Accuracy      *float64     `json:"accuracy"` // GPS accuracy in meters
+	Timezone      string       `gorm:"type:varchar(64)" json:"timezone"` // IANA zone, e.g. Europe/Berlin
 	
 	// Address information
 	Address       string       `json:"address"`
@@ -175,3 +177,22 @@ func (ulh *UserLocationHistory) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// Generalized returns a copy of the location that only reveals a random point
+// within its geoprivacy grid cell, without address, altitude or statistics.
+// seed keeps the point stable across requests.
+func (l *Location) Generalized(seed []byte) Location {
+	lat, lng := geoprivacy.Obscure(l.Latitude, l.Longitude, seed)
+	return Location{
+		Latitude:     lat,
+		Longitude:    lng,
+		Timezone:     l.Timezone,
+		State:        l.State,
+		Country:      l.Country,
+		CountryCode:  l.CountryCode,
+		LocationType: l.LocationType,
+		Ecosystem:    l.Ecosystem,
+		Climate:      l.Climate,
+		Habitat:      l.Habitat,
+	}
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationType is what a notification is about. The values match the
+// real-time event types they are created from.
+type NotificationType string
+
+const (
+	NotificationCatchVerified NotificationType = "catch_verified"
+	NotificationBadgeEarned   NotificationType = "badge_earned"
+	NotificationLike          NotificationType = "like"
+	NotificationComment       NotificationType = "comment"
+	NotificationFollow        NotificationType = "follow"
+	NotificationStreak        NotificationType = "streak_milestone"
+)
+
+// NotificationTypes lists every type, in the order preferences are shown
+var NotificationTypes = []NotificationType{
+	NotificationCatchVerified,
+	NotificationBadgeEarned,
+	NotificationLike,
+	NotificationComment,
+	NotificationFollow,
+	NotificationStreak,
+}
+
+// IsValid returns true if t is a known notification type
+func (t NotificationType) IsValid() bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification is an entry in a user's inbox
+type Notification struct {
+	ID        uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID        `gorm:"type:uuid;not null;index:idx_notifications_user_created,priority:1" json:"user_id"`
+	Type      NotificationType `gorm:"type:varchar(30);not null" json:"type"`
+	ActorID   *uuid.UUID       `gorm:"type:uuid" json:"actor_id,omitempty"` // Who caused it, if anyone
+	Title     string           `gorm:"type:varchar(255);not null" json:"title"`
+	Body      string           `gorm:"type:text" json:"body"`
+	Data      json.RawMessage  `gorm:"type:jsonb;serializer:json" json:"data"` // The event payload
+	ReadAt    *time.Time       `gorm:"index" json:"read_at"`
+	CreatedAt time.Time        `gorm:"index:idx_notifications_user_created,priority:2" json:"created_at"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	n.ID = uuid.New()
+	return nil
+}
+
+// NotificationPreference is whether a user wants a type of notification in
+// their inbox and pushed to their devices. Types without a row are enabled.
+type NotificationPreference struct {
+	UserID    uuid.UUID        `gorm:"type:uuid;primaryKey" json:"-"`
+	Type      NotificationType `gorm:"type:varchar(30);primaryKey" json:"type"`
+	InApp     bool             `gorm:"not null" json:"in_app"`
+	Push      bool             `gorm:"not null" json:"push"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// PushPlatform is the kind of device a push token belongs to
+type PushPlatform string
+
+const (
+	PushPlatformAndroid PushPlatform = "android"
+	PushPlatformIOS     PushPlatform = "ios"
+	PushPlatformWeb     PushPlatform = "web"
+)
+
+// DeviceToken is a push token registered by one of a user's devices
+type DeviceToken struct {
+	ID        uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID    `gorm:"type:uuid;not null;index" json:"user_id"`
+	Token     string       `gorm:"type:varchar(512);not null;uniqueIndex" json:"token"`
+	Platform  PushPlatform `gorm:"type:varchar(20);not null" json:"platform"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+func (d *DeviceToken) BeforeCreate(tx *gorm.DB) error {
+	d.ID = uuid.New()
+	return nil
+}
+
+// RegisterDeviceRequest registers a device for push notifications
+type RegisterDeviceRequest struct {
+	Token    string       `json:"token" binding:"required,max=512"`
+	Platform PushPlatform `json:"platform" binding:"required,oneof=android ios web"`
+}
+
+// UnregisterDeviceRequest stops pushes to a device
+type UnregisterDeviceRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UpdateNotificationPreferencesRequest changes the preferences for the types listed
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreference `json:"preferences" binding:"required,dive"`
+}
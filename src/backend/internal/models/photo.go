@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PhotoStatus represents the upload state of a photo
+type PhotoStatus string
+
+const (
+	PhotoPending  PhotoStatus = "pending"  // Signed upload URL issued, object not confirmed yet
+	PhotoUploaded PhotoStatus = "uploaded" // Original stored and public renditions generated
+)
+
+// PhotoRenditions holds the public URLs of the resized copies of a photo
+type PhotoRenditions struct {
+	Grid   string `json:"grid"`   // Square crop for profile grids
+	Feed   string `json:"feed"`   // Medium size for feed cards
+	Detail string `json:"detail"` // Large size for the catch detail screen
+}
+
+// Photo represents an image uploaded to our storage by a user. The original is
+// kept private; URL and Renditions point at re-encoded copies without EXIF data.
+type Photo struct {
+	ID             uuid.UUID       `gorm:"type:uuid;primary_key" json:"id"`
+	UserID         uuid.UUID       `gorm:"type:uuid;not null;index" json:"user_id"`
+	StorageKey     string          `gorm:"not null;uniqueIndex" json:"-"` // Key of the private original
+	URL            string          `gorm:"not null;index" json:"url"`     // Full size public copy
+	ContentType    string          `gorm:"type:varchar(50)" json:"content_type"`
+	Size           int64           `json:"size"` // in bytes
+	Status         PhotoStatus     `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	Width          int             `json:"width"`
+	Height         int             `json:"height"`
+	BlurHash       string          `gorm:"type:varchar(64)" json:"blurhash"`
+	PerceptualHash *int64          `gorm:"index" json:"-"`
+	Renditions     PhotoRenditions `gorm:"embedded;embeddedPrefix:rendition_" json:"renditions"`
+	ProcessedAt    *time.Time      `json:"processed_at"`
+
+	// Metadata read from the original's EXIF before it was stripped. The position is
+	// never exposed directly since it reveals exactly where the photo was taken.
+	TakenAt       *time.Time `json:"taken_at"`
+	TakenAtOffset *int       `json:"-"` // Camera's UTC offset in seconds, if known
+	ExifLatitude  *float64   `json:"-"`
+	ExifLongitude *float64   `json:"-"`
+	ExifAltitude  *float64   `json:"-"` // in meters above sea level
+	ExifAccuracy  *float64   `json:"-"` // GPS horizontal error in meters
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (p *Photo) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsUploaded returns true if the photo has been stored and processed
+func (p *Photo) IsUploaded() bool {
+	return p.Status == PhotoUploaded
+}
+
+// LocalTakenAt returns the capture time in the camera's time zone. When the
+// offset was not recorded the camera's wall clock is interpreted in fallback,
+// normally the zone of the catch location.
+func (p *Photo) LocalTakenAt(fallback *time.Location) (time.Time, bool) {
+	if p.TakenAt == nil {
+		return time.Time{}, false
+	}
+	if p.TakenAtOffset != nil {
+		return p.TakenAt.In(time.FixedZone("", *p.TakenAtOffset)), true
+	}
+
+	wall := p.TakenAt.UTC()
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, fallback), true
+}
+
+// HasExifPosition returns true if the original photo carried GPS coordinates
+func (p *Photo) HasExifPosition() bool {
+	return p.ExifLatitude != nil && p.ExifLongitude != nil
+}
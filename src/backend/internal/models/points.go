@@ -0,0 +1,64 @@
+package models
+
+import "math"
+
+// PointsComponent identifies one line of a catch's points breakdown
+type PointsComponent string
+
+const (
+	PointsSpecies        PointsComponent = "species"         // Base points, rarity and difficulty of the species
+	PointsRegionalRarity PointsComponent = "regional_rarity" // Species seldom recorded near the catch location
+	PointsConservation   PointsComponent = "conservation"    // Species threatened on the IUCN Red List
+	PointsFirstCatch     PointsComponent = "first_catch"     // First time the user caught this species
+	PointsStreak         PointsComponent = "streak"          // Catches on consecutive days
+)
+
+// PointsItem is a single line of a points breakdown. Items either add points
+// to the subtotal or multiply it, never both.
+type PointsItem struct {
+	Component   PointsComponent `json:"component"`
+	Description string          `json:"description"`
+	Points      int             `json:"points,omitempty"`
+	Multiplier  float64         `json:"multiplier,omitempty"`
+}
+
+// PointsBreakdown itemizes how the points awarded for a catch were computed:
+// the additive items are summed into Subtotal, which is then scaled by the
+// product of the multiplier items to give Total
+type PointsBreakdown struct {
+	Items      []PointsItem `json:"items"`
+	Subtotal   int          `json:"subtotal"`
+	Multiplier float64      `json:"multiplier"`
+	Total      int          `json:"total"`
+}
+
+// AddPoints appends an additive item, ignoring zero amounts
+func (b *PointsBreakdown) AddPoints(component PointsComponent, description string, points int) {
+	if points == 0 {
+		return
+	}
+	b.Items = append(b.Items, PointsItem{Component: component, Description: description, Points: points})
+}
+
+// AddMultiplier appends a multiplier item, ignoring multipliers of 1
+func (b *PointsBreakdown) AddMultiplier(component PointsComponent, description string, multiplier float64) {
+	if multiplier == 1 {
+		return
+	}
+	b.Items = append(b.Items, PointsItem{Component: component, Description: description, Multiplier: multiplier})
+}
+
+// Compute fills in Subtotal, Multiplier and Total from the items
+func (b *PointsBreakdown) Compute() {
+	b.Subtotal = 0
+	b.Multiplier = 1
+	for _, item := range b.Items {
+		if item.Multiplier != 0 {
+			b.Multiplier *= item.Multiplier
+		} else {
+			b.Subtotal += item.Points
+		}
+	}
+	b.Multiplier = math.Round(b.Multiplier*100) / 100
+	b.Total = int(math.Round(float64(b.Subtotal) * b.Multiplier))
+}
@@ -73,6 +73,7 @@ type Species struct {
 	// Conservation and rarity
 	ConservationStatus ConservationStatus `gorm:"type:varchar(2);default:'LC'" json:"conservation_status"`
 	Rarity            Rarity             `gorm:"type:varchar(20);default:'common'" json:"rarity"`
+	IsSensitive       bool               `gorm:"default:false" json:"is_sensitive"` // Targeted by poaching or disturbance; sightings are obscured
 	
 	// Game mechanics
 	BasePoints        int                `gorm:"default:10" json:"base_points"` // Points awarded for catching
@@ -130,3 +131,9 @@ func (s *Species) IsEndangered() bool {
 		s.ConservationStatus == StatusEndangered ||
 		s.ConservationStatus == StatusCriticallyEndangered
 }
+
+// RequiresGeoprivacy returns true if the exact location of sightings must be
+// hidden from the public, because the species is threatened or flagged sensitive
+func (s *Species) RequiresGeoprivacy() bool {
+	return s.IsSensitive || s.IsEndangered()
+}
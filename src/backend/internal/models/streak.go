@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StreakFreeze records a missed day a streak freeze covered, so replaying the
+// user's catch history keeps the streaks their freezes saved
+type StreakFreeze struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_streak_freezes_user_day,priority:1" json:"user_id"`
+	Day       time.Time `gorm:"type:date;not null;uniqueIndex:idx_streak_freezes_user_day,priority:2" json:"day"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (sf *StreakFreeze) BeforeCreate(tx *gorm.DB) error {
+	sf.ID = uuid.New()
+	return nil
+}
+
+// StreakDay returns the day a catch made at t counts for in a daily streak: its
+// calendar day in zone, where catches in the first grace hours after midnight
+// still count for the day before. Days are midnight UTC.
+func StreakDay(t time.Time, zone *time.Location, grace time.Duration) time.Time {
+	return calendarDay(t.In(zone).Add(-grace))
+}
+
+// calendarDay returns the date of t, in t's time zone, as midnight UTC
+func calendarDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
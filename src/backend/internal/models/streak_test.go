@@ -0,0 +1,216 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// day parses a date as a streak day, midnight UTC
+func day(t *testing.T, date string) time.Time {
+	t.Helper()
+	d, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func days(t *testing.T, dates ...string) []time.Time {
+	t.Helper()
+	var parsed []time.Time
+	for _, date := range dates {
+		parsed = append(parsed, day(t, date))
+	}
+	return parsed
+}
+
+// dayPtr is day for the optional dates of UserStats, nil for ""
+func dayPtr(t *testing.T, date string) *time.Time {
+	t.Helper()
+	if date == "" {
+		return nil
+	}
+	d := day(t, date)
+	return &d
+}
+
+// checkDate compares an optional date of UserStats with want, "" for nil
+func checkDate(t *testing.T, name string, got *time.Time, want string) {
+	t.Helper()
+	switch {
+	case got == nil && want == "":
+	case got == nil:
+		t.Errorf("%s = nil, want %s", name, want)
+	case want == "":
+		t.Errorf("%s = %s, want nil", name, got.Format(time.DateOnly))
+	case !got.Equal(day(t, want)):
+		t.Errorf("%s = %s, want %s", name, got.Format(time.DateOnly), want)
+	}
+}
+
+func TestStreakDay(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		at    string // UTC
+		zone  *time.Location
+		grace time.Duration
+		want  string
+	}{
+		{"before midnight", "2024-06-15T03:59:00Z", newYork, 0, "2024-06-14"},
+		{"midnight without grace", "2024-06-15T04:00:00Z", newYork, 0, "2024-06-15"},
+		{"local date, not UTC", "2024-06-15T15:30:00Z", tokyo, 0, "2024-06-16"},
+		{"in the grace", "2024-06-15T04:30:00Z", newYork, 3 * time.Hour, "2024-06-14"},
+		{"end of the grace", "2024-06-15T06:59:00Z", newYork, 3 * time.Hour, "2024-06-14"},
+		{"after the grace", "2024-06-15T07:00:00Z", newYork, 3 * time.Hour, "2024-06-15"},
+		// Clocks go forward at 2:00 on 10 March, so 3 hours after midnight is 4:00
+		{"grace over spring DST", "2024-03-10T07:30:00Z", newYork, 3 * time.Hour, "2024-03-09"},
+		{"after grace over spring DST", "2024-03-10T08:00:00Z", newYork, 3 * time.Hour, "2024-03-10"},
+		// Clocks go back at 2:00 on 3 November, so 3 hours after midnight is 2:00
+		{"grace over autumn DST", "2024-11-03T06:59:00Z", newYork, 3 * time.Hour, "2024-11-02"},
+		{"after grace over autumn DST", "2024-11-03T07:00:00Z", newYork, 3 * time.Hour, "2024-11-03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := StreakDay(at, tt.zone, tt.grace)
+			if !got.Equal(day(t, tt.want)) || got.Location() != time.UTC {
+				t.Errorf("StreakDay() = %v, want %s at midnight UTC", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoverMissedDays(t *testing.T) {
+	tests := []struct {
+		name        string
+		streak      int
+		lastStreak  string
+		freezes     int
+		through     string
+		wantMissed  []string
+		wantStreak  int
+		wantLast    string
+		wantFreezes int
+	}{
+		{"nothing missed", 4, "2024-06-10", 2, "2024-06-10", nil, 4, "2024-06-10", 2},
+		{"through an earlier day", 4, "2024-06-10", 2, "2024-06-08", nil, 4, "2024-06-10", 2},
+		{"one day covered", 4, "2024-06-10", 2, "2024-06-11", []string{"2024-06-11"}, 4, "2024-06-11", 1},
+		{"every freeze spent", 4, "2024-06-10", 2, "2024-06-12", []string{"2024-06-11", "2024-06-12"}, 4, "2024-06-12", 0},
+		// Freezes are spent only if they cover every missed day
+		{"freezes run out", 4, "2024-06-10", 2, "2024-06-13", nil, 0, "2024-06-10", 2},
+		{"no freezes", 4, "2024-06-10", 0, "2024-06-11", nil, 0, "2024-06-10", 0},
+		{"no streak", 0, "2024-06-10", 2, "2024-06-11", nil, 0, "2024-06-10", 2},
+		{"never caught", 0, "", 2, "2024-06-11", nil, 0, "", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &UserStats{CurrentStreak: tt.streak, LastStreakDate: dayPtr(t, tt.lastStreak), StreakFreezes: tt.freezes}
+			missed := stats.CoverMissedDays(day(t, tt.through))
+			if !slices.EqualFunc(missed, days(t, tt.wantMissed...), time.Time.Equal) {
+				t.Errorf("CoverMissedDays() = %v, want %v", missed, tt.wantMissed)
+			}
+			if stats.CurrentStreak != tt.wantStreak {
+				t.Errorf("CurrentStreak = %d, want %d", stats.CurrentStreak, tt.wantStreak)
+			}
+			if stats.StreakFreezes != tt.wantFreezes {
+				t.Errorf("StreakFreezes = %d, want %d", stats.StreakFreezes, tt.wantFreezes)
+			}
+			checkDate(t, "LastStreakDate", stats.LastStreakDate, tt.wantLast)
+		})
+	}
+}
+
+func TestReplayStreak(t *testing.T) {
+	tests := []struct {
+		name        string
+		days        []string
+		frozen      []string
+		wantStreak  int
+		wantLongest int
+		wantCatch   string
+		wantLast    string
+	}{
+		{"nothing", nil, nil, 0, 0, "", ""},
+		{"consecutive days", []string{"2024-06-01", "2024-06-02", "2024-06-03"}, nil, 3, 3, "2024-06-03", "2024-06-03"},
+		{"several catches a day", []string{"2024-06-01", "2024-06-01", "2024-06-02"}, nil, 2, 2, "2024-06-02", "2024-06-02"},
+		{"gap restarts", []string{"2024-06-01", "2024-06-02", "2024-06-03", "2024-06-05"}, nil, 1, 3, "2024-06-05", "2024-06-05"},
+		{"freeze bridges a gap",
+			[]string{"2024-06-01", "2024-06-02", "2024-06-04"}, []string{"2024-06-03"},
+			3, 3, "2024-06-04", "2024-06-04"},
+		{"freezes after the last catch",
+			[]string{"2024-06-01", "2024-06-02"}, []string{"2024-06-03", "2024-06-04"},
+			2, 2, "2024-06-02", "2024-06-04"},
+		{"freeze too late to bridge",
+			[]string{"2024-06-01", "2024-06-04"}, []string{"2024-06-03"},
+			1, 1, "2024-06-04", "2024-06-04"},
+		{"freeze before any catch", []string{"2024-06-02"}, []string{"2024-06-01"}, 1, 1, "2024-06-02", "2024-06-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Whatever was there before is replaced
+			stats := &UserStats{CurrentStreak: 10, LongestStreak: 20, LastCatchDate: dayPtr(t, "2024-07-01"), LastStreakDate: dayPtr(t, "2024-07-01"), StreakFreezes: 1}
+			stats.ReplayStreak(days(t, tt.days...), days(t, tt.frozen...))
+			if stats.CurrentStreak != tt.wantStreak || stats.LongestStreak != tt.wantLongest {
+				t.Errorf("streak = %d, longest %d, want %d, longest %d", stats.CurrentStreak, stats.LongestStreak, tt.wantStreak, tt.wantLongest)
+			}
+			if stats.StreakFreezes != 1 {
+				t.Errorf("StreakFreezes = %d, want it left at 1", stats.StreakFreezes)
+			}
+			checkDate(t, "LastCatchDate", stats.LastCatchDate, tt.wantCatch)
+			checkDate(t, "LastStreakDate", stats.LastStreakDate, tt.wantLast)
+		})
+	}
+}
+
+func TestRecordCatchDay(t *testing.T) {
+	tests := []struct {
+		name        string
+		streak      int
+		longest     int
+		lastCatch   string
+		lastStreak  string
+		day         string
+		wantStreak  int
+		wantLongest int
+		wantCatch   string
+		wantLast    string
+	}{
+		{"first catch", 0, 0, "", "", "2024-06-10", 1, 1, "2024-06-10", "2024-06-10"},
+		{"next day", 4, 4, "2024-06-10", "2024-06-10", "2024-06-11", 5, 5, "2024-06-11", "2024-06-11"},
+		{"same day", 4, 4, "2024-06-10", "2024-06-10", "2024-06-10", 4, 4, "2024-06-10", "2024-06-10"},
+		{"after a gap", 4, 6, "2024-06-10", "2024-06-10", "2024-06-12", 1, 6, "2024-06-12", "2024-06-12"},
+		{"after the streak ended", 0, 6, "2024-06-10", "2024-06-10", "2024-06-11", 1, 6, "2024-06-11", "2024-06-11"},
+		{"after a frozen day", 4, 4, "2024-06-10", "2024-06-11", "2024-06-12", 5, 5, "2024-06-12", "2024-06-12"},
+		// Synced late from offline: the streak has moved on, so the catch leaves it alone
+		{"late sync", 4, 4, "2024-06-10", "2024-06-10", "2024-06-08", 4, 4, "2024-06-10", "2024-06-10"},
+		{"late sync of a frozen day", 4, 4, "2024-06-10", "2024-06-11", "2024-06-11", 4, 4, "2024-06-11", "2024-06-11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &UserStats{CurrentStreak: tt.streak, LongestStreak: tt.longest, LastCatchDate: dayPtr(t, tt.lastCatch), LastStreakDate: dayPtr(t, tt.lastStreak)}
+			stats.recordCatchDay(day(t, tt.day))
+			if stats.CurrentStreak != tt.wantStreak || stats.LongestStreak != tt.wantLongest {
+				t.Errorf("streak = %d, longest %d, want %d, longest %d", stats.CurrentStreak, stats.LongestStreak, tt.wantStreak, tt.wantLongest)
+			}
+			checkDate(t, "LastCatchDate", stats.LastCatchDate, tt.wantCatch)
+			checkDate(t, "LastStreakDate", stats.LastStreakDate, tt.wantLast)
+			if got := stats.StreakEndingOn(day(t, tt.wantLast)); got != tt.wantStreak {
+				t.Errorf("StreakEndingOn(%s) = %d, want %d", tt.wantLast, got, tt.wantStreak)
+			}
+		})
+	}
+}
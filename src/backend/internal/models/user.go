@@ -16,6 +16,14 @@ const (
 	AuthProviderFirebase AuthProvider = "firebase"
 )
 
+type UserRole string
+
+const (
+	RoleUser      UserRole = "user"
+	RoleModerator UserRole = "moderator"
+	RoleAdmin     UserRole = "admin"
+)
+
 type User struct {
 	ID           uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
 	Email        string       `gorm:"uniqueIndex;not null" json:"email"`
@@ -25,7 +33,11 @@ type User struct {
 	Avatar       string       `json:"avatar"`
 	Provider     AuthProvider `gorm:"type:varchar(20);default:'local'" json:"provider"`
 	ProviderID   string       `json:"-"`
+	Role         UserRole     `gorm:"type:varchar(20);default:'user'" json:"role"`
 	RefreshToken string       `json:"-"`
+	DefaultCatchVisibility CatchVisibility `gorm:"type:varchar(20);default:'public'" json:"default_catch_visibility"` // Applied to new catches that don't set one
+	IsPrivate    bool         `gorm:"default:false" json:"is_private"` // Follows must be approved
+	Timezone     string       `json:"timezone"` // IANA name streak days are counted in; empty for where each catch was made
 	CreatedAt    time.Time    `json:"created_at"`
 	UpdatedAt    time.Time    `json:"updated_at"`
 }
@@ -35,6 +47,16 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// CanModerate returns true if the user may review other users' catches
+func (u *User) CanModerate() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
+}
+
+// IsAdmin returns true if the user may manage the app's content, such as badges
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
@@ -53,6 +75,14 @@ type AuthResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type UpdateProfileRequest struct {
+	Name                   *string `json:"name" binding:"omitempty,min=1"`
+	Avatar                 *string `json:"avatar" binding:"omitempty,url"`
+	DefaultCatchVisibility *string `json:"default_catch_visibility" binding:"omitempty,oneof=public location_hidden followers private"`
+	IsPrivate              *bool   `json:"is_private"`
+	Timezone               *string `json:"timezone" binding:"omitempty,timezone"`
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
\ No newline at end of file
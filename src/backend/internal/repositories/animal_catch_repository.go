@@ -1,10 +1,15 @@
 package repositories
 
 import (
+	"math"
+	"time"
+
 	"github.com/anidex/backend/internal/config"
 	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AnimalCatchRepository struct {
@@ -17,15 +22,30 @@ func NewAnimalCatchRepository() *AnimalCatchRepository {
 	}
 }
 
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *AnimalCatchRepository) WithTx(tx *gorm.DB) *AnimalCatchRepository {
+	return &AnimalCatchRepository{db: tx}
+}
+
 // Create creates a new animal catch record
 func (r *AnimalCatchRepository) Create(catch *models.AnimalCatch) error {
 	return r.db.Create(catch).Error
 }
 
+// CreateIfNotExists inserts the catch unless one with the same ID already exists.
+// It reports whether a row was inserted, so retried submissions are never duplicated.
+func (r *AnimalCatchRepository) CreateIfNotExists(catch *models.AnimalCatch) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(catch)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 // GetByID retrieves an animal catch by its ID with relationships loaded
 func (r *AnimalCatchRepository) GetByID(id uuid.UUID) (*models.AnimalCatch, error) {
 	var catch models.AnimalCatch
-	err := r.db.Preload("User").Preload("Species").Preload("Location").
+	err := r.db.Preload("User").Preload("Species").Preload("Location").Preload("Photo").
 		Where("id = ?", id).First(&catch).Error
 	if err != nil {
 		return nil, err
@@ -33,6 +53,26 @@ func (r *AnimalCatchRepository) GetByID(id uuid.UUID) (*models.AnimalCatch, erro
 	return &catch, nil
 }
 
+// GetForUpdate retrieves a catch with its species and locks it until the
+// transaction ends
+func (r *AnimalCatchRepository) GetForUpdate(id uuid.UUID) (*models.AnimalCatch, error) {
+	var catch models.AnimalCatch
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Species").
+		Where("id = ?", id).First(&catch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &catch, nil
+}
+
+// GetByIDsWithSpecies retrieves catches by their IDs with their species
+func (r *AnimalCatchRepository) GetByIDsWithSpecies(ids []uuid.UUID) ([]models.AnimalCatch, error) {
+	var catches []models.AnimalCatch
+	err := r.db.Preload("Species").Where("id IN ?", ids).Find(&catches).Error
+	return catches, err
+}
+
 // GetByUserID retrieves all catches by a specific user with pagination
 func (r *AnimalCatchRepository) GetByUserID(userID uuid.UUID, limit, offset int) ([]models.AnimalCatch, int64, error) {
 	var catches []models.AnimalCatch
@@ -45,7 +85,7 @@ func (r *AnimalCatchRepository) GetByUserID(userID uuid.UUID, limit, offset int)
 	}
 
 	// Get paginated results with relationships
-	err = r.db.Preload("Species").Preload("Location").
+	err = r.db.Preload("Species").Preload("Location").Preload("Photo").
 		Where("user_id = ?", userID).
 		Order("caught_at DESC").
 		Limit(limit).Offset(offset).
@@ -54,124 +94,486 @@ func (r *AnimalCatchRepository) GetByUserID(userID uuid.UUID, limit, offset int)
 	return catches, total, err
 }
 
-// GetByLocationID retrieves all catches at a specific location with pagination
-func (r *AnimalCatchRepository) GetByLocationID(locationID uuid.UUID, limit, offset int) ([]models.AnimalCatch, int64, error) {
+// visibleTo limits a catch query to the catches the viewer may see. Followers-only
+// catches are matched for their owner only. Moderators see every catch.
+func visibleTo(viewer models.Viewer) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Moderator {
+			return db
+		}
+		return db.Where(`(animal_catches.visibility IN ? OR animal_catches.user_id = ? OR
+			(animal_catches.visibility = ? AND animal_catches.user_id IN (
+				SELECT followee_id FROM follows WHERE follower_id = ? AND status = ?)))`,
+			[]models.CatchVisibility{models.VisibilityPublic, models.VisibilityLocationHidden}, viewer.UserID,
+			models.VisibilityFollowers, viewer.UserID, models.FollowAccepted)
+	}
+}
+
+// listedAtLocationFor is like visibleTo, but for queries that reveal where catches
+// were made it also leaves out other users' catches whose location is hidden or
+// whose species needs geoprivacy, even if it was only flagged after the catch
+func listedAtLocationFor(viewer models.Viewer) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Moderator {
+			return db
+		}
+		return db.Where(`(animal_catches.user_id = ? OR (animal_catches.is_public = ? AND animal_catches.species_id NOT IN (
+				SELECT id FROM species WHERE is_sensitive = ? OR conservation_status IN ?)))`,
+			viewer.UserID, true, true, []models.ConservationStatus{
+				models.StatusVulnerable, models.StatusEndangered, models.StatusCriticallyEndangered,
+			})
+	}
+}
+
+// redactFor strips what the viewer may not see from a page of catches
+func redactFor(catches []models.AnimalCatch, viewer models.Viewer) {
+	for i := range catches {
+		catches[i].RedactFor(viewer)
+	}
+}
+
+// GetByLocationID retrieves the catches at a specific location that the viewer
+// may see, with pagination
+func (r *AnimalCatchRepository) GetByLocationID(locationID uuid.UUID, viewer models.Viewer, limit, offset int) ([]models.AnimalCatch, int64, error) {
 	var catches []models.AnimalCatch
 	var total int64
 
+	query := r.db.Model(&models.AnimalCatch{}).
+		Where("location_id = ?", locationID).
+		Scopes(listedAtLocationFor(viewer))
+
 	// Count total records
-	err := r.db.Model(&models.AnimalCatch{}).Where("location_id = ?", locationID).Count(&total).Error
+	err := query.Session(&gorm.Session{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results with relationships
-	err = r.db.Preload("User").Preload("Species").Preload("Location").
-		Where("location_id = ? AND is_public = ?", locationID, true).
+	err = query.Preload("User").Preload("Species").Preload("Location").Preload("Photo").
 		Order("caught_at DESC").
 		Limit(limit).Offset(offset).
 		Find(&catches).Error
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return catches, total, err
+	redactFor(catches, viewer)
+	return catches, total, nil
 }
 
-// GetBySpeciesID retrieves all catches of a specific species with pagination
-func (r *AnimalCatchRepository) GetBySpeciesID(speciesID uuid.UUID, limit, offset int) ([]models.AnimalCatch, int64, error) {
+// GetBySpeciesID retrieves the catches of a specific species that the viewer may
+// see, with pagination
+func (r *AnimalCatchRepository) GetBySpeciesID(speciesID uuid.UUID, viewer models.Viewer, limit, offset int) ([]models.AnimalCatch, int64, error) {
 	var catches []models.AnimalCatch
 	var total int64
 
+	query := r.db.Model(&models.AnimalCatch{}).
+		Where("species_id = ?", speciesID).
+		Scopes(visibleTo(viewer))
+
 	// Count total records
-	err := r.db.Model(&models.AnimalCatch{}).Where("species_id = ?", speciesID).Count(&total).Error
+	err := query.Session(&gorm.Session{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results with relationships
-	err = r.db.Preload("User").Preload("Location").
-		Where("species_id = ? AND is_public = ?", speciesID, true).
+	err = query.Preload("User").Preload("Species").Preload("Location").Preload("Photo").
 		Order("caught_at DESC").
 		Limit(limit).Offset(offset).
 		Find(&catches).Error
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return catches, total, err
+	redactFor(catches, viewer)
+	return catches, total, nil
 }
 
-// IsFirstCatchForUser checks if this would be the user's first catch of this species
+// IsFirstCatchForUser checks if this would be the user's first catch of this
+// species. Rejected catches don't count.
 func (r *AnimalCatchRepository) IsFirstCatchForUser(userID, speciesID uuid.UUID) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.AnimalCatch{}).
-		Where("user_id = ? AND species_id = ?", userID, speciesID).
+	err := r.countedCatches(userID).
+		Where("animal_catches.species_id = ?", speciesID).
 		Count(&count).Error
 	return count == 0, err
 }
 
-// GetRecentPublicCatches retrieves recent public catches for feed
-func (r *AnimalCatchRepository) GetRecentPublicCatches(limit, offset int) ([]models.AnimalCatch, int64, error) {
+// HasPublicCatchAtLocation reports whether the location has a public catch of the
+// species, or by the user, when the respective ID is not nil, other than
+// exceptID. Rejected catches don't count, as in LocationRepository.UpdateStats.
+func (r *AnimalCatchRepository) HasPublicCatchAtLocation(locationID, speciesID, userID, exceptID uuid.UUID) (bool, error) {
+	query := r.db.Model(&models.AnimalCatch{}).
+		Where("location_id = ? AND is_public = ? AND verification_status <> ? AND id <> ?", locationID, true, models.VerificationRejected, exceptID)
+	if speciesID != uuid.Nil {
+		query = query.Where("species_id = ?", speciesID)
+	}
+	if userID != uuid.Nil {
+		query = query.Where("user_id = ?", userID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// HasCatchInPlace reports whether the user has a catch in the given country and,
+// if city is not empty, city. Rejected catches don't count.
+func (r *AnimalCatchRepository) HasCatchInPlace(userID uuid.UUID, country, city string) (bool, error) {
+	query := r.countedCatches(userID).
+		Joins("JOIN locations ON locations.id = animal_catches.location_id").
+		Where("locations.country = ?", country)
+	if city != "" {
+		query = query.Where("locations.city = ?", city)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// countedCatches selects the user's catches that count towards badges: all but
+// the rejected ones
+func (r *AnimalCatchRepository) countedCatches(userID uuid.UUID) *gorm.DB {
+	return r.db.Model(&models.AnimalCatch{}).
+		Where("animal_catches.user_id = ? AND animal_catches.verification_status <> ?", userID, models.VerificationRejected)
+}
+
+// CountReviewedBy counts the catches the user approved or rejected as a moderator
+func (r *AnimalCatchRepository) CountReviewedBy(reviewerID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.AnimalCatch{}).
+		Where("verified_by = ? AND verification_status IN ?", reviewerID,
+			[]models.VerificationStatus{models.VerificationApproved, models.VerificationRejected}).
+		Count(&count).Error
+	return count, err
+}
+
+// GetHistoryPage returns up to limit of the user's catches that count towards
+// stats and badges, oldest first, starting after the given position, with their
+// species and location. Used to replay a user's history in pages.
+func (r *AnimalCatchRepository) GetHistoryPage(userID uuid.UUID, after *pagination.Cursor, limit int) ([]models.AnimalCatch, error) {
+	query := r.countedCatches(userID).
+		Preload("Species").
+		Preload("Location")
+	if after != nil {
+		query = query.Where("(animal_catches.caught_at, animal_catches.id) > (?, ?)", after.Time, after.ID)
+	}
+	var catches []models.AnimalCatch
+	err := query.Order("animal_catches.caught_at ASC, animal_catches.id ASC").
+		Limit(limit).
+		Find(&catches).Error
+	return catches, err
+}
+
+// streakDaySQL returns the SQL of the streak day of a catch (see
+// models.StreakDay) given the SQL of the user's time zone, empty if they have
+// none. The grace period, in seconds, is its argument. Catch locations must be
+// joined as locations.
+func streakDaySQL(userZone string) string {
+	return `((animal_catches.caught_at AT TIME ZONE COALESCE(NULLIF(` + userZone + `, ''), NULLIF(locations.timezone, ''), 'UTC'))
+			- make_interval(secs => ?))::date`
+}
+
+// GetStreakDays returns every distinct streak day on which the user caught
+// something, oldest first: calendar days in zone or, if it is empty, where each
+// catch was made, less the grace period after midnight (see models.StreakDay).
+// Rejected catches don't count.
+func (r *AnimalCatchRepository) GetStreakDays(userID uuid.UUID, zone string, grace time.Duration) ([]time.Time, error) {
+	var days []time.Time
+	err := r.db.Raw(`
+		SELECT DISTINCT `+streakDaySQL("?")+` AS day
+		FROM animal_catches
+		LEFT JOIN locations ON locations.id = animal_catches.location_id
+		WHERE animal_catches.user_id = ? AND animal_catches.verification_status <> ?
+		ORDER BY day ASC`, zone, grace.Seconds(), userID, models.VerificationRejected).
+		Scan(&days).Error
+	return days, err
+}
+
+// GetFirstCatch retrieves the oldest of the user's catches that count, with its
+// location. It marks the user's home, which distances are measured from.
+func (r *AnimalCatchRepository) GetFirstCatch(userID uuid.UUID) (*models.AnimalCatch, error) {
+	var catch models.AnimalCatch
+	err := r.countedCatches(userID).
+		Preload("Location").
+		Order("animal_catches.caught_at ASC, animal_catches.id ASC").
+		First(&catch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &catch, nil
+}
+
+// GetFirstOfSpecies retrieves the oldest of the user's catches of a species that
+// count, with its species
+func (r *AnimalCatchRepository) GetFirstOfSpecies(userID, speciesID uuid.UUID) (*models.AnimalCatch, error) {
+	var catch models.AnimalCatch
+	err := r.countedCatches(userID).
+		Preload("Species").
+		Where("animal_catches.species_id = ?", speciesID).
+		Order("animal_catches.caught_at ASC, animal_catches.id ASC").
+		First(&catch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &catch, nil
+}
+
+// CountPlaces counts the distinct countries, and cities within them, of the
+// user's counted catches
+func (r *AnimalCatchRepository) CountPlaces(userID uuid.UUID) (countries, cities int64, err error) {
+	row := r.countedCatches(userID).
+		Joins("JOIN locations ON locations.id = animal_catches.location_id").
+		Where("locations.country <> ''").
+		Select(`COUNT(DISTINCT locations.country),
+			COUNT(DISTINCT CASE WHEN locations.city <> '' THEN locations.country || '/' || locations.city END)`).
+		Row()
+	err = row.Scan(&countries, &cities)
+	return countries, cities, err
+}
+
+// GetFarthestDistance returns how far, in km, the farthest location of the
+// user's counted catches is from the given point, 0 if they have none
+func (r *AnimalCatchRepository) GetFarthestDistance(userID uuid.UUID, lat, lng float64) (float64, error) {
+	var distance float64
+	err := r.countedCatches(userID).
+		Joins("JOIN locations ON locations.id = animal_catches.location_id").
+		Select(`COALESCE(MAX(6371 * 2 * ASIN(LEAST(1, SQRT(
+			POWER(SIN(RADIANS(locations.latitude - ?) / 2), 2) +
+			COS(RADIANS(?)) * COS(RADIANS(locations.latitude)) * POWER(SIN(RADIANS(locations.longitude - ?) / 2), 2))))), 0)`,
+			lat, lat, lng).
+		Scan(&distance).Error
+	return distance, err
+}
+
+// boundingBox returns the latitude and longitude half-widths, in degrees, of a
+// box around a point at lat that contains a circle of radiusKm
+func boundingBox(lat, radiusKm float64) (latDelta, lngDelta float64) {
+	latDelta = radiusKm / 111.0
+	lngDelta = radiusKm / (111.0 * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	return latDelta, lngDelta
+}
+
+// CountSpeciesNear counts the catches of a species, by anyone, at locations within
+// roughly radiusKm of the given coordinates. Rejected catches are not counted.
+func (r *AnimalCatchRepository) CountSpeciesNear(speciesID uuid.UUID, lat, lng, radiusKm float64) (int64, error) {
+	// A bounding box is close enough for a bonus and can use plain comparisons
+	latDelta, lngDelta := boundingBox(lat, radiusKm)
+
+	var count int64
+	err := r.db.Model(&models.AnimalCatch{}).
+		Joins("JOIN locations ON locations.id = animal_catches.location_id").
+		Where("animal_catches.species_id = ? AND animal_catches.verification_status <> ?", speciesID, models.VerificationRejected).
+		Where("locations.latitude BETWEEN ? AND ?", lat-latDelta, lat+latDelta).
+		Where("locations.longitude BETWEEN ? AND ?", lng-lngDelta, lng+lngDelta).
+		Count(&count).Error
+	return count, err
+}
+
+// GetFeedCatches retrieves the given catches for a feed, leaving out those the
+// viewer may not see and rejected ones. The result is in no particular order.
+func (r *AnimalCatchRepository) GetFeedCatches(ids []uuid.UUID, viewer models.Viewer) ([]models.AnimalCatch, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var catches []models.AnimalCatch
+	err := r.db.Model(&models.AnimalCatch{}).
+		Where("animal_catches.id IN ? AND animal_catches.verification_status <> ?", ids, models.VerificationRejected).
+		Scopes(visibleTo(viewer)).
+		Preload("User", publicProfile).Preload("Species").Preload("Location").Preload("Photo").
+		Find(&catches).Error
+	if err != nil {
+		return nil, err
+	}
+	redactFor(catches, viewer)
+	return catches, nil
+}
+
+// GetRecentVisible retrieves the user's most recent catches that the viewer may
+// see, with their species, newest first. Rejected catches are left out.
+func (r *AnimalCatchRepository) GetRecentVisible(userID uuid.UUID, viewer models.Viewer, limit int) ([]models.AnimalCatch, error) {
+	var catches []models.AnimalCatch
+	err := r.db.Model(&models.AnimalCatch{}).
+		Where("animal_catches.user_id = ? AND animal_catches.verification_status <> ?", userID, models.VerificationRejected).
+		Scopes(visibleTo(viewer)).
+		Preload("Species").
+		Order("animal_catches.created_at DESC").
+		Limit(limit).
+		Find(&catches).Error
+	return catches, err
+}
+
+// GetPendingCatches retrieves catches awaiting verification, oldest first.
+// An empty reason returns the whole queue.
+func (r *AnimalCatchRepository) GetPendingCatches(reason models.ModerationReason, limit, offset int) ([]models.AnimalCatch, int64, error) {
 	var catches []models.AnimalCatch
 	var total int64
 
-	// Count total public records
-	err := r.db.Model(&models.AnimalCatch{}).Where("is_public = ?", true).Count(&total).Error
+	query := r.db.Model(&models.AnimalCatch{}).Where("verification_status = ?", models.VerificationPending)
+	if reason != "" {
+		query = query.Where("moderation_reason = ?", reason)
+	}
+
+	// Count total pending records
+	err := query.Session(&gorm.Session{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results with relationships
-	err = r.db.Preload("User").Preload("Species").Preload("Location").
-		Where("is_public = ? AND verification_status = ?", true, models.VerificationApproved).
-		Order("caught_at DESC").
+	err = query.Preload("User").Preload("Species").Preload("Location").Preload("Photo").
+		Order("created_at ASC").
 		Limit(limit).Offset(offset).
 		Find(&catches).Error
 
 	return catches, total, err
 }
 
-// GetUserStats retrieves statistics for a user's catches
-func (r *AnimalCatchRepository) GetUserStats(userID uuid.UUID) (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
+// ReviewedCatch identifies a catch updated by VerifyCatches and its owner
+type ReviewedCatch struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// VerifyCatches moves pending catches to the given verification status in bulk,
+// recording the reviewer. Catches that are no longer pending or that belong to
+// the reviewer are left untouched. Returns the catches that were actually updated.
+func (r *AnimalCatchRepository) VerifyCatches(ids []uuid.UUID, status models.VerificationStatus, reviewerID uuid.UUID, notes string) ([]ReviewedCatch, error) {
+	var updated []ReviewedCatch
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the rows so two moderators can't review the same catch at once
+		err := tx.Model(&models.AnimalCatch{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "user_id").
+			Where("id IN ? AND verification_status = ? AND user_id <> ?", ids, models.VerificationPending, reviewerID).
+			Find(&updated).Error
+		if err != nil || len(updated) == 0 {
+			return err
+		}
 
-	// Total catches
-	var totalCatches int64
-	err := r.db.Model(&models.AnimalCatch{}).Where("user_id = ?", userID).Count(&totalCatches).Error
+		updatedIDs := make([]uuid.UUID, len(updated))
+		for i, catch := range updated {
+			updatedIDs[i] = catch.ID
+		}
+
+		now := time.Now()
+		return tx.Model(&models.AnimalCatch{}).
+			Where("id IN ?", updatedIDs).
+			Updates(map[string]interface{}{
+				"verification_status": status,
+				"verified_by":         reviewerID,
+				"verified_at":         now,
+				"verification_notes":  notes,
+			}).Error
+	})
 	if err != nil {
 		return nil, err
 	}
-	stats["total_catches"] = totalCatches
 
-	// Unique species caught
-	var uniqueSpecies int64
-	err = r.db.Model(&models.AnimalCatch{}).
-		Where("user_id = ?", userID).
-		Distinct("species_id").
-		Count(&uniqueSpecies).Error
+	return updated, nil
+}
+
+// GetPreviousUserCatch retrieves the user's most recent catch before the given time,
+// excluding the given catch
+func (r *AnimalCatchRepository) GetPreviousUserCatch(userID uuid.UUID, before time.Time, excludeID uuid.UUID) (*models.AnimalCatch, error) {
+	var catch models.AnimalCatch
+	err := r.db.Preload("Location").
+		Where("user_id = ? AND caught_at <= ? AND id <> ?", userID, before, excludeID).
+		Order("caught_at DESC").
+		First(&catch).Error
 	if err != nil {
 		return nil, err
 	}
-	stats["unique_species"] = uniqueSpecies
+	return &catch, nil
+}
 
-	// Total points earned
-	var totalPoints int64
-	err = r.db.Model(&models.AnimalCatch{}).
-		Where("user_id = ?", userID).
-		Select("COALESCE(SUM(points_awarded), 0)").
-		Scan(&totalPoints).Error
-	if err != nil {
-		return nil, err
+// GetUnscoredPendingIDs returns up to limit pending catches that automatic
+// verification hasn't scored and that haven't changed since before, oldest first
+func (r *AnimalCatchRepository) GetUnscoredPendingIDs(before time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&models.AnimalCatch{}).
+		Where("verification_status = ? AND verification_score IS NULL AND updated_at < ?", models.VerificationPending, before).
+		Order("created_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ApplyAutoVerification records the outcome of automatic verification. The catch
+// is only touched while it is still pending, so a moderator decision made in the
+// meantime always wins. Returns whether the catch was updated.
+func (r *AnimalCatchRepository) ApplyAutoVerification(id uuid.UUID, status models.VerificationStatus, score float64, notes string) (bool, error) {
+	updates := map[string]interface{}{
+		"verification_status": status,
+		"verification_score":  score,
+		"verification_notes":  notes,
+	}
+	if status != models.VerificationPending {
+		updates["verified_at"] = time.Now()
 	}
-	stats["total_points"] = totalPoints
 
-	// Verified catches
-	var verifiedCatches int64
-	err = r.db.Model(&models.AnimalCatch{}).
-		Where("user_id = ? AND verification_status IN ?", userID, 
-			[]models.VerificationStatus{models.VerificationApproved, models.VerificationAuto}).
-		Count(&verifiedCatches).Error
-	if err != nil {
+	result := r.db.Model(&models.AnimalCatch{}).
+		Where("id = ? AND verification_status = ?", id, models.VerificationPending).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SimilarPhotoCatch is a catch whose photo hash is close to a given one
+type SimilarPhotoCatch struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	Distance int // Hamming distance between the perceptual hashes
+}
+
+// FindOwnSimilarPhoto returns the user's catch whose perceptual hash is closest
+// to hash, within maxDistance bits, or nil if there is none
+func (r *AnimalCatchRepository) FindOwnSimilarPhoto(userID uuid.UUID, hash int64, maxDistance int, excludeID uuid.UUID) (*SimilarPhotoCatch, error) {
+	return r.findSimilarPhoto(hash, maxDistance, excludeID, "user_id = ?", userID)
+}
+
+// FindOthersSimilarPhoto returns the catch by another user whose perceptual hash
+// is closest to hash, within maxDistance bits, or nil if there is none. Rejected
+// catches are left out: they don't make a photo suspect.
+func (r *AnimalCatchRepository) FindOthersSimilarPhoto(userID uuid.UUID, hash int64, maxDistance int, excludeID uuid.UUID) (*SimilarPhotoCatch, error) {
+	return r.findSimilarPhoto(hash, maxDistance, excludeID, "user_id <> ? AND verification_status <> ?", userID, models.VerificationRejected)
+}
+
+// findSimilarPhoto returns the closest catch matching where, oldest first among
+// equals. Hamming distance can't use an index, so this scans every hashed catch;
+// fine at our volume, revisit with hash banding if it grows.
+func (r *AnimalCatchRepository) findSimilarPhoto(hash int64, maxDistance int, excludeID uuid.UUID, where string, args ...interface{}) (*SimilarPhotoCatch, error) {
+	values := append([]interface{}{hash, excludeID}, args...)
+	values = append(values, maxDistance)
+
+	var similar []SimilarPhotoCatch
+	err := r.db.Raw(`
+		SELECT id, user_id, distance FROM (
+			SELECT id, user_id, created_at,
+				length(replace(((perceptual_hash # ?)::bit(64))::text, '0', '')) AS distance
+			FROM animal_catches
+			WHERE perceptual_hash IS NOT NULL AND id <> ? AND `+where+`
+		) hashed
+		WHERE distance <= ?
+		ORDER BY distance ASC, created_at ASC
+		LIMIT 1`, values...).
+		Scan(&similar).Error
+	if err != nil || len(similar) == 0 {
 		return nil, err
 	}
-	stats["verified_catches"] = verifiedCatches
+	return &similar[0], nil
+}
 
-	return stats, nil
+// GetBatchAfter returns up to limit catches with IDs greater than afterID, in ID
+// order, with their location. Used to walk every catch in batches for backfills.
+func (r *AnimalCatchRepository) GetBatchAfter(afterID uuid.UUID, limit int) ([]models.AnimalCatch, error) {
+	var catches []models.AnimalCatch
+	err := r.db.Preload("Location").
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&catches).Error
+	return catches, err
 }
 
 // Update updates an existing animal catch
@@ -179,7 +581,52 @@ func (r *AnimalCatchRepository) Update(catch *models.AnimalCatch) error {
 	return r.db.Save(catch).Error
 }
 
+// UpdateScore saves the first-catch flag and points of a catch
+func (r *AnimalCatchRepository) UpdateScore(catch *models.AnimalCatch) error {
+	return r.db.Model(catch).
+		Select("is_first_catch", "points_awarded", "combo_multiplier", "points_breakdown").
+		Updates(catch).Error
+}
+
+// UpdateFields updates only the given columns of an animal catch
+func (r *AnimalCatchRepository) UpdateFields(id uuid.UUID, updates map[string]interface{}) error {
+	return r.db.Model(&models.AnimalCatch{}).Where("id = ?", id).Updates(updates).Error
+}
+
 // Delete deletes an animal catch (soft delete)
 func (r *AnimalCatchRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&models.AnimalCatch{}, id).Error
 }
+
+// DeleteWithInteractions deletes a catch together with its likes, comments and
+// feed items, and unlinks the badges it earned. It returns how many of the
+// likes and comments were by other users, which count towards the owner's stats.
+// Run it in the transaction that holds the owner's stats lock.
+func (r *AnimalCatchRepository) DeleteWithInteractions(catch *models.AnimalCatch) (likesReceived, commentsReceived int64, err error) {
+	likes := r.db.Where("catch_id = ? AND user_id <> ?", catch.ID, catch.UserID).Delete(&models.CatchLike{})
+	if likes.Error != nil {
+		return 0, 0, likes.Error
+	}
+	if err := r.db.Where("catch_id = ?", catch.ID).Delete(&models.CatchLike{}).Error; err != nil {
+		return 0, 0, err
+	}
+	comments := r.db.Where("catch_id = ? AND user_id <> ?", catch.ID, catch.UserID).Delete(&models.CatchComment{})
+	if comments.Error != nil {
+		return 0, 0, comments.Error
+	}
+	if err := r.db.Where("catch_id = ?", catch.ID).Delete(&models.CatchComment{}).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := r.db.Where("catch_id = ?", catch.ID).Delete(&models.FeedItem{}).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := r.db.Model(&models.UserBadge{}).
+		Where("related_catch_id = ?", catch.ID).
+		Update("related_catch_id", nil).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := r.db.Where("id = ?", catch.ID).Delete(&models.AnimalCatch{}).Error; err != nil {
+		return 0, 0, err
+	}
+	return likes.RowsAffected, comments.RowsAffected, nil
+}
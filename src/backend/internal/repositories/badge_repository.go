@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BadgeRepository struct {
+	db *gorm.DB
+}
+
+func NewBadgeRepository() *BadgeRepository {
+	return &BadgeRepository{
+		db: config.DB,
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *BadgeRepository) WithTx(tx *gorm.DB) *BadgeRepository {
+	return &BadgeRepository{db: tx}
+}
+
+// GetActive retrieves every badge that can currently be earned
+func (r *BadgeRepository) GetActive() ([]models.Badge, error) {
+	var badges []models.Badge
+	err := r.db.Where("is_active = ?", true).
+		Order("badge_type ASC, points_awarded ASC, name ASC").
+		Find(&badges).Error
+	return badges, err
+}
+
+// GetAll retrieves every badge, active or not
+func (r *BadgeRepository) GetAll() ([]models.Badge, error) {
+	var badges []models.Badge
+	err := r.db.Order("badge_type ASC, points_awarded ASC, name ASC").Find(&badges).Error
+	return badges, err
+}
+
+// GetByID retrieves a badge
+func (r *BadgeRepository) GetByID(id uuid.UUID) (*models.Badge, error) {
+	var badge models.Badge
+	err := r.db.Where("id = ?", id).First(&badge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &badge, nil
+}
+
+// GetByName retrieves the badge with the given name
+func (r *BadgeRepository) GetByName(name string) (*models.Badge, error) {
+	var badge models.Badge
+	err := r.db.Where("name = ?", name).First(&badge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &badge, nil
+}
+
+// Create adds a badge. Every field is written, so that false isn't replaced by
+// a column default.
+func (r *BadgeRepository) Create(badge *models.Badge) error {
+	return r.db.Select("*").Omit(clause.Associations).Create(badge).Error
+}
+
+// Update saves every field of a badge
+func (r *BadgeRepository) Update(badge *models.Badge) error {
+	return r.db.Omit(clause.Associations).Save(badge).Error
+}
+
+// GetUserBadges retrieves a user's badge progress records, earned or not
+func (r *BadgeRepository) GetUserBadges(userID uuid.UUID) ([]models.UserBadge, error) {
+	var userBadges []models.UserBadge
+	err := r.db.Where("user_id = ?", userID).Find(&userBadges).Error
+	return userBadges, err
+}
+
+// GetUserBadgesWithBadge retrieves a user's badge progress records, earned or
+// not, with their badges
+func (r *BadgeRepository) GetUserBadgesWithBadge(userID uuid.UUID) ([]models.UserBadge, error) {
+	var userBadges []models.UserBadge
+	err := r.db.Preload("Badge").Where("user_id = ?", userID).Find(&userBadges).Error
+	return userBadges, err
+}
+
+// SaveUserBadge creates or updates a user's progress towards a badge
+func (r *BadgeRepository) SaveUserBadge(userBadge *models.UserBadge) error {
+	if userBadge.ID == uuid.Nil {
+		return r.db.Omit(clause.Associations).Create(userBadge).Error
+	}
+	return r.db.Omit(clause.Associations).Save(userBadge).Error
+}
+
+// GetShowcase retrieves the earned badges a user shows on their profile, with
+// the badges, in display order
+func (r *BadgeRepository) GetShowcase(userID uuid.UUID) ([]models.UserBadge, error) {
+	var userBadges []models.UserBadge
+	err := r.db.Preload("Badge").
+		Where("user_id = ? AND earned_at IS NOT NULL AND is_displayed = ?", userID, true).
+		Order("display_order ASC, earned_at ASC").
+		Find(&userBadges).Error
+	return userBadges, err
+}
+
+// SetShowcase shows the given earned badges on the user's profile, in order,
+// and hides the others. Badges not earned are ignored.
+func (r *BadgeRepository) SetShowcase(userID uuid.UUID, badgeIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.UserBadge{}).
+			Where("user_id = ? AND earned_at IS NOT NULL", userID).
+			Updates(map[string]interface{}{"is_displayed": false, "display_order": 0}).Error
+		if err != nil {
+			return err
+		}
+		for i, badgeID := range badgeIDs {
+			err := tx.Model(&models.UserBadge{}).
+				Where("user_id = ? AND badge_id = ? AND earned_at IS NOT NULL", userID, badgeID).
+				Updates(map[string]interface{}{"is_displayed": true, "display_order": i}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Badge rules are evaluated in SQL over the user's catches that aren't
+// rejected, joined with their species and locations.
+
+// localCatchTime is when a catch was made, in the time zone where it was made
+const localCatchTime = "(animal_catches.caught_at AT TIME ZONE COALESCE(NULLIF(locations.timezone, ''), 'UTC'))"
+
+// catchSeason is the meteorological season of a catch, from the local month,
+// flipped south of the equator
+const catchSeason = `(CASE (EXTRACT(MONTH FROM ` + localCatchTime + `)::int % 12 / 3
+	+ CASE WHEN locations.latitude < 0 THEN 2 ELSE 0 END) % 4
+	WHEN 0 THEN 'winter' WHEN 1 THEN 'spring' WHEN 2 THEN 'summer' ELSE 'autumn' END)`
+
+// continentZones maps time zone names, as LIKE patterns, to continents, first
+// match wins. Zones not listed, such as nautical ones at sea, have no continent.
+var continentZones = []struct {
+	pattern   string
+	continent string
+}{
+	{"America/Argentina/%", models.ContinentSouthAmerica},
+	{"America/Asuncion", models.ContinentSouthAmerica},
+	{"America/Bahia", models.ContinentSouthAmerica},
+	{"America/Belem", models.ContinentSouthAmerica},
+	{"America/Bogota", models.ContinentSouthAmerica},
+	{"America/Campo_Grande", models.ContinentSouthAmerica},
+	{"America/Caracas", models.ContinentSouthAmerica},
+	{"America/Cayenne", models.ContinentSouthAmerica},
+	{"America/Cuiaba", models.ContinentSouthAmerica},
+	{"America/Fortaleza", models.ContinentSouthAmerica},
+	{"America/Guayaquil", models.ContinentSouthAmerica},
+	{"America/Guyana", models.ContinentSouthAmerica},
+	{"America/La_Paz", models.ContinentSouthAmerica},
+	{"America/Lima", models.ContinentSouthAmerica},
+	{"America/Manaus", models.ContinentSouthAmerica},
+	{"America/Montevideo", models.ContinentSouthAmerica},
+	{"America/Noronha", models.ContinentSouthAmerica},
+	{"America/Paramaribo", models.ContinentSouthAmerica},
+	{"America/Porto_Velho", models.ContinentSouthAmerica},
+	{"America/Punta_Arenas", models.ContinentSouthAmerica},
+	{"America/Recife", models.ContinentSouthAmerica},
+	{"America/Rio_Branco", models.ContinentSouthAmerica},
+	{"America/Santiago", models.ContinentSouthAmerica},
+	{"America/Sao_Paulo", models.ContinentSouthAmerica},
+	{"Atlantic/Stanley", models.ContinentSouthAmerica},
+	{"Pacific/Easter", models.ContinentSouthAmerica},
+	{"Pacific/Galapagos", models.ContinentSouthAmerica},
+	{"America/%", models.ContinentNorthAmerica},
+	{"Atlantic/Bermuda", models.ContinentNorthAmerica},
+	{"Europe/%", models.ContinentEurope},
+	{"Arctic/%", models.ContinentEurope},
+	{"Atlantic/Azores", models.ContinentEurope},
+	{"Atlantic/Faroe", models.ContinentEurope},
+	{"Atlantic/Reykjavik", models.ContinentEurope},
+	{"Africa/%", models.ContinentAfrica},
+	{"Atlantic/%", models.ContinentAfrica},
+	{"Indian/Chagos", models.ContinentAsia},
+	{"Indian/Maldives", models.ContinentAsia},
+	{"Indian/%", models.ContinentAfrica},
+	{"Asia/%", models.ContinentAsia},
+	{"Australia/%", models.ContinentOceania},
+	{"Pacific/%", models.ContinentOceania},
+	{"Antarctica/%", models.ContinentAntarctica},
+}
+
+// locationContinent is the continent of a location, from its time zone
+var locationContinent = func() string {
+	var sql strings.Builder
+	sql.WriteString("(CASE")
+	for _, zone := range continentZones {
+		fmt.Fprintf(&sql, " WHEN locations.timezone LIKE '%s' THEN '%s'", zone.pattern, zone.continent)
+	}
+	sql.WriteString(" END)")
+	return sql.String()
+}()
+
+// minutesFromMidnight is how far, in minutes, a catch was made from the nearest
+// local midnight
+const minutesFromMidnight = "(LEAST(EXTRACT(EPOCH FROM " + localCatchTime + "::time), 86400 - EXTRACT(EPOCH FROM " + localCatchTime + "::time)) / 60)"
+
+// ruleFieldColumns are the SQL expressions of the fields rule conditions test
+var ruleFieldColumns = map[models.RuleField]string{
+	models.FieldCatchTimeOfDay:           "animal_catches.time_of_day",
+	models.FieldCatchWeather:             "animal_catches.weather",
+	models.FieldCatchSeason:              catchSeason,
+	models.FieldCatchMonth:               "EXTRACT(MONTH FROM " + localCatchTime + ")",
+	models.FieldCatchHour:                "EXTRACT(HOUR FROM " + localCatchTime + ")",
+	models.FieldCatchMinutesFromMidnight: minutesFromMidnight,
+	models.FieldCatchTemperature:         "animal_catches.temperature",
+	models.FieldCatchVisibility:          "animal_catches.visibility",
+	models.FieldCatchIsFirstCatch:        "animal_catches.is_first_catch",
+	models.FieldCatchVerificationStatus:  "animal_catches.verification_status",
+	models.FieldSpeciesID:                "species.id",
+	models.FieldSpeciesCategory:          "species.category",
+	models.FieldSpeciesFamily:            "species.family",
+	models.FieldSpeciesGenus:             "species.genus",
+	models.FieldSpeciesRarity:            "species.rarity",
+	models.FieldSpeciesConservation:      "species.conservation_status",
+	models.FieldLocationType:             "locations.location_type",
+	models.FieldLocationCountry:          "locations.country",
+	models.FieldLocationCountryCode:      "locations.country_code",
+	models.FieldLocationCity:             "locations.city",
+	models.FieldLocationContinent:        locationContinent,
+}
+
+// ruleMeasureColumns are the SQL aggregates of the measures counted over catches.
+// Distinct counts leave out unknown values.
+var ruleMeasureColumns = map[models.RuleMeasure]string{
+	models.MeasureCatches:       "COUNT(*)",
+	models.MeasureSpecies:       "COUNT(DISTINCT animal_catches.species_id)",
+	models.MeasureFamilies:      "COUNT(DISTINCT NULLIF(species.family, ''))",
+	models.MeasureCategories:    "COUNT(DISTINCT species.category)",
+	models.MeasureLocations:     "COUNT(DISTINCT animal_catches.location_id)",
+	models.MeasureCountries:     "COUNT(DISTINCT NULLIF(locations.country, ''))",
+	models.MeasureCities:        "COUNT(DISTINCT CASE WHEN locations.city <> '' THEN locations.country || '/' || locations.city END)",
+	models.MeasureContinents:    "COUNT(DISTINCT " + locationContinent + ")",
+	models.MeasureLocationTypes: "COUNT(DISTINCT locations.location_type)",
+	models.MeasureWeatherTypes:  "COUNT(DISTINCT NULLIF(animal_catches.weather, ''))",
+	models.MeasureSeasons:       "COUNT(DISTINCT " + catchSeason + ")",
+	models.MeasureDays:          "COUNT(DISTINCT " + localCatchTime + "::date)",
+	models.MeasureFirstCatch:    "COUNT(*)",
+}
+
+// ruleConditionSQL returns the SQL of a validated rule condition and its arguments
+func ruleConditionSQL(condition *models.RuleCondition) (string, []interface{}, error) {
+	switch {
+	case condition.All != nil || condition.Any != nil:
+		children, joiner := condition.All, " AND "
+		if condition.Any != nil {
+			children, joiner = condition.Any, " OR "
+		}
+		parts := make([]string, 0, len(children))
+		var args []interface{}
+		for i := range children {
+			sql, childArgs, err := ruleConditionSQL(&children[i])
+			if err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, sql)
+			args = append(args, childArgs...)
+		}
+		return "(" + strings.Join(parts, joiner) + ")", args, nil
+
+	case condition.Not != nil:
+		sql, args, err := ruleConditionSQL(condition.Not)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(NOT COALESCE(" + sql + ", false))", args, nil
+	}
+
+	column, ok := ruleFieldColumns[condition.Field]
+	if !ok {
+		return "", nil, fmt.Errorf("badge rule field %q has no column", condition.Field)
+	}
+	value := condition.Value
+	switch condition.Op {
+	case models.OpEq:
+		return column + " = ?", []interface{}{value}, nil
+	case models.OpNe:
+		return column + " IS DISTINCT FROM ?", []interface{}{value}, nil
+	case models.OpIn:
+		return column + " IN ?", []interface{}{value}, nil
+	case models.OpNotIn:
+		return "COALESCE(" + column + " NOT IN ?, true)", []interface{}{value}, nil
+	case models.OpGt:
+		return column + " > ?", []interface{}{value}, nil
+	case models.OpGte:
+		return column + " >= ?", []interface{}{value}, nil
+	case models.OpLt:
+		return column + " < ?", []interface{}{value}, nil
+	case models.OpLte:
+		return column + " <= ?", []interface{}{value}, nil
+	}
+	return "", nil, fmt.Errorf("badge rule operator %q has no SQL", condition.Op)
+}
+
+// CountForRule counts what a requirement of a badge rule measures over the
+// user's catches that aren't rejected. The requirement must be valid and count
+// a catch measure.
+func (r *AnimalCatchRepository) CountForRule(userID uuid.UUID, requirement *models.RuleRequirement) (int64, error) {
+	aggregate, ok := ruleMeasureColumns[requirement.Count]
+	if !ok {
+		return 0, fmt.Errorf("badge rule measure %q isn't counted over catches", requirement.Count)
+	}
+
+	query := r.countedCatches(userID).
+		Joins("JOIN species ON species.id = animal_catches.species_id").
+		Joins("JOIN locations ON locations.id = animal_catches.location_id")
+	if requirement.Where != nil {
+		sql, args, err := ruleConditionSQL(requirement.Where)
+		if err != nil {
+			return 0, err
+		}
+		query = query.Where(sql, args...)
+	}
+	if requirement.Count == models.MeasureFirstCatch {
+		first := r.countedCatches(userID).
+			Select("animal_catches.id").
+			Order("animal_catches.caught_at ASC, animal_catches.id ASC").
+			Limit(1)
+		query = query.Where("animal_catches.id = (?)", first)
+	}
+
+	var count int64
+	err := query.Select(aggregate).Scan(&count).Error
+	return count, err
+}
+
+// CountForRule counts the active species matching a validated rule condition on
+// species fields, or every active species if it is nil
+func (r *SpeciesRepository) CountForRule(where *models.RuleCondition) (int64, error) {
+	query := r.db.Model(&models.Species{}).Where("species.is_active = ?", true)
+	if where != nil {
+		sql, args, err := ruleConditionSQL(where)
+		if err != nil {
+			return 0, err
+		}
+		query = query.Where(sql, args...)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
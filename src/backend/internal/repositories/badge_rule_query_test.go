@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestRuleConditionSQL(t *testing.T) {
+	rarity := func(op models.RuleOp, value interface{}) models.RuleCondition {
+		return models.RuleCondition{Field: models.FieldSpeciesRarity, Op: op, Value: value}
+	}
+	temperature := func(op models.RuleOp, value float64) models.RuleCondition {
+		return models.RuleCondition{Field: models.FieldCatchTemperature, Op: op, Value: value}
+	}
+	rareOrEpic := []interface{}{"rare", "epic"}
+
+	tests := []struct {
+		name      string
+		condition models.RuleCondition
+		wantSQL   string
+		wantArgs  []interface{}
+	}{
+		{"eq", rarity(models.OpEq, "rare"), "species.rarity = ?", []interface{}{"rare"}},
+		{"ne matches unknown values", rarity(models.OpNe, "rare"), "species.rarity IS DISTINCT FROM ?", []interface{}{"rare"}},
+		{"in", rarity(models.OpIn, rareOrEpic), "species.rarity IN ?", []interface{}{rareOrEpic}},
+		{"not_in matches unknown values", rarity(models.OpNotIn, rareOrEpic), "COALESCE(species.rarity NOT IN ?, true)", []interface{}{rareOrEpic}},
+		{"gt", temperature(models.OpGt, 30), "animal_catches.temperature > ?", []interface{}{30.0}},
+		{"gte", temperature(models.OpGte, 30), "animal_catches.temperature >= ?", []interface{}{30.0}},
+		{"lt", temperature(models.OpLt, -10), "animal_catches.temperature < ?", []interface{}{-10.0}},
+		{"lte", temperature(models.OpLte, -10), "animal_catches.temperature <= ?", []interface{}{-10.0}},
+		{"minutes from midnight",
+			models.RuleCondition{Field: models.FieldCatchMinutesFromMidnight, Op: models.OpLte, Value: 5.0},
+			minutesFromMidnight + " <= ?", []interface{}{5.0}},
+		{"season",
+			models.RuleCondition{Field: models.FieldCatchSeason, Op: models.OpEq, Value: "winter"},
+			catchSeason + " = ?", []interface{}{"winter"}},
+		{"all",
+			models.RuleCondition{All: []models.RuleCondition{rarity(models.OpEq, "rare"), temperature(models.OpGt, 30)}},
+			"(species.rarity = ? AND animal_catches.temperature > ?)", []interface{}{"rare", 30.0}},
+		{"any",
+			models.RuleCondition{Any: []models.RuleCondition{rarity(models.OpEq, "rare"), temperature(models.OpGt, 30)}},
+			"(species.rarity = ? OR animal_catches.temperature > ?)", []interface{}{"rare", 30.0}},
+		{"not is true for unknown values",
+			models.RuleCondition{Not: &models.RuleCondition{Field: models.FieldSpeciesRarity, Op: models.OpEq, Value: "rare"}},
+			"(NOT COALESCE(species.rarity = ?, false))", []interface{}{"rare"}},
+		{"nested keeps argument order",
+			models.RuleCondition{All: []models.RuleCondition{
+				{Any: []models.RuleCondition{rarity(models.OpEq, "rare"), rarity(models.OpEq, "epic")}},
+				{Not: &models.RuleCondition{Field: models.FieldCatchTemperature, Op: models.OpLt, Value: 0.0}},
+			}},
+			"((species.rarity = ? OR species.rarity = ?) AND (NOT COALESCE(animal_catches.temperature < ?, false)))",
+			[]interface{}{"rare", "epic", 0.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := ruleConditionSQL(&tt.condition)
+			if err != nil {
+				t.Fatalf("ruleConditionSQL() error = %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("ruleConditionSQL() sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("ruleConditionSQL() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestRuleConditionSQLRejectsUnknown(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition models.RuleCondition
+	}{
+		{"unknown field", models.RuleCondition{Field: "catch.mood", Op: models.OpEq, Value: "happy"}},
+		{"unknown operator", models.RuleCondition{Field: models.FieldCatchHour, Op: "like", Value: 1.0}},
+		{"unknown inside all", models.RuleCondition{All: []models.RuleCondition{
+			{Field: models.FieldCatchHour, Op: models.OpEq, Value: 1.0},
+			{Field: "catch.mood", Op: models.OpEq, Value: "happy"},
+		}}},
+		{"unknown inside not", models.RuleCondition{Not: &models.RuleCondition{Field: "catch.mood", Op: models.OpEq, Value: "happy"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ruleConditionSQL(&tt.condition); err == nil {
+				t.Fatal("ruleConditionSQL() succeeded")
+			}
+		})
+	}
+}
+
+// testDB connects to the database in TEST_DATABASE_URL, skipping the test if
+// it isn't set
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// catchExpression evaluates a SQL expression over one catch made at caughtAt,
+// at a location in the given time zone and latitude
+func catchExpression(t *testing.T, db *gorm.DB, expression string, caughtAt time.Time, timezone string, latitude float64, dest interface{}) {
+	t.Helper()
+	err := db.Raw(`SELECT `+expression+`
+		FROM (SELECT ?::timestamptz AS caught_at) AS animal_catches,
+			(SELECT ?::text AS timezone, ?::float8 AS latitude) AS locations`,
+		caughtAt, timezone, latitude).Scan(dest).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCatchSeason(t *testing.T) {
+	db := testDB(t)
+
+	tests := []struct {
+		name     string
+		caughtAt string
+		timezone string
+		latitude float64
+		want     string
+	}{
+		{"northern winter", "2024-01-15T12:00:00Z", "Europe/London", 51.5, models.SeasonWinter},
+		{"northern spring", "2024-03-01T12:00:00Z", "Europe/London", 51.5, models.SeasonSpring},
+		{"northern summer", "2024-08-31T12:00:00Z", "Europe/London", 51.5, models.SeasonSummer},
+		{"northern autumn", "2024-11-30T12:00:00Z", "Europe/London", 51.5, models.SeasonAutumn},
+		{"southern summer", "2024-01-15T12:00:00Z", "Australia/Sydney", -33.9, models.SeasonSummer},
+		{"southern autumn", "2024-03-15T12:00:00Z", "Australia/Sydney", -33.9, models.SeasonAutumn},
+		{"southern winter", "2024-07-15T12:00:00Z", "Australia/Sydney", -33.9, models.SeasonWinter},
+		{"southern spring", "2024-10-15T12:00:00Z", "Australia/Sydney", -33.9, models.SeasonSpring},
+		// The local month decides, not the UTC one
+		{"local month ahead of UTC", "2024-11-30T23:30:00Z", "Europe/Berlin", 52.5, models.SeasonWinter},
+		{"local month behind UTC", "2024-06-01T02:00:00Z", "America/New_York", 40.7, models.SeasonSpring},
+		{"no time zone is UTC", "2024-08-31T23:30:00Z", "", 10, models.SeasonSummer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caughtAt, err := time.Parse(time.RFC3339, tt.caughtAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var season string
+			catchExpression(t, db, catchSeason, caughtAt, tt.timezone, tt.latitude, &season)
+			if season != tt.want {
+				t.Errorf("catchSeason = %s, want %s", season, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinutesFromMidnight(t *testing.T) {
+	db := testDB(t)
+
+	tests := []struct {
+		name     string
+		caughtAt string
+		timezone string
+		want     float64
+	}{
+		{"midnight", "2024-06-01T00:00:00Z", "UTC", 0},
+		{"just after", "2024-06-01T00:10:00Z", "UTC", 10},
+		{"just before", "2024-06-01T23:55:00Z", "UTC", 5},
+		{"noon is furthest", "2024-06-01T12:00:00Z", "UTC", 720},
+		{"seconds count", "2024-06-01T23:59:30Z", "UTC", 0.5},
+		{"local midnight", "2024-06-01T22:57:00Z", "Europe/Berlin", 57},
+		{"no time zone is UTC", "2024-06-01T22:57:00Z", "", 63},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caughtAt, err := time.Parse(time.RFC3339, tt.caughtAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var minutes float64
+			catchExpression(t, db, minutesFromMidnight, caughtAt, tt.timezone, 0, &minutes)
+			if minutes != tt.want {
+				t.Errorf("minutesFromMidnight = %v, want %v", minutes, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CatchInteractionRepository stores likes and comments on catches. Every change
+// also adjusts the catch's likes_count/comments_count and, for interactions by
+// someone other than the owner, the owner's total_likes/total_comments, in the
+// same transaction.
+type CatchInteractionRepository struct {
+	db *gorm.DB
+}
+
+func NewCatchInteractionRepository() *CatchInteractionRepository {
+	return &CatchInteractionRepository{
+		db: config.DB,
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *CatchInteractionRepository) WithTx(tx *gorm.DB) *CatchInteractionRepository {
+	return &CatchInteractionRepository{db: tx}
+}
+
+// Like records userID's like of the catch. It returns false if they had
+// already liked it, in which case nothing changes.
+func (r *CatchInteractionRepository) Like(catch *models.AnimalCatch, userID uuid.UUID) (bool, error) {
+	liked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		like := models.CatchLike{CatchID: catch.ID, UserID: userID}
+		result := tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "catch_id"}, {Name: "user_id"}}, DoNothing: true}).
+			Create(&like)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		liked = true
+		return adjustInteractionCounters(tx, catch, userID, "likes_count", "total_likes", 1)
+	})
+	return liked, err
+}
+
+// Unlike removes userID's like of the catch. It returns false if they hadn't
+// liked it.
+func (r *CatchInteractionRepository) Unlike(catch *models.AnimalCatch, userID uuid.UUID) (bool, error) {
+	unliked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("catch_id = ? AND user_id = ?", catch.ID, userID).Delete(&models.CatchLike{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		unliked = true
+		return adjustInteractionCounters(tx, catch, userID, "likes_count", "total_likes", -1)
+	})
+	return unliked, err
+}
+
+// HasLiked reports whether userID likes the catch
+func (r *CatchInteractionRepository) HasLiked(catchID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.CatchLike{}).
+		Where("catch_id = ? AND user_id = ?", catchID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetLikesCount returns the current number of likes on a catch
+func (r *CatchInteractionRepository) GetLikesCount(catchID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.Model(&models.AnimalCatch{}).
+		Where("id = ?", catchID).
+		Pluck("likes_count", &count).Error
+	return count, err
+}
+
+// CountReceived counts the likes and comments on the user's catches by other
+// users, what UserStats.TotalLikes and TotalComments track
+func (r *CatchInteractionRepository) CountReceived(userID uuid.UUID) (likes, comments int64, err error) {
+	err = r.db.Model(&models.CatchLike{}).
+		Joins("JOIN animal_catches ON animal_catches.id = catch_likes.catch_id").
+		Where("animal_catches.user_id = ? AND catch_likes.user_id <> ?", userID, userID).
+		Count(&likes).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	err = r.db.Model(&models.CatchComment{}).
+		Joins("JOIN animal_catches ON animal_catches.id = catch_comments.catch_id").
+		Where("animal_catches.user_id = ? AND catch_comments.user_id <> ?", userID, userID).
+		Count(&comments).Error
+	return likes, comments, err
+}
+
+// GetComments retrieves the comments on a catch, oldest first, with the public
+// profile of their authors
+func (r *CatchInteractionRepository) GetComments(catchID uuid.UUID, limit, offset int) ([]models.CatchComment, int64, error) {
+	var comments []models.CatchComment
+	var total int64
+
+	query := r.db.Model(&models.CatchComment{}).Where("catch_id = ?", catchID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("User", publicProfile).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&comments).Error
+	return comments, total, err
+}
+
+// GetComment retrieves a comment on a catch
+func (r *CatchInteractionRepository) GetComment(catchID, commentID uuid.UUID) (*models.CatchComment, error) {
+	var comment models.CatchComment
+	err := r.db.Preload("User", publicProfile).
+		Where("id = ? AND catch_id = ?", commentID, catchID).
+		First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// CreateComment adds a comment to the catch
+func (r *CatchInteractionRepository) CreateComment(catch *models.AnimalCatch, comment *models.CatchComment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		comment.CatchID = catch.ID
+		if err := tx.Omit(clause.Associations).Create(comment).Error; err != nil {
+			return err
+		}
+		return adjustInteractionCounters(tx, catch, comment.UserID, "comments_count", "total_comments", 1)
+	})
+}
+
+// UpdateCommentContent replaces the content of a comment and marks it edited
+func (r *CatchInteractionRepository) UpdateCommentContent(comment *models.CatchComment, content string) error {
+	now := time.Now()
+	err := r.db.Model(comment).Omit(clause.Associations).Updates(map[string]interface{}{
+		"content":   content,
+		"edited_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	comment.Content = content
+	comment.EditedAt = &now
+	return nil
+}
+
+// DeleteComment removes a comment from the catch. Deleting a comment that is
+// already gone is not an error and leaves the counters alone.
+func (r *CatchInteractionRepository) DeleteComment(catch *models.AnimalCatch, comment *models.CatchComment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND catch_id = ?", comment.ID, catch.ID).Delete(&models.CatchComment{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return adjustInteractionCounters(tx, catch, comment.UserID, "comments_count", "total_comments", -1)
+	})
+}
+
+// adjustInteractionCounters moves a catch counter, and the owner's matching
+// stats counter unless actorID is the owner, by delta. Neither goes below zero.
+// The catch row is updated before the stats row, the same order deleting and
+// reviewing catches take them in.
+func adjustInteractionCounters(tx *gorm.DB, catch *models.AnimalCatch, actorID uuid.UUID, catchColumn, statsColumn string, delta int) error {
+	err := tx.Model(&models.AnimalCatch{}).
+		Where("id = ?", catch.ID).
+		UpdateColumn(catchColumn, gorm.Expr("GREATEST("+catchColumn+" + ?, 0)", delta)).Error
+	if err != nil {
+		return err
+	}
+	if actorID == catch.UserID {
+		return nil
+	}
+	return tx.Model(&models.UserStats{}).
+		Where("user_id = ?", catch.UserID).
+		UpdateColumns(map[string]interface{}{
+			statsColumn:    gorm.Expr("GREATEST("+statsColumn+" + ?, 0)", delta),
+			"last_updated": time.Now(),
+		}).Error
+}
+
+// publicProfile limits a preloaded user to what other users may see
+func publicProfile(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "username", "name", "avatar")
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FeedRepository stores the materialized feed. Items are pointers to catches;
+// whether the reader may still see a catch is checked when the feed is read.
+type FeedRepository struct {
+	db *gorm.DB
+}
+
+func NewFeedRepository() *FeedRepository {
+	return &FeedRepository{
+		db: config.DB,
+	}
+}
+
+// FanOutToFollowers adds a following item for the catch to the feed of every
+// accepted follower of its author, in a single statement however many there are
+func (r *FeedRepository) FanOutToFollowers(catchID, authorID uuid.UUID, rankedAt time.Time) error {
+	return r.db.Exec(`
+		INSERT INTO feed_items (id, recipient_id, catch_id, author_id, reason, ranked_at, latitude, longitude, created_at)
+		SELECT gen_random_uuid(), follower_id, ?, ?, ?, ?, 0, 0, ?
+		FROM follows
+		WHERE followee_id = ? AND status = ?
+		ON CONFLICT (recipient_id, catch_id) DO NOTHING
+	`, catchID, authorID, models.FeedReasonFollowing, rankedAt, time.Now(), authorID, models.FollowAccepted).Error
+}
+
+// AddItems inserts feed items, skipping catches already in the recipient's feed
+func (r *FeedRepository) AddItems(items []models.FeedItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "recipient_id"}, {Name: "catch_id"}},
+		DoNothing: true,
+	}).Create(&items).Error
+}
+
+// RemoveAuthor removes the author's catches from the recipient's following items
+func (r *FeedRepository) RemoveAuthor(recipientID, authorID uuid.UUID) error {
+	return r.db.Where("recipient_id = ? AND author_id = ? AND reason = ?", recipientID, authorID, models.FeedReasonFollowing).
+		Delete(&models.FeedItem{}).Error
+}
+
+// GetFollowing returns the recipient's following items, best ranked first,
+// starting after cursor. Items of authors the recipient no longer follows,
+// left behind if removing them failed, are skipped: GetRare and GetNearby
+// return those authors' catches instead, and each catch must come from one
+// source only.
+func (r *FeedRepository) GetFollowing(recipientID uuid.UUID, cursor *pagination.Cursor, limit int) ([]models.FeedItem, error) {
+	query := r.db.Where("recipient_id = ? AND reason = ?", recipientID, models.FeedReasonFollowing).
+		Where("author_id IN (SELECT followee_id FROM follows WHERE follower_id = ? AND status = ?)", recipientID, models.FollowAccepted)
+	return pageFeedItems(query, cursor, limit)
+}
+
+// GetRare returns the shared rare items, best ranked first, starting after
+// cursor. Catches by readerID and the accounts they follow are left out; those
+// reach the reader as following items. A catch is shared once, as rare or
+// nearby.
+func (r *FeedRepository) GetRare(readerID uuid.UUID, cursor *pagination.Cursor, limit int) ([]models.FeedItem, error) {
+	query := r.db.Where("recipient_id = ? AND reason = ?", uuid.Nil, models.FeedReasonRare).
+		Scopes(notFollowedBy(readerID))
+	return pageFeedItems(query, cursor, limit)
+}
+
+// GetNearby returns the shared nearby items within roughly radiusKm of the
+// given coordinates, best ranked first, starting after cursor. Like GetRare it
+// leaves out catches by readerID and the accounts they follow.
+func (r *FeedRepository) GetNearby(readerID uuid.UUID, lat, lng, radiusKm float64, cursor *pagination.Cursor, limit int) ([]models.FeedItem, error) {
+	latDelta, lngDelta := boundingBox(lat, radiusKm)
+	query := r.db.Where("recipient_id = ? AND reason = ?", uuid.Nil, models.FeedReasonNearby).
+		Where("latitude BETWEEN ? AND ?", lat-latDelta, lat+latDelta).
+		Where("longitude BETWEEN ? AND ?", lng-lngDelta, lng+lngDelta).
+		Scopes(notFollowedBy(readerID))
+	return pageFeedItems(query, cursor, limit)
+}
+
+func notFollowedBy(readerID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if readerID == uuid.Nil {
+			return db
+		}
+		return db.Where("author_id <> ? AND author_id NOT IN (SELECT followee_id FROM follows WHERE follower_id = ? AND status = ?)",
+			readerID, readerID, models.FollowAccepted)
+	}
+}
+
+// pageFeedItems orders feed items by rank, keyed on (ranked_at, catch_id)
+func pageFeedItems(query *gorm.DB, cursor *pagination.Cursor, limit int) ([]models.FeedItem, error) {
+	if cursor != nil {
+		query = query.Where("(ranked_at, catch_id) < (?, ?)", cursor.Time, cursor.ID)
+	}
+	var items []models.FeedItem
+	err := query.Order("ranked_at DESC, catch_id DESC").Limit(limit).Find(&items).Error
+	return items, err
+}
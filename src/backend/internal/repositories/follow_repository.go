@@ -0,0 +1,287 @@
+package repositories
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FollowRepository stores the follow graph. Follows becoming accepted or being
+// removed adjust UserStats.FollowersCount/FollowingCount in the same transaction.
+type FollowRepository struct {
+	db *gorm.DB
+}
+
+func NewFollowRepository() *FollowRepository {
+	return &FollowRepository{
+		db: config.DB,
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *FollowRepository) WithTx(tx *gorm.DB) *FollowRepository {
+	return &FollowRepository{db: tx}
+}
+
+// Get retrieves the follow from followerID to followeeID, in any status
+func (r *FollowRepository) Get(followerID, followeeID uuid.UUID) (*models.Follow, error) {
+	var follow models.Follow
+	err := r.db.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).First(&follow).Error
+	if err != nil {
+		return nil, err
+	}
+	return &follow, nil
+}
+
+// IsFollowing reports whether followerID has an accepted follow of followeeID
+func (r *FollowRepository) IsFollowing(followerID, followeeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Follow{}).
+		Where("follower_id = ? AND followee_id = ? AND status = ?", followerID, followeeID, models.FollowAccepted).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountAccepted counts the user's accepted followers and the users they follow,
+// what UserStats.FollowersCount and FollowingCount track
+func (r *FollowRepository) CountAccepted(userID uuid.UUID) (followers, following int64, err error) {
+	err = r.db.Model(&models.Follow{}).
+		Where("followee_id = ? AND status = ?", userID, models.FollowAccepted).
+		Count(&followers).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	err = r.db.Model(&models.Follow{}).
+		Where("follower_id = ? AND status = ?", userID, models.FollowAccepted).
+		Count(&following).Error
+	return followers, following, err
+}
+
+// Create adds a follow with the given status unless one already exists, and
+// returns the follow now in place. created is false if it already existed, in
+// which case it is left as it was.
+func (r *FollowRepository) Create(followerID, followeeID uuid.UUID, status models.FollowStatus) (follow *models.Follow, created bool, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		follow = &models.Follow{FollowerID: followerID, FolloweeID: followeeID, Status: status}
+		if status == models.FollowAccepted {
+			now := time.Now()
+			follow.AcceptedAt = &now
+		}
+		result := tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "follower_id"}, {Name: "followee_id"}}, DoNothing: true}).
+			Create(follow)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			follow = &models.Follow{}
+			return tx.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).First(follow).Error
+		}
+		created = true
+		if status != models.FollowAccepted {
+			return nil
+		}
+		return adjustFollowersCounters(tx, []uuid.UUID{followerID}, followeeID, 1)
+	})
+	return follow, created, err
+}
+
+// Accept turns a pending follow into an accepted one. It returns false if there
+// was no pending follow.
+func (r *FollowRepository) Accept(followerID, followeeID uuid.UUID) (bool, error) {
+	accepted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Follow{}).
+			Where("follower_id = ? AND followee_id = ? AND status = ?", followerID, followeeID, models.FollowPending).
+			Updates(map[string]interface{}{
+				"status":      models.FollowAccepted,
+				"accepted_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		accepted = true
+		return adjustFollowersCounters(tx, []uuid.UUID{followerID}, followeeID, 1)
+	})
+	return accepted, err
+}
+
+// AcceptAllPending accepts every pending follow of followeeID, for when a
+// private account is made public. It returns the followers accepted.
+func (r *FollowRepository) AcceptAllPending(followeeID uuid.UUID) ([]uuid.UUID, error) {
+	var followerIDs []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Follow{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("followee_id = ? AND status = ?", followeeID, models.FollowPending).
+			Pluck("follower_id", &followerIDs).Error
+		if err != nil || len(followerIDs) == 0 {
+			return err
+		}
+
+		err = tx.Model(&models.Follow{}).
+			Where("followee_id = ? AND follower_id IN ?", followeeID, followerIDs).
+			Updates(map[string]interface{}{
+				"status":      models.FollowAccepted,
+				"accepted_at": time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+		return adjustFollowersCounters(tx, followerIDs, followeeID, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return followerIDs, nil
+}
+
+// Delete removes the follow from followerID to followeeID. If status is not
+// empty only a follow in that status is removed. It returns false if there was
+// nothing to remove.
+func (r *FollowRepository) Delete(followerID, followeeID uuid.UUID, status models.FollowStatus) (bool, error) {
+	deleted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var follow models.Follow
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("follower_id = ? AND followee_id = ?", followerID, followeeID)
+		if status != "" {
+			query = query.Where("status = ?", status)
+		}
+		err := query.First(&follow).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&follow).Error; err != nil {
+			return err
+		}
+		deleted = true
+		if !follow.IsAccepted() {
+			return nil
+		}
+		return adjustFollowersCounters(tx, []uuid.UUID{followerID}, followeeID, -1)
+	})
+	return deleted, err
+}
+
+// GetFollowers lists the accepted followers of userID, most recent first,
+// starting after cursor. next is nil on the last page.
+func (r *FollowRepository) GetFollowers(userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]models.Follow, *pagination.Cursor, error) {
+	query := r.db.Where("follows.followee_id = ? AND follows.status = ?", userID, models.FollowAccepted).
+		Preload("Follower", publicProfile)
+	return listFollows(query, "accepted_at", cursor, limit)
+}
+
+// GetFollowing lists the accounts userID follows, most recent first, starting
+// after cursor. next is nil on the last page.
+func (r *FollowRepository) GetFollowing(userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]models.Follow, *pagination.Cursor, error) {
+	query := r.db.Where("follows.follower_id = ? AND follows.status = ?", userID, models.FollowAccepted).
+		Preload("Followee", publicProfile)
+	return listFollows(query, "accepted_at", cursor, limit)
+}
+
+// GetPendingRequests lists the follow requests awaiting userID's approval,
+// most recent first, starting after cursor. next is nil on the last page.
+func (r *FollowRepository) GetPendingRequests(userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]models.Follow, *pagination.Cursor, error) {
+	query := r.db.Where("follows.followee_id = ? AND follows.status = ?", userID, models.FollowPending).
+		Preload("Follower", publicProfile)
+	return listFollows(query, "created_at", cursor, limit)
+}
+
+// listFollows runs a keyset-paginated follow query ordered by timeColumn,
+// flagging the follows that are returned by the other user
+func listFollows(query *gorm.DB, timeColumn string, cursor *pagination.Cursor, limit int) ([]models.Follow, *pagination.Cursor, error) {
+	query = query.Model(&models.Follow{}).
+		Select(`follows.*, EXISTS (
+			SELECT 1 FROM follows back
+			WHERE back.follower_id = follows.followee_id AND back.followee_id = follows.follower_id AND back.status = ?
+		) AS mutual`, models.FollowAccepted)
+	if cursor != nil {
+		query = query.Where("(follows."+timeColumn+", follows.id) < (?, ?)", cursor.Time, cursor.ID)
+	}
+
+	var follows []models.Follow
+	err := query.Order("follows." + timeColumn + " DESC, follows.id DESC").
+		Limit(limit + 1).
+		Find(&follows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(follows) <= limit {
+		return follows, nil, nil
+	}
+
+	follows = follows[:limit]
+	last := follows[limit-1]
+	next := &pagination.Cursor{Time: last.CreatedAt, ID: last.ID}
+	if timeColumn == "accepted_at" && last.AcceptedAt != nil {
+		next.Time = *last.AcceptedAt
+	}
+	return follows, next, nil
+}
+
+// adjustFollowersCounters moves followeeID's followers count by delta for each
+// of followerIDs, and each follower's following count by delta
+func adjustFollowersCounters(tx *gorm.DB, followerIDs []uuid.UUID, followeeID uuid.UUID, delta int) error {
+	deltas := map[uuid.UUID][2]int{followeeID: {delta * len(followerIDs), 0}}
+	for _, id := range followerIDs {
+		d := deltas[id]
+		deltas[id] = [2]int{d[0], d[1] + delta}
+	}
+	return applyFollowCounterDeltas(tx, deltas)
+}
+
+// applyFollowCounterDeltas adds {followers, following} deltas to each user's
+// stats, creating the stats row if needed. Rows are updated in ID order so that
+// concurrent follows between the same users can't deadlock.
+func applyFollowCounterDeltas(tx *gorm.DB, deltas map[uuid.UUID][2]int) error {
+	userIDs := make([]uuid.UUID, 0, len(deltas))
+	for id := range deltas {
+		userIDs = append(userIDs, id)
+	}
+	slices.SortFunc(userIDs, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+	now := time.Now()
+	for _, userID := range userIDs {
+		d := deltas[userID]
+		if d == [2]int{} {
+			continue
+		}
+		stats := models.UserStats{
+			UserID:         userID,
+			FollowersCount: max(d[0], 0),
+			FollowingCount: max(d[1], 0),
+			LastUpdated:    now,
+		}
+		err := tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "user_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"followers_count": gorm.Expr("GREATEST(user_stats.followers_count + ?, 0)", d[0]),
+					"following_count": gorm.Expr("GREATEST(user_stats.following_count + ?, 0)", d[1]),
+					"last_updated":    now,
+				}),
+			}).
+			Create(&stats).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
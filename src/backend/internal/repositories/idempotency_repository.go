@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db: config.DB,
+	}
+}
+
+// Begin claims the key for a new request. If the key is already in use it returns
+// the existing record with claimed set to false. Records older than ttl, and
+// records still processing after lease, whose request must have died, are
+// discarded first so keys can be reused.
+func (r *IdempotencyRepository) Begin(userID uuid.UUID, key, requestHash string, ttl, lease time.Duration) (record *models.IdempotencyKey, claimed bool, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Where("user_id = ? AND key = ?", userID, key).
+			Where("created_at < ? OR (status = ? AND updated_at < ?)", now.Add(-ttl), models.IdempotencyProcessing, now.Add(-lease)).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+
+		record = &models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash,
+			Status:      models.IdempotencyProcessing,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			claimed = true
+			return nil
+		}
+
+		record = &models.IdempotencyKey{}
+		return tx.Where("user_id = ? AND key = ?", userID, key).First(record).Error
+	})
+	return record, claimed, err
+}
+
+// Complete stores the response so later requests with the same key can replay it
+func (r *IdempotencyRepository) Complete(id uuid.UUID, status int, body []byte) error {
+	return r.db.Model(&models.IdempotencyKey{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          models.IdempotencyCompleted,
+			"response_status": status,
+			"response_body":   body,
+		}).Error
+}
+
+// Release forgets a claimed key so the request can be retried
+func (r *IdempotencyRepository) Release(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired removes records older than ttl
+func (r *IdempotencyRepository) DeleteExpired(ttl time.Duration) (int64, error) {
+	result := r.db.Where("created_at < ?", time.Now().Add(-ttl)).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
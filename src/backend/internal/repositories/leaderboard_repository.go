@@ -0,0 +1,307 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LeaderboardQuery selects a leaderboard
+type LeaderboardQuery struct {
+	Metric  models.LeaderboardMetric
+	Scope   models.LeaderboardScope
+	Country string        // Home country of the users ranked, for the country and city scopes
+	City    string        // Home city of the users ranked, for the city scope
+	Since   time.Time     // Start of the window; zero for all time
+	Grace   time.Duration // Streak grace period after midnight, for windowed streak boards
+}
+
+// UserRanks are a user's places on the all-time points leaderboards and the
+// home region that decides their country and city boards
+type UserRanks struct {
+	UserID      uuid.UUID
+	GlobalRank  *int
+	CountryRank *int
+	CityRank    *int
+	HomeCountry string
+	HomeCity    string
+}
+
+type LeaderboardRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaderboardRepository() *LeaderboardRepository {
+	return &LeaderboardRepository{
+		db: config.DB,
+	}
+}
+
+// allTimeColumns are the user stats columns ranked by the all-time boards
+var allTimeColumns = map[models.LeaderboardMetric]string{
+	models.LeaderboardPoints:  "user_stats.total_points",
+	models.LeaderboardSpecies: "user_stats.unique_species",
+	models.LeaderboardStreak:  "user_stats.longest_streak",
+}
+
+// scoresSQL returns the SQL of the users on the board with their value, which
+// is always positive, and its arguments
+func scoresSQL(q LeaderboardQuery) (string, []interface{}, error) {
+	var scope string
+	var scopeArgs []interface{}
+	switch q.Scope {
+	case models.LeaderboardGlobal:
+	case models.LeaderboardCountry:
+		scope, scopeArgs = " AND user_stats.home_country = ?", []interface{}{q.Country}
+	case models.LeaderboardCity:
+		scope, scopeArgs = " AND user_stats.home_country = ? AND user_stats.home_city = ?", []interface{}{q.Country, q.City}
+	default:
+		return "", nil, fmt.Errorf("unknown leaderboard scope %q", q.Scope)
+	}
+
+	if q.Since.IsZero() {
+		column, ok := allTimeColumns[q.Metric]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown leaderboard metric %q", q.Metric)
+		}
+		return "SELECT user_stats.user_id, " + column + " AS value FROM user_stats WHERE " + column + " > 0" + scope, scopeArgs, nil
+	}
+
+	// The catches in the window of the users in scope
+	catchJoins := `FROM animal_catches
+		JOIN user_stats ON user_stats.user_id = animal_catches.user_id
+		LEFT JOIN locations ON locations.id = animal_catches.location_id`
+	catchFilter := `
+		WHERE animal_catches.verification_status <> ? AND animal_catches.caught_at >= ?` + scope
+	catches := catchJoins + catchFilter
+	args := append([]interface{}{models.VerificationRejected, q.Since}, scopeArgs...)
+
+	switch q.Metric {
+	case models.LeaderboardPoints:
+		return `SELECT animal_catches.user_id, SUM(animal_catches.points_awarded) AS value ` + catches + `
+			GROUP BY animal_catches.user_id HAVING SUM(animal_catches.points_awarded) > 0`, args, nil
+	case models.LeaderboardSpecies:
+		return `SELECT animal_catches.user_id, COUNT(DISTINCT animal_catches.species_id) AS value ` + catches + `
+			GROUP BY animal_catches.user_id`, args, nil
+	case models.LeaderboardStreak:
+		// Streak days as the user's stats count them: catch days, joined across
+		// missed days by the freezes that covered them, which don't add to the
+		// length. Consecutive days less their position among the user's days
+		// are the same within a run.
+		freezes := `FROM streak_freezes
+			JOIN user_stats ON user_stats.user_id = streak_freezes.user_id
+			WHERE streak_freezes.day >= ?::date` + scope
+		streakArgs := append([]interface{}{q.Grace.Seconds()}, args...)
+		streakArgs = append(streakArgs, q.Since.UTC())
+		streakArgs = append(streakArgs, scopeArgs...)
+		return `SELECT user_id, MAX(days) AS value FROM (
+				SELECT user_id, SUM(caught) AS days FROM (
+					SELECT user_id, caught, day - (ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY day))::int AS run FROM (
+						SELECT user_id, day, MAX(caught) AS caught FROM (
+							SELECT animal_catches.user_id, ` + streakDaySQL("users.timezone") + ` AS day, 1 AS caught
+							` + catchJoins + `
+							JOIN users ON users.id = animal_catches.user_id` + catchFilter + `
+							UNION ALL
+							SELECT streak_freezes.user_id, streak_freezes.day, 0 AS caught ` + freezes + `
+						) marked_days
+						GROUP BY user_id, day
+					) streak_days
+				) runs
+				GROUP BY user_id, run
+			) streaks
+			GROUP BY user_id
+			HAVING MAX(days) > 0`, streakArgs, nil
+	}
+	return "", nil, fmt.Errorf("unknown leaderboard metric %q", q.Metric)
+}
+
+// rankedSQL ranks the users on the board. Position orders users sharing a rank.
+func rankedSQL(q LeaderboardQuery) (string, []interface{}, error) {
+	scores, args, err := scoresSQL(q)
+	if err != nil {
+		return "", nil, err
+	}
+	return `WITH scores AS (` + scores + `),
+		ranked AS (
+			SELECT user_id, value,
+				RANK() OVER (ORDER BY value DESC) AS rank,
+				ROW_NUMBER() OVER (ORDER BY value DESC, user_id) AS position
+			FROM scores
+		)`, args, nil
+}
+
+type leaderboardRow struct {
+	Rank     int
+	Value    int
+	UserID   uuid.UUID
+	Username string
+	Name     string
+	Avatar   string
+}
+
+const leaderboardColumns = `SELECT ranked.rank, ranked.value, users.id AS user_id, users.username, users.name, users.avatar
+	FROM ranked JOIN users ON users.id = ranked.user_id`
+
+// GetPage retrieves a page of the leaderboard, best first, and how many users
+// are on it
+func (r *LeaderboardRepository) GetPage(q LeaderboardQuery, offset, limit int) ([]models.LeaderboardEntry, int64, error) {
+	ranked, args, err := rankedSQL(q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := r.db.Raw(ranked+` SELECT COUNT(*) FROM ranked`, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []leaderboardRow
+	err = r.db.Raw(ranked+leaderboardColumns+` ORDER BY ranked.position LIMIT ? OFFSET ?`, append(args, limit, offset)...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return leaderboardEntries(rows), total, nil
+}
+
+// GetAround retrieves the user's entry on the leaderboard with up to radius
+// users either side of it, best first. It is empty if the user isn't on the board.
+func (r *LeaderboardRepository) GetAround(q LeaderboardQuery, userID uuid.UUID, radius int) ([]models.LeaderboardEntry, error) {
+	ranked, args, err := rankedSQL(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []leaderboardRow
+	err = r.db.Raw(ranked+`, me AS (SELECT position FROM ranked WHERE user_id = ?) `+leaderboardColumns+`
+		JOIN me ON ranked.position BETWEEN me.position - ? AND me.position + ?
+		ORDER BY ranked.position`, append(args, userID, radius, radius)...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return leaderboardEntries(rows), nil
+}
+
+func leaderboardEntries(rows []leaderboardRow) []models.LeaderboardEntry {
+	entries := make([]models.LeaderboardEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, models.LeaderboardEntry{
+			Rank:  row.Rank,
+			Value: row.Value,
+			User: &models.User{
+				ID:       row.UserID,
+				Username: row.Username,
+				Name:     row.Name,
+				Avatar:   row.Avatar,
+			},
+		})
+	}
+	return entries
+}
+
+// rankUsersSQL works out every user's home region, the country and then the
+// city in it where most of their catches were made, and their ranks by all-time
+// points. Only the users whose ranks or region changed are returned, by user ID.
+const rankUsersSQL = `
+WITH counted AS (
+	SELECT animal_catches.user_id, animal_catches.caught_at, locations.country, locations.city
+	FROM animal_catches
+	JOIN locations ON locations.id = animal_catches.location_id
+	WHERE animal_catches.verification_status <> ? AND locations.country <> ''
+),
+home_countries AS (
+	SELECT DISTINCT ON (user_id) user_id, country
+	FROM counted
+	GROUP BY user_id, country
+	ORDER BY user_id, COUNT(*) DESC, MAX(caught_at) DESC
+),
+home_cities AS (
+	SELECT DISTINCT ON (counted.user_id) counted.user_id, counted.city
+	FROM counted
+	JOIN home_countries ON home_countries.user_id = counted.user_id AND home_countries.country = counted.country
+	WHERE counted.city <> ''
+	GROUP BY counted.user_id, counted.city
+	ORDER BY counted.user_id, COUNT(*) DESC, MAX(counted.caught_at) DESC
+),
+ranks AS (
+	SELECT user_stats.user_id, user_stats.global_rank AS old_global_rank, user_stats.country_rank AS old_country_rank,
+		user_stats.city_rank AS old_city_rank, user_stats.home_country AS old_home_country, user_stats.home_city AS old_home_city,
+		COALESCE(home_countries.country, '') AS home_country,
+		COALESCE(home_cities.city, '') AS home_city,
+		CASE WHEN user_stats.total_points > 0
+			THEN RANK() OVER (ORDER BY user_stats.total_points DESC) END AS global_rank,
+		CASE WHEN user_stats.total_points > 0 AND home_countries.country IS NOT NULL
+			THEN RANK() OVER (PARTITION BY home_countries.country ORDER BY user_stats.total_points DESC) END AS country_rank,
+		CASE WHEN user_stats.total_points > 0 AND home_cities.city IS NOT NULL
+			THEN RANK() OVER (PARTITION BY home_countries.country, home_cities.city ORDER BY user_stats.total_points DESC) END AS city_rank
+	FROM user_stats
+	LEFT JOIN home_countries ON home_countries.user_id = user_stats.user_id
+	LEFT JOIN home_cities ON home_cities.user_id = user_stats.user_id
+)
+SELECT user_id, global_rank, country_rank, city_rank, home_country, home_city
+FROM ranks
+WHERE global_rank IS DISTINCT FROM old_global_rank
+	OR country_rank IS DISTINCT FROM old_country_rank
+	OR city_rank IS DISTINCT FROM old_city_rank
+	OR home_country IS DISTINCT FROM old_home_country
+	OR home_city IS DISTINCT FROM old_home_city
+ORDER BY user_id`
+
+// EachChangedRanks works out every user's ranks and home region from one
+// snapshot and passes the ones that changed to fn in batches, by user ID
+func (r *LeaderboardRepository) EachChangedRanks(batchSize int, fn func([]UserRanks) error) error {
+	rows, err := r.db.Raw(rankUsersSQL, models.VerificationRejected).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := make([]UserRanks, 0, batchSize)
+	for rows.Next() {
+		var ranks UserRanks
+		if err := rows.Scan(&ranks.UserID, &ranks.GlobalRank, &ranks.CountryRank, &ranks.CityRank, &ranks.HomeCountry, &ranks.HomeCity); err != nil {
+			return err
+		}
+		batch = append(batch, ranks)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// SaveRanks writes users' ranks and home regions in one transaction. The
+// batch must be ordered by user ID, the order stats rows are locked in.
+func (r *LeaderboardRepository) SaveRanks(batch []UserRanks) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, ranks := range batch {
+			err := tx.Model(&models.UserStats{}).
+				Where("user_id = ?", ranks.UserID).
+				UpdateColumns(map[string]interface{}{
+					"global_rank":  ranks.GlobalRank,
+					"country_rank": ranks.CountryRank,
+					"city_rank":    ranks.CityRank,
+					"home_country": ranks.HomeCountry,
+					"home_city":    ranks.HomeCity,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
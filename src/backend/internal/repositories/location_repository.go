@@ -1,10 +1,13 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/anidex/backend/internal/config"
 	"github.com/anidex/backend/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type LocationRepository struct {
@@ -17,6 +20,11 @@ func NewLocationRepository() *LocationRepository {
 	}
 }
 
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *LocationRepository) WithTx(tx *gorm.DB) *LocationRepository {
+	return &LocationRepository{db: tx}
+}
+
 // Create creates a new location record
 func (r *LocationRepository) Create(location *models.Location) error {
 	return r.db.Create(location).Error
@@ -32,6 +40,16 @@ func (r *LocationRepository) GetByID(id uuid.UUID) (*models.Location, error) {
 	return &location, nil
 }
 
+// GetForUpdate retrieves a location and locks it until the transaction ends
+func (r *LocationRepository) GetForUpdate(id uuid.UUID) (*models.Location, error) {
+	var location models.Location
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&location).Error
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
 // FindNearby finds a location within the specified radius (in km) of given coordinates
 func (r *LocationRepository) FindNearby(lat, lng, radiusKm float64) (*models.Location, error) {
 	var locations []models.Location
@@ -66,12 +84,13 @@ func (r *LocationRepository) FindNearby(lat, lng, radiusKm float64) (*models.Loc
 	return closestLocation, nil
 }
 
-// GetNearbyWithCatches retrieves locations within radius that have animal catches
+// GetNearbyWithCatches retrieves locations within radius that have public animal
+// catches. Locations only used by private or location-hidden catches are left out.
 func (r *LocationRepository) GetNearbyWithCatches(lat, lng, radiusKm float64) ([]models.Location, error) {
 	var locations []models.Location
 	
-	// Get all locations
-	err := r.db.Find(&locations).Error
+	// Get all locations with public catches
+	err := r.db.Where("catch_count > ?", 0).Find(&locations).Error
 	if err != nil {
 		return nil, err
 	}
@@ -110,16 +129,17 @@ func (r *LocationRepository) GetHotspots(limit int) ([]models.Location, error) {
 }
 
 // UpdateStats updates location statistics (catch count, species count, etc.)
+// from its public catches. Rejected catches don't count.
 func (r *LocationRepository) UpdateStats(locationID uuid.UUID) error {
 	// Update catch count
 	err := r.db.Exec(`
 		UPDATE locations 
 		SET catch_count = (
 			SELECT COUNT(*) FROM animal_catches 
-			WHERE location_id = ? AND is_public = true
+			WHERE location_id = ? AND is_public = true AND verification_status <> ?
 		)
 		WHERE id = ?
-	`, locationID, locationID).Error
+	`, locationID, models.VerificationRejected, locationID).Error
 	
 	if err != nil {
 		return err
@@ -130,10 +150,10 @@ func (r *LocationRepository) UpdateStats(locationID uuid.UUID) error {
 		UPDATE locations 
 		SET species_count = (
 			SELECT COUNT(DISTINCT species_id) FROM animal_catches 
-			WHERE location_id = ? AND is_public = true
+			WHERE location_id = ? AND is_public = true AND verification_status <> ?
 		)
 		WHERE id = ?
-	`, locationID, locationID).Error
+	`, locationID, models.VerificationRejected, locationID).Error
 	
 	if err != nil {
 		return err
@@ -144,10 +164,10 @@ func (r *LocationRepository) UpdateStats(locationID uuid.UUID) error {
 		UPDATE locations 
 		SET user_count = (
 			SELECT COUNT(DISTINCT user_id) FROM animal_catches 
-			WHERE location_id = ? AND is_public = true
+			WHERE location_id = ? AND is_public = true AND verification_status <> ?
 		)
 		WHERE id = ?
-	`, locationID, locationID).Error
+	`, locationID, models.VerificationRejected, locationID).Error
 	
 	if err != nil {
 		return err
@@ -158,14 +178,66 @@ func (r *LocationRepository) UpdateStats(locationID uuid.UUID) error {
 		UPDATE locations 
 		SET last_catch_at = (
 			SELECT MAX(caught_at) FROM animal_catches 
-			WHERE location_id = ? AND is_public = true
+			WHERE location_id = ? AND is_public = true AND verification_status <> ?
 		)
 		WHERE id = ?
-	`, locationID, locationID).Error
+	`, locationID, models.VerificationRejected, locationID).Error
 	
 	return err
 }
 
+// RecordCatch increments the location statistics for one new public catch,
+// without recounting every catch like UpdateStats does
+func (r *LocationRepository) RecordCatch(locationID uuid.UUID, newSpecies, newUser bool, caughtAt time.Time) error {
+	updates := map[string]interface{}{
+		"catch_count":   gorm.Expr("catch_count + 1"),
+		"last_catch_at": gorm.Expr("GREATEST(last_catch_at, ?)", caughtAt),
+	}
+	if newSpecies {
+		updates["species_count"] = gorm.Expr("species_count + 1")
+	}
+	if newUser {
+		updates["user_count"] = gorm.Expr("user_count + 1")
+	}
+	return r.db.Model(&models.Location{}).Where("id = ?", locationID).Updates(updates).Error
+}
+
+// RecordUserPosition adds a position to the user's location history
+func (r *LocationRepository) RecordUserPosition(userID uuid.UUID, lat, lng float64) error {
+	return r.db.Omit(clause.Associations).Create(&models.UserLocationHistory{
+		UserID:    userID,
+		Latitude:  lat,
+		Longitude: lng,
+	}).Error
+}
+
+// GetLastKnownPosition returns the most recent of the user's recorded positions
+// and the places of their catches. ok is false if there is neither.
+func (r *LocationRepository) GetLastKnownPosition(userID uuid.UUID) (lat, lng float64, ok bool, err error) {
+	var positions []struct {
+		Latitude  float64
+		Longitude float64
+	}
+	err = r.db.Raw(`
+		SELECT latitude, longitude FROM (
+			SELECT latitude, longitude, recorded_at AS seen_at
+			FROM user_location_histories
+			WHERE user_id = ?
+			UNION ALL
+			SELECT locations.latitude, locations.longitude, animal_catches.caught_at AS seen_at
+			FROM animal_catches
+			JOIN locations ON locations.id = animal_catches.location_id
+			WHERE animal_catches.user_id = ?
+		) positions
+		ORDER BY seen_at DESC
+		LIMIT 1
+	`, userID, userID).Scan(&positions).Error
+	if err != nil || len(positions) == 0 {
+		return 0, 0, false, err
+	}
+	return positions[0].Latitude, positions[0].Longitude, true, nil
+}
+
 // GetLocationsByCountry retrieves locations by country with pagination
 func (r *LocationRepository) GetLocationsByCountry(country string, limit, offset int) ([]models.Location, int64, error) {
 	var locations []models.Location
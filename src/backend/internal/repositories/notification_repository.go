@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRepository stores users' inboxes, their notification preferences
+// and the devices to push to
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{
+		db: config.DB,
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *NotificationRepository) WithTx(tx *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: tx}
+}
+
+func (r *NotificationRepository) Create(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+// List returns the user's notifications, newest first, starting after cursor.
+// next is nil on the last page.
+func (r *NotificationRepository) List(userID uuid.UUID, unreadOnly bool, cursor *pagination.Cursor, limit int) ([]models.Notification, *pagination.Cursor, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if cursor != nil {
+		query = query.Where("(created_at, id) < (?, ?)", cursor.Time, cursor.ID)
+	}
+
+	var notifications []models.Notification
+	err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&notifications).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(notifications) <= limit {
+		return notifications, nil, nil
+	}
+
+	notifications = notifications[:limit]
+	last := notifications[limit-1]
+	return notifications, &pagination.Cursor{Time: last.CreatedAt, ID: last.ID}, nil
+}
+
+func (r *NotificationRepository) CountUnread(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications as read. It returns false if
+// the user has no such notification; marking it again is not an error.
+func (r *NotificationRepository) MarkRead(userID, notificationID uuid.UUID) (bool, error) {
+	result := r.db.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkAllRead marks every unread notification of the user as read and returns
+// how many there were
+func (r *NotificationRepository) MarkAllRead(userID uuid.UUID) (int64, error) {
+	result := r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// GetPreferences returns the preferences the user has set; types without one
+// are left out
+func (r *NotificationRepository) GetPreferences(userID uuid.UUID) ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	err := r.db.Where("user_id = ?", userID).Find(&preferences).Error
+	return preferences, err
+}
+
+// GetPreference returns the user's preference for a type, or nil if they haven't set one
+func (r *NotificationRepository) GetPreference(userID uuid.UUID, notificationType models.NotificationType) (*models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	err := r.db.Where("user_id = ? AND type = ?", userID, notificationType).Limit(1).Find(&preferences).Error
+	if err != nil || len(preferences) == 0 {
+		return nil, err
+	}
+	return &preferences[0], nil
+}
+
+// SavePreferences inserts or replaces the given preferences
+func (r *NotificationRepository) SavePreferences(preferences []models.NotificationPreference) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"in_app", "push", "updated_at"}),
+	}).Create(&preferences).Error
+}
+
+// SaveDeviceToken registers a push token for the user. A token already
+// registered, possibly by another account signed in on the same device, is
+// moved to this user.
+func (r *NotificationRepository) SaveDeviceToken(device *models.DeviceToken) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "updated_at"}),
+	}).Create(device).Error
+}
+
+// DeleteDeviceToken removes one of the user's push tokens
+func (r *NotificationRepository) DeleteDeviceToken(userID uuid.UUID, token string) error {
+	return r.db.Where("user_id = ? AND token = ?", userID, token).Delete(&models.DeviceToken{}).Error
+}
+
+// DeleteDeviceTokens removes tokens the push provider reported as no longer valid
+func (r *NotificationRepository) DeleteDeviceTokens(tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return r.db.Where("token IN ?", tokens).Delete(&models.DeviceToken{}).Error
+}
+
+func (r *NotificationRepository) GetDeviceTokens(userID uuid.UUID) ([]models.DeviceToken, error) {
+	var devices []models.DeviceToken
+	err := r.db.Where("user_id = ?", userID).Find(&devices).Error
+	return devices, err
+}
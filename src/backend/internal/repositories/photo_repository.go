@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PhotoRepository struct {
+	db *gorm.DB
+}
+
+func NewPhotoRepository() *PhotoRepository {
+	return &PhotoRepository{
+		db: config.DB,
+	}
+}
+
+// Create creates a new photo record
+func (r *PhotoRepository) Create(photo *models.Photo) error {
+	return r.db.Create(photo).Error
+}
+
+// GetByID retrieves a photo by its ID
+func (r *PhotoRepository) GetByID(id uuid.UUID) (*models.Photo, error) {
+	var photo models.Photo
+	err := r.db.Where("id = ?", id).First(&photo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// GetByStorageKey retrieves a photo by the key of its storage object
+func (r *PhotoRepository) GetByStorageKey(key string) (*models.Photo, error) {
+	var photo models.Photo
+	err := r.db.Where("storage_key = ?", key).First(&photo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// GetByURL retrieves a photo by its public URL
+func (r *PhotoRepository) GetByURL(url string) (*models.Photo, error) {
+	var photo models.Photo
+	err := r.db.Where("url = ?", url).First(&photo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// Update saves all fields of a photo
+func (r *PhotoRepository) Update(photo *models.Photo) error {
+	return r.db.Omit(clause.Associations).Save(photo).Error
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"github.com/anidex/backend/internal/config"
+	"gorm.io/gorm"
+)
+
+// Transaction runs fn in a database transaction, committing if it returns nil.
+// Repositories obtained with WithTx(tx) inside fn take part in the transaction.
+func Transaction(fn func(tx *gorm.DB) error) error {
+	return config.DB.Transaction(fn)
+}
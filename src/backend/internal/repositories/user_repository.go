@@ -15,6 +15,7 @@ type UserRepository interface {
 	FindByProviderID(provider models.AuthProvider, providerID string) (*models.User, error)
 	Update(user *models.User) error
 	UpdateRefreshToken(userID uuid.UUID, refreshToken string) error
+	GetIDsAfter(afterID uuid.UUID, limit int) ([]uuid.UUID, error)
 }
 
 type userRepository struct {
@@ -73,4 +74,16 @@ func (r *userRepository) Update(user *models.User) error {
 
 func (r *userRepository) UpdateRefreshToken(userID uuid.UUID, refreshToken string) error {
 	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("refresh_token", refreshToken).Error
-}
\ No newline at end of file
+}
+
+// GetIDsAfter returns up to limit user IDs greater than afterID, in ID order.
+// Used to walk every user in batches.
+func (r *userRepository) GetIDsAfter(afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&models.User{}).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
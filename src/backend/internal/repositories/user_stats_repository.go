@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserStatsRepository struct {
+	db *gorm.DB
+}
+
+func NewUserStatsRepository() *UserStatsRepository {
+	return &UserStatsRepository{
+		db: config.DB,
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *UserStatsRepository) WithTx(tx *gorm.DB) *UserStatsRepository {
+	return &UserStatsRepository{db: tx}
+}
+
+// GetByUserID retrieves the stats of a user
+func (r *UserStatsRepository) GetByUserID(userID uuid.UUID) (*models.UserStats, error) {
+	var stats models.UserStats
+	err := r.db.Where("user_id = ?", userID).First(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetForUpdate retrieves the stats of a user, creating them if needed, and locks
+// the row until the transaction ends. Holding this lock serializes everything
+// that changes a user's stats, so it must be taken before any other row lock
+// but those of existing catches: likes, comments, deletions and reviews write
+// the catch first.
+func (r *UserStatsRepository) GetForUpdate(userID uuid.UUID) (*models.UserStats, error) {
+	initial := models.UserStats{UserID: userID, LastUpdated: time.Now()}
+	err := r.db.Omit(clause.Associations).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).
+		Create(&initial).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var stats models.UserStats
+	err = r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		First(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Update saves the stats
+func (r *UserStatsRepository) Update(stats *models.UserStats) error {
+	stats.LastUpdated = time.Now()
+	return r.db.Omit(clause.Associations).Save(stats).Error
+}
+
+// AddReceived adds to the likes and comments the user has received. Neither
+// goes below zero.
+func (r *UserStatsRepository) AddReceived(userID uuid.UUID, likes, comments int) error {
+	if likes == 0 && comments == 0 {
+		return nil
+	}
+	return r.db.Model(&models.UserStats{}).
+		Where("user_id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"total_likes":    gorm.Expr("GREATEST(total_likes + ?, 0)", likes),
+			"total_comments": gorm.Expr("GREATEST(total_comments + ?, 0)", comments),
+			"last_updated":   time.Now(),
+		}).Error
+}
+
+// GetUserIDsWithStreakBefore returns up to limit users, after afterID in ID
+// order, whose streak is running and last covered a day before day
+func (r *UserStatsRepository) GetUserIDsWithStreakBefore(day time.Time, afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.db.Model(&models.UserStats{}).
+		Where("current_streak > 0 AND last_streak_date < ? AND user_id > ?", day, afterID).
+		Order("user_id ASC").
+		Limit(limit).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// AddStreakFreezes records the missed days a user's streak freezes covered
+func (r *UserStatsRepository) AddStreakFreezes(userID uuid.UUID, days []time.Time) error {
+	if len(days) == 0 {
+		return nil
+	}
+	freezes := make([]models.StreakFreeze, len(days))
+	for i, day := range days {
+		freezes[i] = models.StreakFreeze{UserID: userID, Day: day}
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&freezes).Error
+}
+
+// GetStreakFreezeDays returns the missed days the user's streak freezes
+// covered, oldest first
+func (r *UserStatsRepository) GetStreakFreezeDays(userID uuid.UUID) ([]time.Time, error) {
+	var days []time.Time
+	err := r.db.Model(&models.StreakFreeze{}).
+		Where("user_id = ?", userID).
+		Order("day ASC").
+		Pluck("day", &days).Error
+	return days, err
+}
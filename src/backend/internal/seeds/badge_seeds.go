@@ -16,7 +16,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityBronze,
 			IconURL:          "https://example.com/badges/first_catch.png",
 			Color:            "#CD7F32",
-			RequiredCount:    intPtr(1),
+			Rule:             rule(require(models.MeasureCatches, 1, nil)),
 			PointsAwarded:    50,
 			IsSecret:         false,
 			IsActive:         true,
@@ -29,8 +29,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/big_cat_hunter.png",
 			Color:            "#C0C0C0",
-			RequiredCount:    intPtr(5),
-			RequiredSpecies:  []string{},
+			Rule:             rule(require(models.MeasureSpecies, 5, fieldIs(models.FieldSpeciesFamily, "Felidae"))),
 			PointsAwarded:    200,
 			IsSecret:         false,
 			IsActive:         true,
@@ -43,8 +42,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/bird_watcher.png",
 			Color:            "#FFD700",
-			RequiredCount:    intPtr(25),
-			RequiredSpecies:  []string{},
+			Rule:             rule(require(models.MeasureSpecies, 25, fieldIs(models.FieldSpeciesCategory, string(models.CategoryBird)))),
 			PointsAwarded:    500,
 			IsSecret:         false,
 			IsActive:         true,
@@ -57,8 +55,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/marine_biologist.png",
 			Color:            "#FFD700",
-			RequiredCount:    intPtr(15),
-			RequiredSpecies:  []string{},
+			Rule:             rule(require(models.MeasureSpecies, 15, fieldIs(models.FieldSpeciesCategory, string(models.CategoryFish)))),
 			PointsAwarded:    400,
 			IsSecret:         false,
 			IsActive:         true,
@@ -71,7 +68,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityPlatinum,
 			IconURL:          "https://example.com/badges/century_club.png",
 			Color:            "#E5E4E2",
-			RequiredCount:    intPtr(100),
+			Rule:             rule(require(models.MeasureCatches, 100, nil)),
 			PointsAwarded:    1000,
 			IsSecret:         false,
 			IsActive:         true,
@@ -86,8 +83,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityDiamond,
 			IconURL:          "https://example.com/badges/legendary_hunter.png",
 			Color:            "#B9F2FF",
-			RequiredRarity:   rarityPtr(models.RarityLegendary),
-			RequiredCount:    intPtr(1),
+			Rule:             rule(require(models.MeasureCatches, 1, fieldIs(models.FieldSpeciesRarity, string(models.RarityLegendary)))),
 			PointsAwarded:    2000,
 			IsSecret:         true,
 			IsActive:         true,
@@ -100,8 +96,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/epic_explorer.png",
 			Color:            "#FFD700",
-			RequiredRarity:   rarityPtr(models.RarityEpic),
-			RequiredCount:    intPtr(5),
+			Rule:             rule(require(models.MeasureCatches, 5, fieldIs(models.FieldSpeciesRarity, string(models.RarityEpic)))),
 			PointsAwarded:    750,
 			IsSecret:         false,
 			IsActive:         true,
@@ -114,8 +109,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/rare_collector.png",
 			Color:            "#C0C0C0",
-			RequiredRarity:   rarityPtr(models.RarityRare),
-			RequiredCount:    intPtr(10),
+			Rule:             rule(require(models.MeasureCatches, 10, fieldIs(models.FieldSpeciesRarity, string(models.RarityRare)))),
 			PointsAwarded:    300,
 			IsSecret:         false,
 			IsActive:         true,
@@ -130,7 +124,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/globe_trotter.png",
 			Color:            "#FFD700",
-			RequiredCount:    intPtr(10),
+			Rule:             rule(require(models.MeasureCountries, 10, nil)),
 			PointsAwarded:    600,
 			IsSecret:         false,
 			IsActive:         true,
@@ -143,8 +137,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/urban_wildlife.png",
 			Color:            "#C0C0C0",
-			RequiredLocationType: locationTypePtr(models.LocationUrban),
-			RequiredCount:    intPtr(20),
+			Rule:             rule(require(models.MeasureCatches, 20, fieldIs(models.FieldLocationType, string(models.LocationUrban)))),
 			PointsAwarded:    250,
 			IsSecret:         false,
 			IsActive:         true,
@@ -157,8 +150,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/safari_master.png",
 			Color:            "#FFD700",
-			RequiredLocationType: locationTypePtr(models.LocationWilderness),
-			RequiredCount:    intPtr(15),
+			Rule:             rule(require(models.MeasureCatches, 15, fieldIs(models.FieldLocationType, string(models.LocationWilderness)))),
 			PointsAwarded:    500,
 			IsSecret:         false,
 			IsActive:         true,
@@ -171,8 +163,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/mountain_climber.png",
 			Color:            "#C0C0C0",
-			RequiredLocationType: locationTypePtr(models.LocationMountain),
-			RequiredCount:    intPtr(5),
+			Rule:             rule(require(models.MeasureCatches, 5, fieldIs(models.FieldLocationType, string(models.LocationMountain)))),
 			PointsAwarded:    300,
 			IsSecret:         false,
 			IsActive:         true,
@@ -187,7 +178,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityBronze,
 			IconURL:          "https://example.com/badges/daily_dedication.png",
 			Color:            "#CD7F32",
-			RequiredDays:     intPtr(7),
+			Rule:             rule(require(models.MeasureLongestStreak, 7, nil)),
 			PointsAwarded:    100,
 			IsSecret:         false,
 			IsActive:         true,
@@ -200,7 +191,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/monthly_master.png",
 			Color:            "#FFD700",
-			RequiredDays:     intPtr(30),
+			Rule:             rule(require(models.MeasureLongestStreak, 30, nil)),
 			PointsAwarded:    500,
 			IsSecret:         false,
 			IsActive:         true,
@@ -213,7 +204,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityDiamond,
 			IconURL:          "https://example.com/badges/eternal_explorer.png",
 			Color:            "#B9F2FF",
-			RequiredDays:     intPtr(100),
+			Rule:             rule(require(models.MeasureLongestStreak, 100, nil)),
 			PointsAwarded:    2000,
 			IsSecret:         true,
 			IsActive:         true,
@@ -228,7 +219,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/night_owl.png",
 			Color:            "#C0C0C0",
-			RequiredCount:    intPtr(10),
+			Rule:             rule(require(models.MeasureCatches, 10, fieldIs(models.FieldCatchTimeOfDay, string(models.TimeNight)))),
 			PointsAwarded:    250,
 			IsSecret:         false,
 			IsActive:         true,
@@ -241,7 +232,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/early_bird.png",
 			Color:            "#C0C0C0",
-			RequiredCount:    intPtr(15),
+			Rule:             rule(require(models.MeasureCatches, 15, fieldIs(models.FieldCatchTimeOfDay, string(models.TimeDawn)))),
 			PointsAwarded:    250,
 			IsSecret:         false,
 			IsActive:         true,
@@ -256,7 +247,9 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityPlatinum,
 			IconURL:          "https://example.com/badges/conservation_hero.png",
 			Color:            "#E5E4E2",
-			RequiredCount:    intPtr(10),
+			Rule:             rule(require(models.MeasureSpecies, 10, fieldIn(models.FieldSpeciesConservation,
+				string(models.StatusVulnerable), string(models.StatusEndangered),
+				string(models.StatusCriticallyEndangered), string(models.StatusExtinctInWild)))),
 			PointsAwarded:    800,
 			IsSecret:         false,
 			IsActive:         true,
@@ -269,7 +262,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/habitat_guardian.png",
 			Color:            "#FFD700",
-			RequiredCount:    intPtr(5),
+			Rule:             rule(require(models.MeasureLocationTypes, 5, fieldIsNot(models.FieldLocationType, string(models.LocationOther)))),
 			PointsAwarded:    400,
 			IsSecret:         false,
 			IsActive:         true,
@@ -284,7 +277,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/community_leader.png",
 			Color:            "#C0C0C0",
-			RequiredCount:    intPtr(100),
+			Rule:             rule(require(models.MeasureLikesReceived, 100, nil)),
 			PointsAwarded:    200,
 			IsSecret:         false,
 			IsActive:         true,
@@ -297,7 +290,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/photo_artist.png",
 			Color:            "#C0C0C0",
-			RequiredCount:    intPtr(50),
+			Rule:             rule(require(models.MeasureCatches, 50, fieldIs(models.FieldCatchVisibility, string(models.VisibilityPublic)))),
 			PointsAwarded:    250,
 			IsSecret:         false,
 			IsActive:         true,
@@ -310,7 +303,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/mentor.png",
 			Color:            "#FFD700",
-			RequiredCount:    intPtr(25),
+			Rule:             rule(require(models.MeasureCatchesReviewed, 25, nil)),
 			PointsAwarded:    500,
 			IsSecret:         false,
 			IsActive:         true,
@@ -325,7 +318,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRaritySilver,
 			IconURL:          "https://example.com/badges/weather_warrior.png",
 			Color:            "#C0C0C0",
-			RequiredCount:    intPtr(5),
+			Rule:             rule(require(models.MeasureWeatherTypes, 5, fieldIsNot(models.FieldCatchWeather, string(models.WeatherUnknown)))),
 			PointsAwarded:    200,
 			IsSecret:         false,
 			IsActive:         true,
@@ -338,7 +331,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityGold,
 			IconURL:          "https://example.com/badges/seasonal_specialist.png",
 			Color:            "#FFD700",
-			RequiredCount:    intPtr(4),
+			Rule:             rule(require(models.MeasureSeasons, 4, nil)),
 			PointsAwarded:    300,
 			IsSecret:         false,
 			IsActive:         true,
@@ -351,7 +344,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityDiamond,
 			IconURL:          "https://example.com/badges/completionist.png",
 			Color:            "#B9F2FF",
-			RequiredCount:    intPtr(500), // Will be updated based on total species
+			Rule:             rule(models.RuleRequirement{Count: models.MeasureSpecies, TargetAll: true}),
 			PointsAwarded:    5000,
 			IsSecret:         true,
 			IsActive:         true,
@@ -366,7 +359,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityDiamond,
 			IconURL:          "https://example.com/badges/lucky_shot.png",
 			Color:            "#B9F2FF",
-			RequiredCount:    intPtr(1),
+			Rule:             rule(require(models.MeasureFirstCatch, 1, fieldIs(models.FieldSpeciesRarity, string(models.RarityLegendary)))),
 			PointsAwarded:    1000,
 			IsSecret:         true,
 			IsActive:         true,
@@ -379,7 +372,7 @@ func GetBadgeSeeds() []models.Badge {
 			BadgeRarity:      models.BadgeRarityPlatinum,
 			IconURL:          "https://example.com/badges/midnight_photographer.png",
 			Color:            "#E5E4E2",
-			RequiredCount:    intPtr(1),
+			Rule:             rule(require(models.MeasureCatches, 1, &models.RuleCondition{Field: models.FieldCatchMinutesFromMidnight, Op: models.OpLte, Value: 5.0})),
 			PointsAwarded:    500,
 			IsSecret:         true,
 			IsActive:         true,
@@ -387,11 +380,28 @@ func GetBadgeSeeds() []models.Badge {
 	}
 }
 
-// Helper functions for pointer values
-func rarityPtr(r models.Rarity) *models.Rarity {
-	return &r
+// rule requires all of the given requirements
+func rule(requirements ...models.RuleRequirement) *models.BadgeRule {
+	return &models.BadgeRule{Requirements: requirements}
 }
 
-func locationTypePtr(lt models.LocationType) *models.LocationType {
-	return &lt
+// require counts measure, over the catches matching where if set, up to target
+func require(measure models.RuleMeasure, target int, where *models.RuleCondition) models.RuleRequirement {
+	return models.RuleRequirement{Count: measure, Where: where, Target: target}
+}
+
+func fieldIs(field models.RuleField, value string) *models.RuleCondition {
+	return &models.RuleCondition{Field: field, Op: models.OpEq, Value: value}
+}
+
+func fieldIsNot(field models.RuleField, value string) *models.RuleCondition {
+	return &models.RuleCondition{Field: field, Op: models.OpNe, Value: value}
+}
+
+func fieldIn(field models.RuleField, values ...string) *models.RuleCondition {
+	list := make([]interface{}, len(values))
+	for i, value := range values {
+		list[i] = value
+	}
+	return &models.RuleCondition{Field: field, Op: models.OpIn, Value: list}
 }
@@ -12,11 +12,14 @@ import (
 	"gorm.io/gorm"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 type AuthService interface {
 	Register(req *models.RegisterRequest) (*models.AuthResponse, error)
 	Login(req *models.LoginRequest) (*models.AuthResponse, error)
 	RefreshToken(refreshToken string) (*models.AuthResponse, error)
 	GetUserByID(userID uuid.UUID) (*models.User, error)
+	UpdateProfile(userID uuid.UUID, req *models.UpdateProfileRequest) (*models.User, error)
 	HandleOAuthLogin(provider models.AuthProvider, providerID, email, name, avatar string) (*models.AuthResponse, error)
 	HandleFirebaseLogin(idToken string, name *string) (*models.AuthResponse, error)
 }
@@ -24,12 +27,14 @@ type AuthService interface {
 type authService struct {
 	userRepo        repositories.UserRepository
 	firebaseService FirebaseService
+	followService   FollowService
 }
 
-func NewAuthService(userRepo repositories.UserRepository, firebaseService FirebaseService) AuthService {
+func NewAuthService(userRepo repositories.UserRepository, firebaseService FirebaseService, followService FollowService) AuthService {
 	return &authService{
 		userRepo:        userRepo,
 		firebaseService: firebaseService,
+		followService:   followService,
 	}
 }
 
@@ -106,6 +111,43 @@ func (s *authService) GetUserByID(userID uuid.UUID) (*models.User, error) {
 	return s.userRepo.FindByID(userID)
 }
 
+func (s *authService) UpdateProfile(userID uuid.UUID, req *models.UpdateProfileRequest) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.Avatar != nil {
+		user.Avatar = *req.Avatar
+	}
+	if req.DefaultCatchVisibility != nil {
+		user.DefaultCatchVisibility = models.CatchVisibility(*req.DefaultCatchVisibility)
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+	madePublic := false
+	if req.IsPrivate != nil {
+		madePublic = user.IsPrivate && !*req.IsPrivate
+		user.IsPrivate = *req.IsPrivate
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	// Requests no longer need approval once the account is public
+	if madePublic {
+		if err := s.followService.AcceptAllPending(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
 func (s *authService) HandleOAuthLogin(provider models.AuthProvider, providerID, email, name, avatar string) (*models.AuthResponse, error) {
 	user, err := s.userRepo.FindByProviderID(provider, providerID)
 	if err != nil {
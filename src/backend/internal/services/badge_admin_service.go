@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBadgeNotFound  = errors.New("badge not found")
+	ErrBadgeNameTaken = errors.New("a badge with this name already exists")
+)
+
+// BadgeAdminService lets admins manage badges and their rules without a
+// deploy. Rules are validated before they are saved, so the badge engine never
+// meets one it can't evaluate. Users' progress follows a new or changed rule
+// as they next catch something; the recompute-stats command credits their
+// earlier catches straight away.
+type BadgeAdminService interface {
+	// List returns every badge, including inactive and secret ones
+	List() ([]models.Badge, error)
+	Create(req *models.BadgeRequest) (*models.Badge, error)
+	// Update replaces everything about a badge
+	Update(badgeID uuid.UUID, req *models.BadgeRequest) (*models.Badge, error)
+	// Deactivate stops a badge from being listed or earned. Users who earned it
+	// keep it.
+	Deactivate(badgeID uuid.UUID) (*models.Badge, error)
+	// ValidateRule returns a *models.RuleError if the rule can't be evaluated
+	ValidateRule(rule *models.BadgeRule) error
+	// EvaluateRule measures where a user stands on each requirement of a rule
+	EvaluateRule(userID uuid.UUID, rule *models.BadgeRule) ([]RequirementProgress, error)
+}
+
+type badgeAdminService struct {
+	userRepo    repositories.UserRepository
+	badgeRepo   *repositories.BadgeRepository
+	badgeEngine BadgeEngine
+}
+
+func NewBadgeAdminService(userRepo repositories.UserRepository, badgeRepo *repositories.BadgeRepository, badgeEngine BadgeEngine) BadgeAdminService {
+	return &badgeAdminService{
+		userRepo:    userRepo,
+		badgeRepo:   badgeRepo,
+		badgeEngine: badgeEngine,
+	}
+}
+
+func (s *badgeAdminService) List() ([]models.Badge, error) {
+	return s.badgeRepo.GetAll()
+}
+
+func (s *badgeAdminService) Create(req *models.BadgeRequest) (*models.Badge, error) {
+	if err := s.check(uuid.Nil, req); err != nil {
+		return nil, err
+	}
+
+	badge := &models.Badge{}
+	applyBadgeRequest(badge, req)
+	if err := s.badgeRepo.Create(badge); err != nil {
+		return nil, err
+	}
+	return badge, nil
+}
+
+func (s *badgeAdminService) Update(badgeID uuid.UUID, req *models.BadgeRequest) (*models.Badge, error) {
+	badge, err := s.get(badgeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.check(badgeID, req); err != nil {
+		return nil, err
+	}
+
+	applyBadgeRequest(badge, req)
+	if err := s.badgeRepo.Update(badge); err != nil {
+		return nil, err
+	}
+	return badge, nil
+}
+
+func (s *badgeAdminService) Deactivate(badgeID uuid.UUID) (*models.Badge, error) {
+	badge, err := s.get(badgeID)
+	if err != nil {
+		return nil, err
+	}
+	if !badge.IsActive {
+		return badge, nil
+	}
+
+	badge.IsActive = false
+	if err := s.badgeRepo.Update(badge); err != nil {
+		return nil, err
+	}
+	return badge, nil
+}
+
+func (s *badgeAdminService) ValidateRule(rule *models.BadgeRule) error {
+	return rule.Validate()
+}
+
+func (s *badgeAdminService) EvaluateRule(userID uuid.UUID, rule *models.BadgeRule) ([]RequirementProgress, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return s.badgeEngine.EvaluateRule(userID, rule)
+}
+
+func (s *badgeAdminService) get(badgeID uuid.UUID) (*models.Badge, error) {
+	badge, err := s.badgeRepo.GetByID(badgeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBadgeNotFound
+	}
+	return badge, err
+}
+
+// check validates the rule of a badge request and that no other badge than
+// badgeID has its name
+func (s *badgeAdminService) check(badgeID uuid.UUID, req *models.BadgeRequest) error {
+	if err := req.Rule.Validate(); err != nil {
+		return err
+	}
+
+	existing, err := s.badgeRepo.GetByName(req.Name)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil
+	case err != nil:
+		return err
+	case existing.ID != badgeID:
+		return ErrBadgeNameTaken
+	}
+	return nil
+}
+
+func applyBadgeRequest(badge *models.Badge, req *models.BadgeRequest) {
+	badge.Name = req.Name
+	badge.Description = req.Description
+	badge.ShortDesc = req.ShortDesc
+	badge.BadgeType = req.BadgeType
+	badge.BadgeRarity = req.BadgeRarity
+	if badge.BadgeRarity == "" {
+		badge.BadgeRarity = models.BadgeRarityBronze
+	}
+	badge.IconURL = req.IconURL
+	badge.Color = req.Color
+	badge.Rule = req.Rule
+	badge.PointsAwarded = req.PointsAwarded
+	badge.IsSecret = req.IsSecret
+	badge.IsActive = req.IsActive == nil || *req.IsActive
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BadgeEngine evaluates the rule of each badge, keeps users' progress towards
+// every active badge up to date and awards the badges they complete. Progress
+// follows the user's catches, so deleting or rejecting catches lowers it, and
+// takes back the badges and badge points those catches had earned.
+type BadgeEngine interface {
+	// EvaluateUser updates a user's progress in a transaction of its own, after
+	// something such as a new catch changed it, and announces the badges earned.
+	// catchID, if not nil, is recorded as the catch that earned them.
+	EvaluateUser(userID uuid.UUID, catchID *uuid.UUID) ([]models.Badge, error)
+	// Reevaluate lowers the progress of a user whose catches were removed, from
+	// within tx, which must hold the lock on stats. Earned badges whose progress
+	// dropped below complete are taken back, their points taken out of stats,
+	// which the caller saves. The badges taken back are returned.
+	Reevaluate(tx *gorm.DB, stats *models.UserStats) ([]models.Badge, error)
+	// Announce publishes a badge_earned event for each badge
+	Announce(userID uuid.UUID, earned []models.Badge, catchID *uuid.UUID)
+	// Rebuild works out the user's badges from scratch, within tx, from stats
+	// rebuilt from their history and their current badge records, which must
+	// have their Badge loaded. It returns the records the user should have, with
+	// their Badge, and saves nothing. Earned badges that are no longer complete
+	// stay earned unless revoke is set; records of inactive badges, or of
+	// badges without a valid rule, are returned unchanged.
+	Rebuild(tx *gorm.DB, stats *models.UserStats, current []models.UserBadge, revoke bool) ([]models.UserBadge, error)
+	// MaxProgress returns the progress that completes the badge, for users who
+	// haven't started it
+	MaxProgress(badge *models.Badge) (int, error)
+	// EvaluateRule measures where the user stands on each requirement of a
+	// valid rule, which needn't belong to a badge, without saving anything
+	EvaluateRule(userID uuid.UUID, rule *models.BadgeRule) ([]RequirementProgress, error)
+}
+
+// RequirementProgress is a user's standing on one requirement of a badge rule
+type RequirementProgress struct {
+	Count    models.RuleMeasure `json:"count"`
+	Progress int                `json:"progress"` // The count, which may exceed the target
+	Target   int                `json:"target"`
+	Met      bool               `json:"met"`
+}
+
+type badgeEngine struct {
+	catchRepo   *repositories.AnimalCatchRepository
+	badgeRepo   *repositories.BadgeRepository
+	statsRepo   *repositories.UserStatsRepository
+	speciesRepo *repositories.SpeciesRepository
+	publisher   events.Publisher
+}
+
+func NewBadgeEngine(catchRepo *repositories.AnimalCatchRepository, badgeRepo *repositories.BadgeRepository, statsRepo *repositories.UserStatsRepository, speciesRepo *repositories.SpeciesRepository, publisher events.Publisher) BadgeEngine {
+	return &badgeEngine{
+		catchRepo:   catchRepo,
+		badgeRepo:   badgeRepo,
+		statsRepo:   statsRepo,
+		speciesRepo: speciesRepo,
+		publisher:   publisher,
+	}
+}
+
+func (e *badgeEngine) EvaluateUser(userID uuid.UUID, catchID *uuid.UUID) ([]models.Badge, error) {
+	var earned []models.Badge
+	err := repositories.Transaction(func(tx *gorm.DB) error {
+		statsRepo := e.statsRepo.WithTx(tx)
+		stats, err := statsRepo.GetForUpdate(userID)
+		if err != nil {
+			return err
+		}
+		earned, err = e.evaluate(tx, stats, catchID)
+		if err != nil || len(earned) == 0 {
+			return err
+		}
+		return statsRepo.Update(stats)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e.Announce(userID, earned, catchID)
+	return earned, nil
+}
+
+func (e *badgeEngine) Reevaluate(tx *gorm.DB, stats *models.UserStats) ([]models.Badge, error) {
+	badgeRepo := e.badgeRepo.WithTx(tx)
+	badges, err := badgeRepo.GetActive()
+	if err != nil {
+		return nil, err
+	}
+	userBadges, err := badgeRepo.GetUserBadges(stats.UserID)
+	if err != nil {
+		return nil, err
+	}
+	byBadge := make(map[uuid.UUID]*models.UserBadge, len(userBadges))
+	for i := range userBadges {
+		byBadge[userBadges[i].BadgeID] = &userBadges[i]
+	}
+
+	rules := &badgeRules{catchRepo: e.catchRepo.WithTx(tx), speciesRepo: e.speciesRepo, stats: stats}
+	var revoked []models.Badge
+	for _, badge := range badges {
+		// Removing catches can't start progress on a badge
+		userBadge := byBadge[badge.ID]
+		if userBadge == nil {
+			continue
+		}
+
+		progress, maxProgress, ok, err := rules.progress(&badge)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		progress = min(progress, maxProgress)
+		if userBadge.IsEarned() {
+			// Only a loss of progress takes a badge back, not a raised target
+			// such as a new species joining a target_all requirement
+			if progress >= userBadge.Progress || progress >= maxProgress {
+				continue
+			}
+			userBadge.EarnedAt = nil
+			userBadge.RelatedCatchID = nil
+			stats.BadgesEarned = max(stats.BadgesEarned-1, 0)
+			stats.TotalPoints = max(stats.TotalPoints-badge.PointsAwarded, 0)
+			revoked = append(revoked, badge)
+		} else if userBadge.Progress == progress && userBadge.MaxProgress == maxProgress {
+			continue
+		}
+		userBadge.Progress = progress
+		userBadge.MaxProgress = maxProgress
+
+		if err := badgeRepo.SaveUserBadge(userBadge); err != nil {
+			return nil, err
+		}
+	}
+	return revoked, nil
+}
+
+func (e *badgeEngine) Announce(userID uuid.UUID, earned []models.Badge, catchID *uuid.UUID) {
+	for _, badge := range earned {
+		e.publisher.Publish(events.New(events.TypeBadgeEarned, userID, uuid.Nil, events.BadgeEarnedData{
+			BadgeID:       badge.ID,
+			Name:          badge.Name,
+			BadgeRarity:   string(badge.BadgeRarity),
+			PointsAwarded: badge.PointsAwarded,
+			CatchID:       catchID,
+		}))
+	}
+}
+
+func (e *badgeEngine) Rebuild(tx *gorm.DB, stats *models.UserStats, current []models.UserBadge, revoke bool) ([]models.UserBadge, error) {
+	badges, err := e.badgeRepo.WithTx(tx).GetActive()
+	if err != nil {
+		return nil, err
+	}
+	byBadge := make(map[uuid.UUID]models.UserBadge, len(current))
+	for _, userBadge := range current {
+		byBadge[userBadge.BadgeID] = userBadge
+	}
+
+	rules := &badgeRules{catchRepo: e.catchRepo.WithTx(tx), speciesRepo: e.speciesRepo, stats: stats}
+	rebuilt := make([]models.UserBadge, 0, len(badges))
+	for _, badge := range badges {
+		userBadge, exists := byBadge[badge.ID]
+		delete(byBadge, badge.ID)
+		if exists && userBadge.IsEarned() && !revoke {
+			rebuilt = append(rebuilt, userBadge)
+			continue
+		}
+
+		progress, maxProgress, ok, err := rules.progress(&badge)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if exists {
+				rebuilt = append(rebuilt, userBadge)
+			}
+			continue
+		}
+
+		progress = min(progress, maxProgress)
+		if !exists {
+			if progress == 0 {
+				continue
+			}
+			userBadge = models.UserBadge{UserID: stats.UserID, BadgeID: badge.ID}
+		}
+		userBadge.Progress = progress
+		userBadge.MaxProgress = maxProgress
+		userBadge.Badge = badge
+
+		switch {
+		case userBadge.IsCompleted() && !userBadge.IsEarned():
+			now := time.Now()
+			userBadge.EarnedAt = &now
+			userBadge.RelatedCatchID = nil
+		case !userBadge.IsCompleted() && userBadge.IsEarned():
+			userBadge.EarnedAt = nil
+			userBadge.RelatedCatchID = nil
+		}
+		rebuilt = append(rebuilt, userBadge)
+	}
+
+	for _, userBadge := range byBadge {
+		rebuilt = append(rebuilt, userBadge)
+	}
+	return rebuilt, nil
+}
+
+func (e *badgeEngine) MaxProgress(badge *models.Badge) (int, error) {
+	if badge.Rule == nil || badge.Rule.Validate() != nil {
+		return 1, nil
+	}
+	total := 0
+	for i := range badge.Rule.Requirements {
+		target, err := requirementTarget(e.speciesRepo, &badge.Rule.Requirements[i])
+		if err != nil {
+			return 0, err
+		}
+		total += target
+	}
+	return max(total, 1), nil
+}
+
+func (e *badgeEngine) EvaluateRule(userID uuid.UUID, rule *models.BadgeRule) ([]RequirementProgress, error) {
+	stats, err := e.statsRepo.GetByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		stats = &models.UserStats{UserID: userID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	rules := &badgeRules{catchRepo: e.catchRepo, speciesRepo: e.speciesRepo, stats: stats}
+	progress := make([]RequirementProgress, 0, len(rule.Requirements))
+	for i := range rule.Requirements {
+		requirement := &rule.Requirements[i]
+		count, err := rules.count(requirement)
+		if err != nil {
+			return nil, err
+		}
+		target, err := requirementTarget(e.speciesRepo, requirement)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, RequirementProgress{
+			Count:    requirement.Count,
+			Progress: count,
+			Target:   target,
+			Met:      count >= target,
+		})
+	}
+	return progress, nil
+}
+
+// evaluate refreshes the user's progress towards every active badge they
+// haven't earned, awarding those that are now complete
+func (e *badgeEngine) evaluate(tx *gorm.DB, stats *models.UserStats, catchID *uuid.UUID) ([]models.Badge, error) {
+	badgeRepo := e.badgeRepo.WithTx(tx)
+	badges, err := badgeRepo.GetActive()
+	if err != nil {
+		return nil, err
+	}
+	userBadges, err := badgeRepo.GetUserBadges(stats.UserID)
+	if err != nil {
+		return nil, err
+	}
+	byBadge := make(map[uuid.UUID]*models.UserBadge, len(userBadges))
+	for i := range userBadges {
+		byBadge[userBadges[i].BadgeID] = &userBadges[i]
+	}
+
+	rules := &badgeRules{catchRepo: e.catchRepo.WithTx(tx), speciesRepo: e.speciesRepo, stats: stats}
+	var earned []models.Badge
+	for _, badge := range badges {
+		userBadge := byBadge[badge.ID]
+		if userBadge != nil && userBadge.IsEarned() {
+			continue
+		}
+
+		progress, maxProgress, ok, err := rules.progress(&badge)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		progress = min(progress, maxProgress)
+		if userBadge == nil {
+			if progress == 0 {
+				continue
+			}
+			userBadge = &models.UserBadge{UserID: stats.UserID, BadgeID: badge.ID}
+		} else if userBadge.Progress == progress && userBadge.MaxProgress == maxProgress {
+			continue
+		}
+		userBadge.Progress = progress
+		userBadge.MaxProgress = maxProgress
+
+		if userBadge.IsCompleted() {
+			now := time.Now()
+			userBadge.EarnedAt = &now
+			userBadge.RelatedCatchID = catchID
+			stats.BadgesEarned++
+			stats.TotalPoints += badge.PointsAwarded
+			earned = append(earned, badge)
+		}
+
+		if err := badgeRepo.SaveUserBadge(userBadge); err != nil {
+			return nil, err
+		}
+	}
+	return earned, nil
+}
+
+// badgeRules measures one user's progress against badge rules
+type badgeRules struct {
+	catchRepo   *repositories.AnimalCatchRepository
+	speciesRepo *repositories.SpeciesRepository
+	stats       *models.UserStats
+}
+
+// progress returns how far the user is towards the badge and what completes
+// it, or false if the badge has no rule we can evaluate. Each requirement
+// counts up to its target, so progress shows how much of the whole rule is met.
+func (r *badgeRules) progress(badge *models.Badge) (progress, maxProgress int, ok bool, err error) {
+	if badge.Rule == nil {
+		return 0, 0, false, nil
+	}
+	if err := badge.Rule.Validate(); err != nil {
+		log.Printf("Skipping badge %s with an invalid rule: %v", badge.Name, err)
+		return 0, 0, false, nil
+	}
+
+	for i := range badge.Rule.Requirements {
+		requirement := &badge.Rule.Requirements[i]
+		count, err := r.count(requirement)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		target, err := requirementTarget(r.speciesRepo, requirement)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		progress += min(count, target)
+		maxProgress += target
+	}
+	return progress, maxProgress, true, nil
+}
+
+// count returns what the requirement measures for the user
+func (r *badgeRules) count(requirement *models.RuleRequirement) (int, error) {
+	switch requirement.Count {
+	case models.MeasureLongestStreak:
+		return r.stats.LongestStreak, nil
+	case models.MeasureLikesReceived:
+		return r.stats.TotalLikes, nil
+	case models.MeasureCommentsReceived:
+		return r.stats.TotalComments, nil
+	case models.MeasureFollowers:
+		return r.stats.FollowersCount, nil
+	case models.MeasureCatchesReviewed:
+		count, err := r.catchRepo.CountReviewedBy(r.stats.UserID)
+		return int(count), err
+	}
+
+	// The stats already keep the unfiltered totals
+	if requirement.Where == nil {
+		switch requirement.Count {
+		case models.MeasureCatches:
+			return r.stats.TotalCatches, nil
+		case models.MeasureSpecies:
+			return r.stats.UniqueSpecies, nil
+		case models.MeasureCountries:
+			return r.stats.CountriesVisited, nil
+		case models.MeasureCities:
+			return r.stats.CitiesVisited, nil
+		}
+	}
+
+	count, err := r.catchRepo.CountForRule(r.stats.UserID, requirement)
+	return int(count), err
+}
+
+// requirementTarget returns the count that meets the requirement. A target of
+// every species follows the species there are now.
+func requirementTarget(speciesRepo *repositories.SpeciesRepository, requirement *models.RuleRequirement) (int, error) {
+	if !requirement.TargetAll {
+		return requirement.Target, nil
+	}
+	total, err := speciesRepo.CountForRule(requirement.Where)
+	if err != nil {
+		return 0, err
+	}
+	return max(int(total), 1), nil
+}
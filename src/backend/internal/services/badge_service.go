@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxShowcaseBadges is how many badges a profile can show
+const maxShowcaseBadges = 10
+
+var (
+	ErrBadgeNotEarned       = errors.New("badge has not been earned")
+	ErrShowcaseTooLarge     = errors.New("too many badges to show")
+	ErrShowcaseDuplicateIDs = errors.New("a badge is listed more than once")
+)
+
+// BadgeProgress is a user's standing on a badge
+type BadgeProgress struct {
+	Badge          *models.Badge `json:"badge"`
+	Progress       int           `json:"progress"`
+	MaxProgress    int           `json:"max_progress"`
+	Percentage     float64       `json:"percentage"`
+	EarnedAt       *time.Time    `json:"earned_at"`
+	RelatedCatchID *uuid.UUID    `json:"related_catch_id"`
+	IsDisplayed    bool          `json:"is_displayed"`  // Shown on the profile, for earned badges
+	DisplayOrder   int           `json:"display_order"` // Position on the profile
+}
+
+type BadgeService interface {
+	// Catalog returns the active badges. Secret badges are left out unless the
+	// viewer has earned them; viewerID may be uuid.Nil.
+	Catalog(viewerID uuid.UUID) ([]models.Badge, error)
+	// Progress returns the user's progress towards every badge in their catalog
+	Progress(userID uuid.UUID) ([]BadgeProgress, error)
+	// Showcase returns the earned badges the user shows on their profile, in order
+	Showcase(userID uuid.UUID) ([]BadgeProgress, error)
+	// SetShowcase shows the given earned badges on the user's profile in the
+	// given order, hiding the rest
+	SetShowcase(userID uuid.UUID, badgeIDs []uuid.UUID) ([]BadgeProgress, error)
+}
+
+type badgeService struct {
+	userRepo    repositories.UserRepository
+	badgeRepo   *repositories.BadgeRepository
+	badgeEngine BadgeEngine
+}
+
+func NewBadgeService(userRepo repositories.UserRepository, badgeRepo *repositories.BadgeRepository, badgeEngine BadgeEngine) BadgeService {
+	return &badgeService{
+		userRepo:    userRepo,
+		badgeRepo:   badgeRepo,
+		badgeEngine: badgeEngine,
+	}
+}
+
+func (s *badgeService) Catalog(viewerID uuid.UUID) ([]models.Badge, error) {
+	badges, _, err := s.catalog(viewerID)
+	return badges, err
+}
+
+func (s *badgeService) Progress(userID uuid.UUID) ([]BadgeProgress, error) {
+	badges, byBadge, err := s.catalog(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make([]BadgeProgress, 0, len(badges))
+	for i := range badges {
+		badge := &badges[i]
+		userBadge := byBadge[badge.ID]
+		if userBadge == nil {
+			maxProgress, err := s.badgeEngine.MaxProgress(badge)
+			if err != nil {
+				return nil, err
+			}
+			userBadge = &models.UserBadge{MaxProgress: maxProgress}
+		}
+		progress = append(progress, badgeProgressOf(badge, userBadge))
+	}
+	return progress, nil
+}
+
+func (s *badgeService) Showcase(userID uuid.UUID) ([]BadgeProgress, error) {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	userBadges, err := s.badgeRepo.GetShowcase(userID)
+	if err != nil {
+		return nil, err
+	}
+	showcase := make([]BadgeProgress, 0, len(userBadges))
+	for i := range userBadges {
+		showcase = append(showcase, badgeProgressOf(&userBadges[i].Badge, &userBadges[i]))
+	}
+	return showcase, nil
+}
+
+func (s *badgeService) SetShowcase(userID uuid.UUID, badgeIDs []uuid.UUID) ([]BadgeProgress, error) {
+	if len(badgeIDs) > maxShowcaseBadges {
+		return nil, ErrShowcaseTooLarge
+	}
+	userBadges, err := s.badgeRepo.GetUserBadges(userID)
+	if err != nil {
+		return nil, err
+	}
+	earned := make(map[uuid.UUID]bool, len(userBadges))
+	for _, userBadge := range userBadges {
+		earned[userBadge.BadgeID] = userBadge.IsEarned()
+	}
+
+	seen := make(map[uuid.UUID]bool, len(badgeIDs))
+	for _, badgeID := range badgeIDs {
+		if seen[badgeID] {
+			return nil, ErrShowcaseDuplicateIDs
+		}
+		seen[badgeID] = true
+		if !earned[badgeID] {
+			return nil, ErrBadgeNotEarned
+		}
+	}
+
+	if err := s.badgeRepo.SetShowcase(userID, badgeIDs); err != nil {
+		return nil, err
+	}
+	return s.Showcase(userID)
+}
+
+// catalog returns the active badges the user may see, with their progress
+// records by badge. Secret badges only show once earned.
+func (s *badgeService) catalog(userID uuid.UUID) ([]models.Badge, map[uuid.UUID]*models.UserBadge, error) {
+	badges, err := s.badgeRepo.GetActive()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byBadge := make(map[uuid.UUID]*models.UserBadge)
+	if userID != uuid.Nil {
+		userBadges, err := s.badgeRepo.GetUserBadges(userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range userBadges {
+			byBadge[userBadges[i].BadgeID] = &userBadges[i]
+		}
+	}
+
+	visible := badges[:0]
+	for _, badge := range badges {
+		if badge.IsSecret {
+			if userBadge := byBadge[badge.ID]; userBadge == nil || !userBadge.IsEarned() {
+				continue
+			}
+		}
+		visible = append(visible, badge)
+	}
+	return visible, byBadge, nil
+}
+
+func badgeProgressOf(badge *models.Badge, userBadge *models.UserBadge) BadgeProgress {
+	return BadgeProgress{
+		Badge:          badge,
+		Progress:       userBadge.Progress,
+		MaxProgress:    userBadge.MaxProgress,
+		Percentage:     userBadge.GetProgressPercentage(),
+		EarnedAt:       userBadge.EarnedAt,
+		RelatedCatchID: userBadge.RelatedCatchID,
+		IsDisplayed:    userBadge.IsEarned() && userBadge.IsDisplayed,
+		DisplayOrder:   userBadge.DisplayOrder,
+	}
+}
@@ -0,0 +1,695 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/timezone"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxCaptureClockSkew is how far in the future a capture time may be before we
+// distrust the device clock and fall back to the submission time
+const maxCaptureClockSkew = 10 * time.Minute
+
+// Perceptual hash distances (in bits out of 64) used for duplicate detection
+const (
+	ownDuplicateDistance       = 2  // The same image re-encoded: a resubmission
+	otherUserDuplicateDistance = 10 // Probably the same image, possibly cropped or edited
+)
+
+var (
+	ErrSpeciesNotFound  = errors.New("species not found")
+	ErrDuplicatePhoto   = errors.New("you have already submitted this photo")
+	ErrCatchIDConflict  = errors.New("catch ID is already in use")
+	ErrCaughtAtInFuture = errors.New("caught_at is in the future")
+	ErrCatchNotOwned    = errors.New("you can only edit your own catches")
+	ErrCatchNotEditable = errors.New("catch can no longer be edited once it has been verified")
+)
+
+// CreateCatchInput holds everything needed to record a catch
+type CreateCatchInput struct {
+	ID          *uuid.UUID // Client-generated ID, makes retries idempotent
+	SpeciesID   uuid.UUID
+	Latitude    float64
+	Longitude   float64
+	PhotoID     *uuid.UUID
+	PhotoURL    string
+	UserNotes   string
+	UserRating  *int
+	Weather     string
+	Temperature *float64
+	CaughtAt    *time.Time             // Client-side capture time, e.g. recorded while offline
+	Visibility  models.CatchVisibility // Empty for the user's default
+}
+
+// UpdateCatchInput holds the fields of a catch to change; nil fields are kept
+type UpdateCatchInput struct {
+	PhotoID     *uuid.UUID
+	UserNotes   *string
+	UserRating  *int
+	Weather     *string
+	Temperature *float64
+	Visibility  *models.CatchVisibility // Can be changed at any time
+}
+
+// changesContent returns true if the input edits more than the visibility
+func (input *UpdateCatchInput) changesContent() bool {
+	return input.PhotoID != nil || input.UserNotes != nil || input.UserRating != nil || input.Weather != nil || input.Temperature != nil
+}
+
+type CatchService interface {
+	// Create records a new catch. If input.ID refers to a catch the user already
+	// created, that catch is returned with created set to false.
+	Create(userID uuid.UUID, input CreateCatchInput) (catch *models.AnimalCatch, created bool, err error)
+	// Update edits one of the user's catches. Only the visibility may change once
+	// the catch has left pending; a new photo sends it through verification again.
+	Update(userID, catchID uuid.UUID, input UpdateCatchInput) (*models.AnimalCatch, error)
+	CheckDuplicatePhoto(userID uuid.UUID, photo *models.Photo, excludeID uuid.UUID) (own, other *repositories.SimilarPhotoCatch, err error)
+	// Delete deletes a catch with its likes and comments and takes it out of its
+	// owner's stats and its location's counts
+	Delete(catchID uuid.UUID) error
+	// Review moves pending catches, other than the reviewer's own, to status and
+	// returns the ones it moved. Rejected catches are taken out of their owners'
+	// stats and their locations' counts.
+	Review(ids []uuid.UUID, status models.VerificationStatus, reviewerID uuid.UUID, notes string) ([]repositories.ReviewedCatch, error)
+}
+
+type catchService struct {
+	userRepo            repositories.UserRepository
+	catchRepo           *repositories.AnimalCatchRepository
+	speciesRepo         *repositories.SpeciesRepository
+	locationRepo        *repositories.LocationRepository
+	statsRepo           *repositories.UserStatsRepository
+	badgeEngine         BadgeEngine
+	statsService        StatsService
+	streakService       StreakService
+	photoService        PhotoService
+	scoringService      ScoringService
+	verificationService VerificationService
+	feedService         FeedService
+	publisher           events.Publisher
+}
+
+func NewCatchService(userRepo repositories.UserRepository, catchRepo *repositories.AnimalCatchRepository, speciesRepo *repositories.SpeciesRepository, locationRepo *repositories.LocationRepository, statsRepo *repositories.UserStatsRepository, badgeEngine BadgeEngine, statsService StatsService, streakService StreakService, photoService PhotoService, scoringService ScoringService, verificationService VerificationService, feedService FeedService, publisher events.Publisher) CatchService {
+	return &catchService{
+		userRepo:            userRepo,
+		catchRepo:           catchRepo,
+		speciesRepo:         speciesRepo,
+		locationRepo:        locationRepo,
+		statsRepo:           statsRepo,
+		badgeEngine:         badgeEngine,
+		statsService:        statsService,
+		streakService:       streakService,
+		photoService:        photoService,
+		scoringService:      scoringService,
+		verificationService: verificationService,
+		feedService:         feedService,
+		publisher:           publisher,
+	}
+}
+
+func (s *catchService) Create(userID uuid.UUID, input CreateCatchInput) (*models.AnimalCatch, bool, error) {
+	// A retried submission returns the catch created the first time
+	if input.ID != nil {
+		existing, err := s.existingCatch(s.catchRepo, userID, *input.ID)
+		if existing != nil || err != nil {
+			return existing, false, err
+		}
+	}
+
+	now := time.Now()
+	if input.CaughtAt != nil && input.CaughtAt.After(now.Add(maxCaptureClockSkew)) {
+		return nil, false, ErrCaughtAtInFuture
+	}
+
+	species, err := s.speciesRepo.GetByID(input.SpeciesID)
+	if err != nil {
+		return nil, false, ErrSpeciesNotFound
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	visibility := input.Visibility
+	if visibility == "" {
+		visibility = user.DefaultCatchVisibility
+	}
+	if !visibility.IsValid() {
+		visibility = models.VisibilityPublic
+	}
+
+	// The photo must be one the caller uploaded to our storage
+	photo, err := s.photoService.ResolveForCatch(userID, input.PhotoID, input.PhotoURL)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Compare the submitted position with the GPS fix embedded in the photo
+	var exifDistance *float64
+	locationMismatch := false
+	if photo.HasExifPosition() {
+		submitted := &models.Location{Latitude: input.Latitude, Longitude: input.Longitude}
+		distance := submitted.DistanceTo(&models.Location{Latitude: *photo.ExifLatitude, Longitude: *photo.ExifLongitude})
+		exifDistance = &distance
+		locationMismatch = distance > config.AppConfig.ExifLocationToleranceKm
+	}
+
+	zone := timezone.Lookup(input.Latitude, input.Longitude)
+
+	// Prefer the time the client recorded, then the camera's, then now
+	caughtAt := now
+	if input.CaughtAt != nil {
+		caughtAt = *input.CaughtAt
+	} else if takenAt, ok := photo.LocalTakenAt(zone); ok && !takenAt.After(now.Add(maxCaptureClockSkew)) {
+		caughtAt = takenAt
+	}
+	caughtAt = caughtAt.In(zone)
+	streakDay := s.streakService.CatchDay(user, caughtAt)
+
+	animalCatch := &models.AnimalCatch{
+		UserID:           userID,
+		SpeciesID:        species.ID,
+		PhotoID:          &photo.ID,
+		UserPhotoURL:     photo.URL,
+		PerceptualHash:   photo.PerceptualHash,
+		UserNotes:        input.UserNotes,
+		UserRating:       input.UserRating,
+		Weather:          models.WeatherCondition(input.Weather),
+		TimeOfDay:        models.GetTimeOfDay(caughtAt, input.Latitude, input.Longitude),
+		Temperature:      input.Temperature,
+		CaughtAt:         caughtAt,
+		LocationMismatch: locationMismatch,
+		ExifDistance:     exifDistance,
+	}
+	animalCatch.SetVisibility(visibility)
+	if species.RequiresGeoprivacy() {
+		animalCatch.ObscureCoordinates()
+	}
+	if input.ID != nil {
+		animalCatch.ID = *input.ID
+	}
+
+	var (
+		location  *models.Location
+		existing  *models.AnimalCatch
+		milestone *StreakMilestone
+		saved     models.UserStats
+	)
+	err = repositories.Transaction(func(tx *gorm.DB) error {
+		catchRepo := s.catchRepo.WithTx(tx)
+		locationRepo := s.locationRepo.WithTx(tx)
+		statsRepo := s.statsRepo.WithTx(tx)
+
+		// Lock the user's stats first: this serializes the user's submissions, so
+		// first-catch, streak and counters are never computed from a stale view
+		stats, err := statsRepo.GetForUpdate(userID)
+		if err != nil {
+			return err
+		}
+
+		// A concurrent retry may have created the catch while we waited for the lock
+		if input.ID != nil {
+			existing, err = s.existingCatch(catchRepo, userID, *input.ID)
+			if existing != nil || err != nil {
+				return err
+			}
+		}
+
+		// Stop the same photo from being submitted repeatedly to farm points
+		ownDuplicate, otherDuplicate, err := s.CheckDuplicatePhoto(userID, photo, animalCatch.ID)
+		if err != nil {
+			return err
+		}
+		if ownDuplicate != nil {
+			return fmt.Errorf("%w (catch %s)", ErrDuplicatePhoto, ownDuplicate.ID)
+		}
+		// Resembling another user's photo needs a human to decide
+		if otherDuplicate != nil {
+			animalCatch.ModerationReason = models.ModerationPossibleDuplicate
+			animalCatch.DuplicateOf = &otherDuplicate.ID
+		}
+
+		location, err = s.findOrCreateLocation(locationRepo, input.Latitude, input.Longitude, zone.String(), photo, locationMismatch)
+		if err != nil {
+			return err
+		}
+		animalCatch.LocationID = location.ID
+
+		novelty, err := s.checkNovelty(catchRepo, animalCatch, location)
+		if err != nil {
+			return err
+		}
+		animalCatch.IsFirstCatch = novelty.species
+
+		// The catch is scored on the streak it extends, so advance that first;
+		// its points are added once known
+		streakBefore := stats.CurrentStreak
+		if err := s.streakService.CoverMissedDays(tx, stats, streakDay); err != nil {
+			return err
+		}
+		stats.RecordCatch(species, 0, novelty.species, streakDay)
+		milestone = s.streakService.ReachMilestone(stats, streakBefore)
+
+		breakdown, err := s.scoringService.Score(ScoreInput{
+			Species:      species,
+			Location:     location,
+			IsFirstCatch: novelty.species,
+			StreakDays:   stats.StreakEndingOn(streakDay),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to score catch: %w", err)
+		}
+		animalCatch.PointsBreakdown = breakdown
+		animalCatch.PointsAwarded = breakdown.Total
+		animalCatch.ComboMultiplier = breakdown.Multiplier
+
+		inserted, err := catchRepo.CreateIfNotExists(animalCatch)
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			// We checked our own catches under the lock, so the ID is someone else's
+			return ErrCatchIDConflict
+		}
+
+		stats.TotalPoints += animalCatch.PointsAwarded
+		if novelty.country {
+			stats.CountriesVisited++
+		}
+		if novelty.city {
+			stats.CitiesVisited++
+		}
+		if err := s.statsService.RecordDistance(tx, stats, animalCatch, location); err != nil {
+			return err
+		}
+		if err := statsRepo.Update(stats); err != nil {
+			return err
+		}
+		saved = *stats
+
+		// The location is locked last, so catches there wait for each other
+		// only while its counters change
+		if animalCatch.IsPublic {
+			return s.recordAtLocation(tx, animalCatch)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	// Auto-verify in the background; low-confidence catches stay pending for moderators
+	s.verificationService.Enqueue(animalCatch.ID)
+
+	// Load relationships for response
+	animalCatch.Species = *species
+	animalCatch.Location = *location
+	animalCatch.Photo = photo
+
+	if err := s.feedService.Publish(animalCatch); err != nil {
+		log.Printf("Failed to publish catch %s to feeds: %v", animalCatch.ID, err)
+	}
+	s.publishEvents(animalCatch)
+	s.streakService.Announce(&saved, milestone)
+
+	// Badge rules run aggregate queries over the user's catches, so they are
+	// evaluated once the catch is committed and its locks released
+	if _, err := s.badgeEngine.EvaluateUser(userID, &animalCatch.ID); err != nil {
+		log.Printf("Failed to update badges of user %s: %v", userID, err)
+	}
+
+	return animalCatch, true, nil
+}
+
+func (s *catchService) Update(userID, catchID uuid.UUID, input UpdateCatchInput) (*models.AnimalCatch, error) {
+	var photo *models.Photo
+	if input.PhotoID != nil {
+		var err error
+		if photo, err = s.photoService.ResolveForCatch(userID, input.PhotoID, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	photoChanged := false
+	err := repositories.Transaction(func(tx *gorm.DB) error {
+		catchRepo := s.catchRepo.WithTx(tx)
+		locationRepo := s.locationRepo.WithTx(tx)
+
+		// The lock keeps verification from completing between the check and the write
+		catch, err := catchRepo.GetForUpdate(catchID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCatchNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if catch.UserID != userID {
+			return ErrCatchNotOwned
+		}
+		if !catch.CanEdit() && input.changesContent() {
+			return ErrCatchNotEditable
+		}
+
+		updates := map[string]interface{}{}
+		if photo != nil && (catch.PhotoID == nil || *catch.PhotoID != photo.ID) {
+			photoUpdates, err := s.photoUpdates(locationRepo, catch, photo)
+			if err != nil {
+				return err
+			}
+			maps.Copy(updates, photoUpdates)
+			photoChanged = true
+		}
+		if input.UserNotes != nil {
+			updates["user_notes"] = *input.UserNotes
+		}
+		if input.UserRating != nil {
+			updates["user_rating"] = *input.UserRating
+		}
+		if input.Weather != nil {
+			updates["weather"] = models.WeatherCondition(*input.Weather)
+		}
+		if input.Temperature != nil {
+			updates["temperature"] = *input.Temperature
+		}
+		visibilityChanged := false
+		if input.Visibility != nil && *input.Visibility != catch.Visibility {
+			catch.SetVisibility(*input.Visibility)
+			updates["visibility"] = catch.Visibility
+			updates["is_public"] = catch.IsPublic
+			visibilityChanged = true
+		}
+
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := catchRepo.UpdateFields(catch.ID, updates); err != nil {
+			return err
+		}
+
+		// Location counters only include catches listed at the location
+		if visibilityChanged {
+			if _, err := locationRepo.GetForUpdate(catch.LocationID); err != nil {
+				return err
+			}
+			return locationRepo.UpdateStats(catch.LocationID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The new photo has to be verified on its own merits
+	if photoChanged {
+		s.verificationService.Enqueue(catchID)
+	}
+
+	return s.catchRepo.GetByID(catchID)
+}
+
+// photoUpdates returns the changes that replace a pending catch's photo: the
+// photo itself, what is derived from it, and the result of verifying the old one
+func (s *catchService) photoUpdates(locationRepo *repositories.LocationRepository, catch *models.AnimalCatch, photo *models.Photo) (map[string]interface{}, error) {
+	ownDuplicate, otherDuplicate, err := s.CheckDuplicatePhoto(catch.UserID, photo, catch.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ownDuplicate != nil {
+		return nil, fmt.Errorf("%w (catch %s)", ErrDuplicatePhoto, ownDuplicate.ID)
+	}
+
+	updates := map[string]interface{}{
+		"photo_id":           photo.ID,
+		"user_photo_url":     photo.URL,
+		"perceptual_hash":    photo.PerceptualHash,
+		"verification_score": nil,
+		"verification_notes": "",
+		"location_mismatch":  false,
+		"exif_distance":      nil,
+	}
+	if otherDuplicate != nil {
+		updates["moderation_reason"] = models.ModerationPossibleDuplicate
+		updates["duplicate_of"] = otherDuplicate.ID
+	} else if catch.ModerationReason == models.ModerationPossibleDuplicate {
+		updates["moderation_reason"] = ""
+		updates["duplicate_of"] = nil
+	}
+
+	// Compare the catch's position with the GPS fix embedded in the new photo
+	if photo.HasExifPosition() {
+		location, err := locationRepo.GetByID(catch.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		distance := location.DistanceTo(&models.Location{Latitude: *photo.ExifLatitude, Longitude: *photo.ExifLongitude})
+		updates["exif_distance"] = distance
+		updates["location_mismatch"] = distance > config.AppConfig.ExifLocationToleranceKm
+	}
+	return updates, nil
+}
+
+func (s *catchService) Delete(catchID uuid.UUID) error {
+	return repositories.Transaction(func(tx *gorm.DB) error {
+		catchRepo := s.catchRepo.WithTx(tx)
+
+		// Locking the catch settles a race with a moderator rejecting it
+		catch, err := catchRepo.GetForUpdate(catchID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCatchNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		likesReceived, commentsReceived, err := catchRepo.DeleteWithInteractions(catch)
+		if err != nil {
+			return err
+		}
+		if catch.VerificationStatus != models.VerificationRejected {
+			if err := s.statsService.RemoveCatches(tx, []models.AnimalCatch{*catch}); err != nil {
+				return err
+			}
+		}
+		if err := s.statsRepo.WithTx(tx).AddReceived(catch.UserID, -int(likesReceived), -int(commentsReceived)); err != nil {
+			return err
+		}
+		return s.recountLocations(tx, []models.AnimalCatch{*catch})
+	})
+}
+
+func (s *catchService) Review(ids []uuid.UUID, status models.VerificationStatus, reviewerID uuid.UUID, notes string) ([]repositories.ReviewedCatch, error) {
+	var reviewed []repositories.ReviewedCatch
+	err := repositories.Transaction(func(tx *gorm.DB) error {
+		catchRepo := s.catchRepo.WithTx(tx)
+
+		var err error
+		reviewed, err = catchRepo.VerifyCatches(ids, status, reviewerID, notes)
+		if err != nil || status != models.VerificationRejected || len(reviewed) == 0 {
+			return err
+		}
+
+		rejectedIDs := make([]uuid.UUID, len(reviewed))
+		for i, catch := range reviewed {
+			rejectedIDs[i] = catch.ID
+		}
+		rejected, err := catchRepo.GetByIDsWithSpecies(rejectedIDs)
+		if err != nil {
+			return err
+		}
+		if err := s.statsService.RemoveCatches(tx, rejected); err != nil {
+			return err
+		}
+		return s.recountLocations(tx, rejected)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reviewed, nil
+}
+
+// recountLocations recounts the public catches at the locations of the given
+// catches, after they were deleted or rejected in tx. Locations are locked
+// after the owners' stats, the order Create takes them in.
+func (s *catchService) recountLocations(tx *gorm.DB, catches []models.AnimalCatch) error {
+	var locationIDs []uuid.UUID
+	for _, catch := range catches {
+		if catch.IsPublic {
+			locationIDs = append(locationIDs, catch.LocationID)
+		}
+	}
+	slices.SortFunc(locationIDs, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	locationIDs = slices.Compact(locationIDs)
+
+	locationRepo := s.locationRepo.WithTx(tx)
+	for _, locationID := range locationIDs {
+		if _, err := locationRepo.GetForUpdate(locationID); err != nil {
+			return err
+		}
+		if err := locationRepo.UpdateStats(locationID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishEvents announces a new catch to users nearby, if it is public
+func (s *catchService) publishEvents(catch *models.AnimalCatch) {
+	if catch.IsPublic {
+		s.publisher.Publish(events.NewNearby(events.TypeCatchCreated, catch.UserID,
+			events.Position{Latitude: catch.Location.Latitude, Longitude: catch.Location.Longitude},
+			events.CatchCreatedData{
+				CatchID:     catch.ID,
+				UserID:      catch.UserID,
+				SpeciesID:   catch.SpeciesID,
+				SpeciesName: catch.Species.CommonName,
+				Rarity:      string(catch.Species.Rarity),
+			}))
+	}
+}
+
+// recordAtLocation counts a new public catch, already inserted in tx, in its
+// location's statistics. The location is locked before checking what the
+// catch is the first of there, so concurrent catches count new species and
+// users once.
+func (s *catchService) recordAtLocation(tx *gorm.DB, catch *models.AnimalCatch) error {
+	catchRepo := s.catchRepo.WithTx(tx)
+	locationRepo := s.locationRepo.WithTx(tx)
+
+	if _, err := locationRepo.GetForUpdate(catch.LocationID); err != nil {
+		return err
+	}
+	speciesSeen, err := catchRepo.HasPublicCatchAtLocation(catch.LocationID, catch.SpeciesID, uuid.Nil, catch.ID)
+	if err != nil {
+		return err
+	}
+	userSeen, err := catchRepo.HasPublicCatchAtLocation(catch.LocationID, uuid.Nil, catch.UserID, catch.ID)
+	if err != nil {
+		return err
+	}
+	return locationRepo.RecordCatch(catch.LocationID, !speciesSeen, !userSeen, catch.CaughtAt)
+}
+
+// catchNovelty records what a catch is the first of for its user. It is
+// checked just before the catch is inserted, with the user's stats locked.
+type catchNovelty struct {
+	species bool // User's first catch of the species
+	country bool // User's first catch in the location's country
+	city    bool // User's first catch in the location's city
+}
+
+func (s *catchService) checkNovelty(catchRepo *repositories.AnimalCatchRepository, catch *models.AnimalCatch, location *models.Location) (catchNovelty, error) {
+	var novelty catchNovelty
+	var err error
+
+	if novelty.species, err = catchRepo.IsFirstCatchForUser(catch.UserID, catch.SpeciesID); err != nil {
+		return novelty, err
+	}
+
+	if location.Country != "" {
+		countrySeen, err := catchRepo.HasCatchInPlace(catch.UserID, location.Country, "")
+		if err != nil {
+			return novelty, err
+		}
+		novelty.country = !countrySeen
+
+		if location.City != "" {
+			citySeen, err := catchRepo.HasCatchInPlace(catch.UserID, location.Country, location.City)
+			if err != nil {
+				return novelty, err
+			}
+			novelty.city = !citySeen
+		}
+	}
+
+	return novelty, nil
+}
+
+// existingCatch returns the user's catch with the given ID, nil if there is
+// none, or ErrCatchIDConflict if the ID belongs to someone else's catch
+func (s *catchService) existingCatch(catchRepo *repositories.AnimalCatchRepository, userID, catchID uuid.UUID) (*models.AnimalCatch, error) {
+	catch, err := catchRepo.GetByID(catchID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if catch.UserID != userID {
+		return nil, ErrCatchIDConflict
+	}
+	return catch, nil
+}
+
+// findOrCreateLocation reuses a known location within 100m or creates a new one.
+// Altitude and accuracy come from the photo unless its GPS fix is elsewhere.
+func (s *catchService) findOrCreateLocation(locationRepo *repositories.LocationRepository, lat, lng float64, zone string, photo *models.Photo, locationMismatch bool) (*models.Location, error) {
+	location := &models.Location{
+		Latitude:  lat,
+		Longitude: lng,
+		Timezone:  zone,
+	}
+	if !locationMismatch {
+		location.Altitude = photo.ExifAltitude
+		location.Accuracy = photo.ExifAccuracy
+	}
+
+	existingLocation, err := locationRepo.FindNearby(lat, lng, 0.1)
+	if err == nil && existingLocation != nil {
+		// Fill in details the existing location is missing
+		changed := false
+		if existingLocation.Altitude == nil && location.Altitude != nil {
+			existingLocation.Altitude = location.Altitude
+			existingLocation.Accuracy = location.Accuracy
+			changed = true
+		}
+		if existingLocation.Timezone == "" {
+			existingLocation.Timezone = zone
+			changed = true
+		}
+		if changed {
+			if err := locationRepo.Update(existingLocation); err != nil {
+				return nil, err
+			}
+		}
+		return existingLocation, nil
+	}
+
+	if err := locationRepo.Create(location); err != nil {
+		return nil, fmt.Errorf("failed to create location: %w", err)
+	}
+	return location, nil
+}
+
+// CheckDuplicatePhoto compares the photo's perceptual hash with every other catch.
+// It returns the caller's own catch if they already submitted the same photo, and
+// the closest catch by another user that the photo resembles.
+func (s *catchService) CheckDuplicatePhoto(userID uuid.UUID, photo *models.Photo, excludeID uuid.UUID) (own, other *repositories.SimilarPhotoCatch, err error) {
+	if photo.PerceptualHash == nil {
+		return nil, nil, nil
+	}
+
+	own, err = s.catchRepo.FindOwnSimilarPhoto(userID, *photo.PerceptualHash, ownDuplicateDistance, excludeID)
+	if err != nil || own != nil {
+		return own, nil, err
+	}
+	other, err = s.catchRepo.FindOthersSimilarPhoto(userID, *photo.PerceptualHash, otherUserDuplicateDistance, excludeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, other, nil
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"log"
+
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/events"
+)
+
+// NewEventBus creates the event bus selected by EVENT_BUS. Use "postgres" when
+// running more than one instance so events reach streams on every instance.
+func NewEventBus() events.Bus {
+	switch config.AppConfig.EventBus {
+	case "postgres":
+		return events.NewPostgresBus(config.DB, config.AppConfig.DatabaseURL)
+	case "local":
+		return events.NewLocalBus()
+	default:
+		log.Fatalf("Unknown event bus %q", config.AppConfig.EventBus)
+		return nil
+	}
+}
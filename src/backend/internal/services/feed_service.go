@@ -0,0 +1,260 @@
+package services
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// feedBackfillCatches is how many recent catches of a newly followed account
+// are added to the follower's feed
+const feedBackfillCatches = 20
+
+// feedRarityWeights scale how interesting a catch is by its species rarity
+var feedRarityWeights = map[models.Rarity]float64{
+	models.RarityCommon:    1,
+	models.RarityUncommon:  1.5,
+	models.RarityRare:      2.5,
+	models.RarityEpic:      4,
+	models.RarityLegendary: 6,
+}
+
+// FeedRanking tunes the feed. An item's weight is its reason weight times its
+// species rarity weight, and every doubling of the weight ranks it like an item
+// created HalfLife later. Changes apply to items written from then on.
+type FeedRanking struct {
+	HalfLife       time.Duration
+	ReasonWeights  map[models.FeedReason]float64
+	NearbyRadiusKm float64
+}
+
+// rankedAt returns the time an item for a catch created at createdAt is ranked at
+func (r FeedRanking) rankedAt(createdAt time.Time, reason models.FeedReason, rarity models.Rarity) time.Time {
+	weight := r.ReasonWeights[reason]
+	if rarityWeight, ok := feedRarityWeights[rarity]; ok {
+		weight *= rarityWeight
+	}
+	if weight <= 0 {
+		return createdAt
+	}
+	return createdAt.Add(time.Duration(math.Log2(weight) * float64(r.HalfLife)))
+}
+
+// FeedPosition is where the reader is, for nearby catches
+type FeedPosition struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// FeedEntry is a catch in a feed and why it is there
+type FeedEntry struct {
+	Reason models.FeedReason   `json:"reason"`
+	Catch  *models.AnimalCatch `json:"catch"`
+}
+
+type FeedService interface {
+	// Publish adds a newly created catch, with its species and location loaded,
+	// to the feeds of the author's followers and, if it is notable and public,
+	// to the shared rare or nearby items
+	Publish(catch *models.AnimalCatch) error
+	// Follow and Unfollow add or remove an account's catches from a follower's feed
+	Follow(followerID, followeeID uuid.UUID) error
+	Unfollow(followerID, followeeID uuid.UUID) error
+
+	// Get returns the user's feed: catches by the accounts they follow, rare
+	// catches and notable catches near position, best ranked first, starting
+	// after cursor. With no position the user's last known one is used. next is
+	// nil on the last page.
+	Get(userID uuid.UUID, position *FeedPosition, cursor *pagination.Cursor, limit int) (entries []FeedEntry, next *pagination.Cursor, err error)
+}
+
+type feedService struct {
+	feedRepo     *repositories.FeedRepository
+	catchRepo    *repositories.AnimalCatchRepository
+	locationRepo *repositories.LocationRepository
+	ranking      FeedRanking
+}
+
+func NewFeedService(feedRepo *repositories.FeedRepository, catchRepo *repositories.AnimalCatchRepository, locationRepo *repositories.LocationRepository, ranking FeedRanking) FeedService {
+	return &feedService{
+		feedRepo:     feedRepo,
+		catchRepo:    catchRepo,
+		locationRepo: locationRepo,
+		ranking:      ranking,
+	}
+}
+
+func (s *feedService) Publish(catch *models.AnimalCatch) error {
+	if catch.Visibility == models.VisibilityPrivate {
+		return nil
+	}
+	rarity := catch.Species.Rarity
+
+	rankedAt := s.ranking.rankedAt(catch.CreatedAt, models.FeedReasonFollowing, rarity)
+	if err := s.feedRepo.FanOutToFollowers(catch.ID, catch.UserID, rankedAt); err != nil {
+		return err
+	}
+
+	// Only catches anyone may see, at their exact place, are shared
+	if !catch.IsPublic {
+		return nil
+	}
+	var reason models.FeedReason
+	switch {
+	case rarity == models.RarityRare || rarity == models.RarityEpic || rarity == models.RarityLegendary:
+		reason = models.FeedReasonRare
+	case isNotable(catch):
+		reason = models.FeedReasonNearby
+	default:
+		return nil
+	}
+	return s.feedRepo.AddItems([]models.FeedItem{{
+		RecipientID: uuid.Nil,
+		CatchID:     catch.ID,
+		AuthorID:    catch.UserID,
+		Reason:      reason,
+		RankedAt:    s.ranking.rankedAt(catch.CreatedAt, reason, rarity),
+		Latitude:    catch.Location.Latitude,
+		Longitude:   catch.Location.Longitude,
+	}})
+}
+
+// isNotable returns true if a catch is worth showing to people nearby who
+// don't follow its author: an uncommon species, or one seldom seen in the region
+func isNotable(catch *models.AnimalCatch) bool {
+	if catch.Species.Rarity != models.RarityCommon {
+		return true
+	}
+	if catch.PointsBreakdown != nil {
+		for _, item := range catch.PointsBreakdown.Items {
+			if item.Component == models.PointsRegionalRarity {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *feedService) Follow(followerID, followeeID uuid.UUID) error {
+	catches, err := s.catchRepo.GetRecentVisible(followeeID, models.Viewer{UserID: followerID}, feedBackfillCatches)
+	if err != nil {
+		return err
+	}
+	items := make([]models.FeedItem, 0, len(catches))
+	for _, catch := range catches {
+		items = append(items, models.FeedItem{
+			RecipientID: followerID,
+			CatchID:     catch.ID,
+			AuthorID:    followeeID,
+			Reason:      models.FeedReasonFollowing,
+			RankedAt:    s.ranking.rankedAt(catch.CreatedAt, models.FeedReasonFollowing, catch.Species.Rarity),
+		})
+	}
+	return s.feedRepo.AddItems(items)
+}
+
+func (s *feedService) Unfollow(followerID, followeeID uuid.UUID) error {
+	return s.feedRepo.RemoveAuthor(followerID, followeeID)
+}
+
+func (s *feedService) Get(userID uuid.UUID, position *FeedPosition, cursor *pagination.Cursor, limit int) ([]FeedEntry, *pagination.Cursor, error) {
+	// Each source returns one extra item so we know whether there is more
+	items, err := s.feedRepo.GetFollowing(userID, cursor, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	rare, err := s.feedRepo.GetRare(userID, cursor, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	items = append(items, rare...)
+
+	position, err = s.resolvePosition(userID, position)
+	if err != nil {
+		return nil, nil, err
+	}
+	if position != nil {
+		nearby, err := s.feedRepo.GetNearby(userID, position.Latitude, position.Longitude, s.ranking.NearbyRadiusKm, cursor, limit+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, nearby...)
+	}
+
+	// Merge the sources; the first `limit` items of the merged order are all
+	// among what was fetched. The sources never share a catch, so the cursor
+	// can't bring one back from another source on a later page.
+	slices.SortFunc(items, func(a, b models.FeedItem) int {
+		if c := b.RankedAt.Compare(a.RankedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.CatchID[:], a.CatchID[:])
+	})
+
+	var next *pagination.Cursor
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		next = &pagination.Cursor{Time: last.RankedAt, ID: last.CatchID}
+	}
+
+	entries, err := s.loadEntries(userID, items)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, next, nil
+}
+
+// loadEntries loads the catches of the feed items, dropping those the user may
+// no longer see. Shared items also need the catch to be verified and still public.
+func (s *feedService) loadEntries(userID uuid.UUID, items []models.FeedItem) ([]FeedEntry, error) {
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		ids[i] = item.CatchID
+	}
+	catches, err := s.catchRepo.GetFeedCatches(ids, models.Viewer{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.AnimalCatch, len(catches))
+	for i := range catches {
+		byID[catches[i].ID] = &catches[i]
+	}
+
+	entries := make([]FeedEntry, 0, len(items))
+	for _, item := range items {
+		catch, ok := byID[item.CatchID]
+		if !ok {
+			continue
+		}
+		if item.Reason != models.FeedReasonFollowing && (!catch.IsVerified() || !catch.IsPublic) {
+			continue
+		}
+		entries = append(entries, FeedEntry{Reason: item.Reason, Catch: catch})
+	}
+	return entries, nil
+}
+
+// resolvePosition records a position the client sent, or falls back to the
+// user's last known one. It returns nil if the user has never shared one.
+func (s *feedService) resolvePosition(userID uuid.UUID, position *FeedPosition) (*FeedPosition, error) {
+	if position != nil {
+		if err := s.locationRepo.RecordUserPosition(userID, position.Latitude, position.Longitude); err != nil {
+			log.Printf("Failed to record position of user %s: %v", userID, err)
+		}
+		return position, nil
+	}
+
+	lat, lng, ok, err := s.locationRepo.GetLastKnownPosition(userID)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &FeedPosition{Latitude: lat, Longitude: lng}, nil
+}
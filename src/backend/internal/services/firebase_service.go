@@ -6,15 +6,19 @@ import (
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/auth"
+	"firebase.google.com/go/v4/messaging"
 	"google.golang.org/api/option"
 )
 
 type FirebaseService interface {
 	VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error)
 	GetUser(ctx context.Context, uid string) (*auth.UserRecord, error)
+	// Messaging returns a Cloud Messaging client using the same credentials
+	Messaging(ctx context.Context) (*messaging.Client, error)
 }
 
 type firebaseService struct {
+	app    *firebase.App
 	client *auth.Client
 }
 
@@ -47,6 +51,7 @@ func NewFirebaseService() FirebaseService {
 	}
 
 	return &firebaseService{
+		app:    app,
 		client: client,
 	}
 }
@@ -65,4 +70,8 @@ func (s *firebaseService) GetUser(ctx context.Context, uid string) (*auth.UserRe
 		return nil, err
 	}
 	return user, nil
-}
\ No newline at end of file
+}
+
+func (s *firebaseService) Messaging(ctx context.Context) (*messaging.Client, error) {
+	return s.app.Messaging(ctx)
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCannotFollowSelf      = errors.New("you cannot follow yourself")
+	ErrFollowRequestNotFound = errors.New("follow request not found")
+	ErrFollowerNotFound      = errors.New("user is not following you")
+	ErrFollowListHidden      = errors.New("this account is private")
+)
+
+// Relationship describes how the viewer and another user follow each other
+type Relationship struct {
+	Following  bool `json:"following"`   // The viewer follows the user
+	Requested  bool `json:"requested"`   // The viewer's follow awaits the user's approval
+	FollowedBy bool `json:"followed_by"` // The user follows the viewer
+	Mutual     bool `json:"mutual"`
+}
+
+// FollowEntry is an account in a follower, following or request list
+type FollowEntry struct {
+	User   *models.User `json:"user"`
+	Since  time.Time    `json:"since"`  // When the follow was accepted, or requested if pending
+	Mutual bool         `json:"mutual"` // The follow goes both ways
+}
+
+type FollowService interface {
+	// Follow follows followeeID, or requests to if their account is private.
+	// Following someone already followed or requested returns the existing follow.
+	Follow(followerID, followeeID uuid.UUID) (*models.Follow, error)
+	// Unfollow removes a follow or cancels a request; it is a no-op if there is none
+	Unfollow(followerID, followeeID uuid.UUID) error
+
+	ApproveRequest(userID, followerID uuid.UUID) error
+	RejectRequest(userID, followerID uuid.UUID) error
+	RemoveFollower(userID, followerID uuid.UUID) error
+	// AcceptAllPending approves every request, for when an account stops being private
+	AcceptAllPending(userID uuid.UUID) error
+
+	// Followers and Following list a user's accepted follows. The lists of a
+	// private account are only shown to the account, its followers and moderators.
+	Followers(viewer models.Viewer, userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]FollowEntry, *pagination.Cursor, error)
+	Following(viewer models.Viewer, userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]FollowEntry, *pagination.Cursor, error)
+	PendingRequests(userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]FollowEntry, *pagination.Cursor, error)
+
+	Relationship(viewerID, userID uuid.UUID) (*Relationship, error)
+	// CanViewCatch applies the catch's visibility, looking up whether the viewer
+	// follows its owner for followers-only catches
+	CanViewCatch(viewer models.Viewer, catch *models.AnimalCatch) (bool, error)
+}
+
+type followService struct {
+	userRepo    repositories.UserRepository
+	followRepo  *repositories.FollowRepository
+	feedService FeedService
+	publisher   events.Publisher
+}
+
+func NewFollowService(userRepo repositories.UserRepository, followRepo *repositories.FollowRepository, feedService FeedService, publisher events.Publisher) FollowService {
+	return &followService{
+		userRepo:    userRepo,
+		followRepo:  followRepo,
+		feedService: feedService,
+		publisher:   publisher,
+	}
+}
+
+func (s *followService) Follow(followerID, followeeID uuid.UUID) (*models.Follow, error) {
+	if followerID == followeeID {
+		return nil, ErrCannotFollowSelf
+	}
+	followee, err := s.findUser(followeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := models.FollowAccepted
+	if followee.IsPrivate {
+		status = models.FollowPending
+	}
+	follow, created, err := s.followRepo.Create(followerID, followeeID, status)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		if follow.IsAccepted() {
+			s.updateFeed(followerID, followeeID, true)
+		}
+		s.publishFollow(followeeID, followerID, follow.Status, false)
+	}
+	return follow, nil
+}
+
+func (s *followService) Unfollow(followerID, followeeID uuid.UUID) error {
+	deleted, err := s.followRepo.Delete(followerID, followeeID, "")
+	if err != nil {
+		return err
+	}
+	if deleted {
+		s.updateFeed(followerID, followeeID, false)
+	}
+	return nil
+}
+
+func (s *followService) ApproveRequest(userID, followerID uuid.UUID) error {
+	accepted, err := s.followRepo.Accept(followerID, userID)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return ErrFollowRequestNotFound
+	}
+	s.updateFeed(followerID, userID, true)
+	s.publishFollow(followerID, userID, models.FollowAccepted, true)
+	return nil
+}
+
+func (s *followService) RejectRequest(userID, followerID uuid.UUID) error {
+	deleted, err := s.followRepo.Delete(followerID, userID, models.FollowPending)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrFollowRequestNotFound
+	}
+	return nil
+}
+
+func (s *followService) RemoveFollower(userID, followerID uuid.UUID) error {
+	deleted, err := s.followRepo.Delete(followerID, userID, models.FollowAccepted)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrFollowerNotFound
+	}
+	s.updateFeed(followerID, userID, false)
+	return nil
+}
+
+func (s *followService) AcceptAllPending(userID uuid.UUID) error {
+	followerIDs, err := s.followRepo.AcceptAllPending(userID)
+	if err != nil {
+		return err
+	}
+	for _, followerID := range followerIDs {
+		s.updateFeed(followerID, userID, true)
+		s.publishFollow(followerID, userID, models.FollowAccepted, true)
+	}
+	return nil
+}
+
+// publishFollow tells recipientID about a follow by otherID, or that otherID
+// approved their request
+func (s *followService) publishFollow(recipientID, otherID uuid.UUID, status models.FollowStatus, approved bool) {
+	s.publisher.Publish(events.New(events.TypeFollow, recipientID, otherID, events.FollowData{
+		UserID:   otherID,
+		Status:   string(status),
+		Approved: approved,
+	}))
+}
+
+// updateFeed adds or removes the followee's catches from the follower's feed.
+// The feed is derived data, so a failure doesn't undo the follow.
+func (s *followService) updateFeed(followerID, followeeID uuid.UUID, following bool) {
+	var err error
+	if following {
+		err = s.feedService.Follow(followerID, followeeID)
+	} else {
+		err = s.feedService.Unfollow(followerID, followeeID)
+	}
+	if err != nil {
+		log.Printf("Failed to update feed of user %s for %s: %v", followerID, followeeID, err)
+	}
+}
+
+func (s *followService) Followers(viewer models.Viewer, userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]FollowEntry, *pagination.Cursor, error) {
+	if err := s.checkListVisible(viewer, userID); err != nil {
+		return nil, nil, err
+	}
+	follows, next, err := s.followRepo.GetFollowers(userID, cursor, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return followEntries(follows, func(f *models.Follow) *models.User { return f.Follower }), next, nil
+}
+
+func (s *followService) Following(viewer models.Viewer, userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]FollowEntry, *pagination.Cursor, error) {
+	if err := s.checkListVisible(viewer, userID); err != nil {
+		return nil, nil, err
+	}
+	follows, next, err := s.followRepo.GetFollowing(userID, cursor, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return followEntries(follows, func(f *models.Follow) *models.User { return f.Followee }), next, nil
+}
+
+func (s *followService) PendingRequests(userID uuid.UUID, cursor *pagination.Cursor, limit int) ([]FollowEntry, *pagination.Cursor, error) {
+	follows, next, err := s.followRepo.GetPendingRequests(userID, cursor, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return followEntries(follows, func(f *models.Follow) *models.User { return f.Follower }), next, nil
+}
+
+func (s *followService) Relationship(viewerID, userID uuid.UUID) (*Relationship, error) {
+	if _, err := s.findUser(userID); err != nil {
+		return nil, err
+	}
+
+	relationship := &Relationship{}
+	outgoing, err := s.followRepo.Get(viewerID, userID)
+	switch {
+	case err == nil:
+		relationship.Following = outgoing.IsAccepted()
+		relationship.Requested = !outgoing.IsAccepted()
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	relationship.FollowedBy, err = s.followRepo.IsFollowing(userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	relationship.Mutual = relationship.Following && relationship.FollowedBy
+	return relationship, nil
+}
+
+func (s *followService) CanViewCatch(viewer models.Viewer, catch *models.AnimalCatch) (bool, error) {
+	isFollower := false
+	if catch.Visibility == models.VisibilityFollowers && viewer.UserID != uuid.Nil && !viewer.Owns(catch) {
+		var err error
+		isFollower, err = s.followRepo.IsFollowing(viewer.UserID, catch.UserID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return catch.VisibleTo(viewer, isFollower), nil
+}
+
+// checkListVisible returns an error unless the viewer may see who the user
+// follows and is followed by
+func (s *followService) checkListVisible(viewer models.Viewer, userID uuid.UUID) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsPrivate || viewer.UserID == userID || viewer.Moderator {
+		return nil
+	}
+	if viewer.UserID != uuid.Nil {
+		following, err := s.followRepo.IsFollowing(viewer.UserID, userID)
+		if err != nil || following {
+			return err
+		}
+	}
+	return ErrFollowListHidden
+}
+
+func (s *followService) findUser(userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+func followEntries(follows []models.Follow, user func(*models.Follow) *models.User) []FollowEntry {
+	entries := make([]FollowEntry, 0, len(follows))
+	for i := range follows {
+		follow := &follows[i]
+		since := follow.CreatedAt
+		if follow.AcceptedAt != nil {
+			since = *follow.AcceptedAt
+		}
+		entries = append(entries, FollowEntry{
+			User:   user(follow),
+			Since:  since,
+			Mutual: follow.Mutual,
+		})
+	}
+	return entries
+}
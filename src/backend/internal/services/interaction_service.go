@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCatchNotFound        = errors.New("catch not found")
+	ErrCommentNotFound      = errors.New("comment not found")
+	ErrCommentEmpty         = errors.New("comment is empty")
+	ErrCommentEditForbidden = errors.New("only the author can edit a comment")
+	ErrCommentDelForbidden  = errors.New("only the author or the catch owner can delete a comment")
+)
+
+// LikeState is a catch's like count and whether the viewer likes it
+type LikeState struct {
+	Liked      bool `json:"liked"`
+	LikesCount int  `json:"likes_count"`
+}
+
+// InteractionService handles likes and comments. Only catches the viewer can
+// see may be liked or commented on; others are reported as ErrCatchNotFound.
+type InteractionService interface {
+	// Like and Unlike are idempotent: repeating them changes nothing
+	Like(viewer models.Viewer, catchID uuid.UUID) (*LikeState, error)
+	Unlike(viewer models.Viewer, catchID uuid.UUID) (*LikeState, error)
+
+	ListComments(viewer models.Viewer, catchID uuid.UUID, limit, offset int) ([]models.CatchComment, int64, error)
+	AddComment(viewer models.Viewer, catchID uuid.UUID, content string) (*models.CatchComment, error)
+	// EditComment is only allowed for the comment's author
+	EditComment(viewer models.Viewer, catchID, commentID uuid.UUID, content string) (*models.CatchComment, error)
+	// DeleteComment is allowed for the comment's author, the catch owner and moderators
+	DeleteComment(viewer models.Viewer, catchID, commentID uuid.UUID) error
+}
+
+type interactionService struct {
+	catchRepo       *repositories.AnimalCatchRepository
+	interactionRepo *repositories.CatchInteractionRepository
+	followService   FollowService
+	publisher       events.Publisher
+}
+
+func NewInteractionService(catchRepo *repositories.AnimalCatchRepository, interactionRepo *repositories.CatchInteractionRepository, followService FollowService, publisher events.Publisher) InteractionService {
+	return &interactionService{
+		catchRepo:       catchRepo,
+		interactionRepo: interactionRepo,
+		followService:   followService,
+		publisher:       publisher,
+	}
+}
+
+func (s *interactionService) Like(viewer models.Viewer, catchID uuid.UUID) (*LikeState, error) {
+	catch, err := s.visibleCatch(viewer, catchID)
+	if err != nil {
+		return nil, err
+	}
+	liked, err := s.interactionRepo.Like(catch, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.likeState(catch.ID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if liked && !viewer.Owns(catch) {
+		s.publisher.Publish(events.New(events.TypeLike, catch.UserID, viewer.UserID, events.LikeData{
+			CatchID:    catch.ID,
+			UserID:     viewer.UserID,
+			LikesCount: state.LikesCount,
+		}))
+	}
+	return state, nil
+}
+
+func (s *interactionService) Unlike(viewer models.Viewer, catchID uuid.UUID) (*LikeState, error) {
+	catch, err := s.visibleCatch(viewer, catchID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.interactionRepo.Unlike(catch, viewer.UserID); err != nil {
+		return nil, err
+	}
+	return s.likeState(catch.ID, false)
+}
+
+func (s *interactionService) likeState(catchID uuid.UUID, liked bool) (*LikeState, error) {
+	count, err := s.interactionRepo.GetLikesCount(catchID)
+	if err != nil {
+		return nil, err
+	}
+	return &LikeState{Liked: liked, LikesCount: count}, nil
+}
+
+func (s *interactionService) ListComments(viewer models.Viewer, catchID uuid.UUID, limit, offset int) ([]models.CatchComment, int64, error) {
+	if _, err := s.visibleCatch(viewer, catchID); err != nil {
+		return nil, 0, err
+	}
+	return s.interactionRepo.GetComments(catchID, limit, offset)
+}
+
+func (s *interactionService) AddComment(viewer models.Viewer, catchID uuid.UUID, content string) (*models.CatchComment, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrCommentEmpty
+	}
+
+	catch, err := s.visibleCatch(viewer, catchID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.CatchComment{UserID: viewer.UserID, Content: content}
+	if err := s.interactionRepo.CreateComment(catch, comment); err != nil {
+		return nil, err
+	}
+
+	if !viewer.Owns(catch) {
+		s.publisher.Publish(events.New(events.TypeComment, catch.UserID, viewer.UserID, events.CommentData{
+			CatchID:   catch.ID,
+			CommentID: comment.ID,
+			UserID:    viewer.UserID,
+			Content:   comment.Content,
+		}))
+	}
+	// Reload to include the author's profile
+	return s.interactionRepo.GetComment(catch.ID, comment.ID)
+}
+
+func (s *interactionService) EditComment(viewer models.Viewer, catchID, commentID uuid.UUID, content string) (*models.CatchComment, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrCommentEmpty
+	}
+
+	catch, err := s.visibleCatch(viewer, catchID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.comment(catch.ID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != viewer.UserID {
+		return nil, ErrCommentEditForbidden
+	}
+
+	if comment.Content != content {
+		if err := s.interactionRepo.UpdateCommentContent(comment, content); err != nil {
+			return nil, err
+		}
+	}
+	return comment, nil
+}
+
+func (s *interactionService) DeleteComment(viewer models.Viewer, catchID, commentID uuid.UUID) error {
+	catch, err := s.visibleCatch(viewer, catchID)
+	if err != nil {
+		return err
+	}
+	comment, err := s.comment(catch.ID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != viewer.UserID && !viewer.Owns(catch) && !viewer.Moderator {
+		return ErrCommentDelForbidden
+	}
+	return s.interactionRepo.DeleteComment(catch, comment)
+}
+
+// visibleCatch loads a catch the viewer is allowed to see
+func (s *interactionService) visibleCatch(viewer models.Viewer, catchID uuid.UUID) (*models.AnimalCatch, error) {
+	catch, err := s.catchRepo.GetByID(catchID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.followService.CanViewCatch(viewer, catch)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrCatchNotFound
+	}
+	return catch, nil
+}
+
+func (s *interactionService) comment(catchID, commentID uuid.UUID) (*models.CatchComment, error) {
+	comment, err := s.interactionRepo.GetComment(catchID, commentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCommentNotFound
+	}
+	return comment, err
+}
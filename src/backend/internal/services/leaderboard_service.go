@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const defaultRankBatchSize = 500
+
+var ErrLeaderboardRegionRequired = errors.New("country, and city for the city scope, are required when you have no home region")
+
+// LeaderboardOptions picks a leaderboard. Empty fields take the defaults: all-time
+// global points. Country and city default to the viewer's home region.
+type LeaderboardOptions struct {
+	Metric  models.LeaderboardMetric
+	Scope   models.LeaderboardScope
+	Window  models.LeaderboardWindow
+	Country string
+	City    string
+}
+
+// Leaderboard is a page of a leaderboard, or the part of it around a user
+type Leaderboard struct {
+	Metric  models.LeaderboardMetric  `json:"metric"`
+	Scope   models.LeaderboardScope   `json:"scope"`
+	Window  models.LeaderboardWindow  `json:"window"`
+	Country string                    `json:"country,omitempty"`
+	City    string                    `json:"city,omitempty"`
+	Since   *time.Time                `json:"since"` // Start of the window, null for all time
+	Entries []models.LeaderboardEntry `json:"entries"`
+	Total   int64                     `json:"total"` // Users on the board, for pages
+}
+
+// RankResult is what a ranking run changed
+type RankResult struct {
+	Updated int // Users whose ranks or home region changed
+}
+
+// LeaderboardService ranks users by points, species and streak, over all time
+// or the current month or week, globally or among users from the same country
+// or city. A user's home region is where most of their catches were made; it
+// and the all-time points ranks on their stats are set by RankUsers, which
+// runs on a schedule, so new users join regional boards after the next run.
+// Leaderboards themselves are worked out when asked for.
+type LeaderboardService interface {
+	// Leaderboard returns a page of a leaderboard. viewerID, which may be
+	// uuid.Nil, supplies the home region when the options leave it out.
+	Leaderboard(viewerID uuid.UUID, options LeaderboardOptions, page, limit int) (*Leaderboard, error)
+	// AroundUser returns the user's entry on a leaderboard with up to radius
+	// users either side. Entries is empty if the user isn't on the board.
+	AroundUser(userID uuid.UUID, options LeaderboardOptions, radius int) (*Leaderboard, error)
+	// RankUsers writes every user's ranks and home region, batchSize users per
+	// transaction
+	RankUsers(batchSize int) (*RankResult, error)
+}
+
+type leaderboardService struct {
+	leaderboardRepo *repositories.LeaderboardRepository
+	statsRepo       *repositories.UserStatsRepository
+	streakGrace     time.Duration // As in StreakConfig, so windowed streaks match users' stats
+}
+
+func NewLeaderboardService(leaderboardRepo *repositories.LeaderboardRepository, statsRepo *repositories.UserStatsRepository, streakGrace time.Duration) LeaderboardService {
+	return &leaderboardService{
+		leaderboardRepo: leaderboardRepo,
+		statsRepo:       statsRepo,
+		streakGrace:     streakGrace,
+	}
+}
+
+func (s *leaderboardService) Leaderboard(viewerID uuid.UUID, options LeaderboardOptions, page, limit int) (*Leaderboard, error) {
+	board, query, err := s.resolve(viewerID, options)
+	if err != nil {
+		return nil, err
+	}
+
+	board.Entries, board.Total, err = s.leaderboardRepo.GetPage(query, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+func (s *leaderboardService) AroundUser(userID uuid.UUID, options LeaderboardOptions, radius int) (*Leaderboard, error) {
+	board, query, err := s.resolve(userID, options)
+	if err != nil {
+		return nil, err
+	}
+
+	board.Entries, err = s.leaderboardRepo.GetAround(query, userID, radius)
+	if err != nil {
+		return nil, err
+	}
+	board.Total = int64(len(board.Entries))
+	return board, nil
+}
+
+func (s *leaderboardService) RankUsers(batchSize int) (*RankResult, error) {
+	if batchSize < 1 {
+		batchSize = defaultRankBatchSize
+	}
+
+	result := &RankResult{}
+	err := s.leaderboardRepo.EachChangedRanks(batchSize, func(batch []repositories.UserRanks) error {
+		if err := s.leaderboardRepo.SaveRanks(batch); err != nil {
+			return err
+		}
+		result.Updated += len(batch)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolve fills in the defaults of the options and the viewer's home region
+func (s *leaderboardService) resolve(viewerID uuid.UUID, options LeaderboardOptions) (*Leaderboard, repositories.LeaderboardQuery, error) {
+	board := &Leaderboard{
+		Metric: options.Metric,
+		Scope:  options.Scope,
+		Window: options.Window,
+	}
+	if board.Metric == "" {
+		board.Metric = models.LeaderboardPoints
+	}
+	if board.Scope == "" {
+		board.Scope = models.LeaderboardGlobal
+	}
+	if board.Window == "" {
+		board.Window = models.LeaderboardAllTime
+	}
+
+	if board.Scope != models.LeaderboardGlobal {
+		board.Country, board.City = options.Country, options.City
+		if board.Country == "" && viewerID != uuid.Nil {
+			stats, err := s.statsRepo.GetByUserID(viewerID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, repositories.LeaderboardQuery{}, err
+			}
+			if stats != nil {
+				board.Country, board.City = stats.HomeCountry, stats.HomeCity
+			}
+		}
+		if board.Scope == models.LeaderboardCountry {
+			board.City = ""
+		}
+		if board.Country == "" || (board.Scope == models.LeaderboardCity && board.City == "") {
+			return nil, repositories.LeaderboardQuery{}, ErrLeaderboardRegionRequired
+		}
+	}
+
+	board.Since = windowStart(board.Window, time.Now())
+	query := repositories.LeaderboardQuery{
+		Metric:  board.Metric,
+		Scope:   board.Scope,
+		Country: board.Country,
+		City:    board.City,
+		Grace:   s.streakGrace,
+	}
+	if board.Since != nil {
+		query.Since = *board.Since
+	}
+	return board, query, nil
+}
+
+// windowStart returns when the current month or week began in UTC, or nil for
+// all time
+func windowStart(window models.LeaderboardWindow, now time.Time) *time.Time {
+	year, month, day := now.UTC().Date()
+	var start time.Time
+	switch window {
+	case models.LeaderboardMonth:
+		start = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	case models.LeaderboardWeek:
+		// Weekdays count from Sunday; weeks start on Monday
+		sinceMonday := (int(now.UTC().Weekday()) + 6) % 7
+		start = time.Date(year, month, day-sinceMonday, 0, 0, 0, 0, time.UTC)
+	default:
+		return nil
+	}
+	return &start
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+const (
+	notificationQueueSize = 1000
+	notificationWorkers   = 2
+	pushTimeout           = 10 * time.Second
+	// notificationPreviewLength is how much of a comment is quoted in a notification
+	notificationPreviewLength = 100
+)
+
+var (
+	ErrNotificationNotFound    = errors.New("notification not found")
+	ErrInvalidNotificationType = errors.New("unknown notification type")
+)
+
+type NotificationService interface {
+	// Publish forwards the event to the real-time bus and, if it is addressed to
+	// a user and of a type they want, adds it to their inbox and pushes it to
+	// their devices. The inbox and push happen in the background.
+	events.Publisher
+
+	List(userID uuid.UUID, unreadOnly bool, cursor *pagination.Cursor, limit int) ([]models.Notification, *pagination.Cursor, error)
+	UnreadCount(userID uuid.UUID) (int64, error)
+	MarkRead(userID, notificationID uuid.UUID) error
+	MarkAllRead(userID uuid.UUID) (int64, error)
+
+	// Preferences returns the user's preference for every type, enabled unless
+	// they turned it off
+	Preferences(userID uuid.UUID) ([]models.NotificationPreference, error)
+	UpdatePreferences(userID uuid.UUID, preferences []models.NotificationPreference) ([]models.NotificationPreference, error)
+
+	RegisterDevice(userID uuid.UUID, req *models.RegisterDeviceRequest) error
+	UnregisterDevice(userID uuid.UUID, token string) error
+}
+
+type notificationService struct {
+	notificationRepo *repositories.NotificationRepository
+	userRepo         repositories.UserRepository
+	push             PushProvider
+	next             events.Publisher
+	queue            chan events.Event
+}
+
+// NewNotificationService starts the background workers that record and push
+// notifications. Events are passed on to next, the real-time bus.
+func NewNotificationService(notificationRepo *repositories.NotificationRepository, userRepo repositories.UserRepository, push PushProvider, next events.Publisher) NotificationService {
+	s := &notificationService{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		push:             push,
+		next:             next,
+		queue:            make(chan events.Event, notificationQueueSize),
+	}
+
+	for i := 0; i < notificationWorkers; i++ {
+		go s.worker()
+	}
+
+	return s
+}
+
+func (s *notificationService) Publish(event events.Event) {
+	s.next.Publish(event)
+
+	if event.RecipientID == uuid.Nil || !models.NotificationType(event.Type).IsValid() {
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		log.Printf("Notification queue full, dropping %s notification for user %s", event.Type, event.RecipientID)
+	}
+}
+
+func (s *notificationService) worker() {
+	for event := range s.queue {
+		if err := s.notify(event); err != nil {
+			log.Printf("Failed to notify user %s of %s: %v", event.RecipientID, event.Type, err)
+		}
+	}
+}
+
+// notify records the event in the recipient's inbox and pushes it, as far as
+// their preferences allow
+func (s *notificationService) notify(event events.Event) error {
+	notificationType := models.NotificationType(event.Type)
+	preference, err := s.notificationRepo.GetPreference(event.RecipientID, notificationType)
+	if err != nil {
+		return err
+	}
+	inApp, push := true, true
+	if preference != nil {
+		inApp, push = preference.InApp, preference.Push
+	}
+	if !inApp && !push {
+		return nil
+	}
+
+	title, body, err := s.render(event)
+	if err != nil {
+		return err
+	}
+	notification := &models.Notification{
+		UserID: event.RecipientID,
+		Type:   notificationType,
+		Title:  title,
+		Body:   body,
+		Data:   event.Data,
+	}
+	if event.ActorID != uuid.Nil {
+		actorID := event.ActorID
+		notification.ActorID = &actorID
+	}
+	if inApp {
+		if err := s.notificationRepo.Create(notification); err != nil {
+			return err
+		}
+	}
+	if push {
+		return s.pushNotification(notification)
+	}
+	return nil
+}
+
+func (s *notificationService) pushNotification(notification *models.Notification) error {
+	devices, err := s.notificationRepo.GetDeviceTokens(notification.UserID)
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+
+	data := map[string]string{
+		"type": string(notification.Type),
+		"data": string(notification.Data),
+	}
+	if notification.ID != uuid.Nil {
+		data["notification_id"] = notification.ID.String()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	invalid, err := s.push.Send(ctx, devices, PushMessage{
+		Title: notification.Title,
+		Body:  notification.Body,
+		Data:  data,
+	})
+	if len(invalid) > 0 {
+		if err := s.notificationRepo.DeleteDeviceTokens(invalid); err != nil {
+			log.Printf("Failed to remove unregistered push tokens: %v", err)
+		}
+	}
+	return err
+}
+
+// render returns the title and body shown for an event
+func (s *notificationService) render(event events.Event) (string, string, error) {
+	switch event.Type {
+	case events.TypeCatchVerified:
+		var data events.CatchVerifiedData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return "", "", err
+		}
+		if data.Status == string(models.VerificationRejected) {
+			return "Catch rejected", "One of your catches didn't pass verification.", nil
+		}
+		return "Catch verified", "One of your catches has been verified.", nil
+
+	case events.TypeBadgeEarned:
+		var data events.BadgeEarnedData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return "", "", err
+		}
+		return "Badge earned", fmt.Sprintf("You earned the %s badge!", data.Name), nil
+
+	case events.TypeLike:
+		return "New like", fmt.Sprintf("%s liked your catch.", s.actorName(event.ActorID)), nil
+
+	case events.TypeComment:
+		var data events.CommentData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return "", "", err
+		}
+		return "New comment", fmt.Sprintf("%s commented: %s", s.actorName(event.ActorID), preview(data.Content)), nil
+
+	case events.TypeStreakMilestone:
+		var data events.StreakMilestoneData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return "", "", err
+		}
+		body := fmt.Sprintf("You've caught something %d days in a row!", data.Days)
+		if data.FreezeEarned {
+			body += " You earned a streak freeze."
+		}
+		return "Streak milestone", body, nil
+
+	case events.TypeFollow:
+		var data events.FollowData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return "", "", err
+		}
+		name := s.actorName(data.UserID)
+		switch {
+		case data.Approved:
+			return "Follow request approved", fmt.Sprintf("%s approved your follow request.", name), nil
+		case data.Status == string(models.FollowPending):
+			return "Follow request", fmt.Sprintf("%s wants to follow you.", name), nil
+		default:
+			return "New follower", fmt.Sprintf("%s started following you.", name), nil
+		}
+	}
+	return "", "", fmt.Errorf("no notification for %s events", event.Type)
+}
+
+// actorName returns the username shown for the user who caused a notification
+func (s *notificationService) actorName(userID uuid.UUID) string {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return "Someone"
+	}
+	return user.Username
+}
+
+func preview(text string) string {
+	runes := []rune(text)
+	if len(runes) <= notificationPreviewLength {
+		return text
+	}
+	return string(runes[:notificationPreviewLength]) + "…"
+}
+
+func (s *notificationService) List(userID uuid.UUID, unreadOnly bool, cursor *pagination.Cursor, limit int) ([]models.Notification, *pagination.Cursor, error) {
+	return s.notificationRepo.List(userID, unreadOnly, cursor, limit)
+}
+
+func (s *notificationService) UnreadCount(userID uuid.UUID) (int64, error) {
+	return s.notificationRepo.CountUnread(userID)
+}
+
+func (s *notificationService) MarkRead(userID, notificationID uuid.UUID) error {
+	found, err := s.notificationRepo.MarkRead(userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *notificationService) MarkAllRead(userID uuid.UUID) (int64, error) {
+	return s.notificationRepo.MarkAllRead(userID)
+}
+
+func (s *notificationService) Preferences(userID uuid.UUID) ([]models.NotificationPreference, error) {
+	saved, err := s.notificationRepo.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	byType := make(map[models.NotificationType]models.NotificationPreference, len(saved))
+	for _, preference := range saved {
+		byType[preference.Type] = preference
+	}
+
+	preferences := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		preference, ok := byType[notificationType]
+		if !ok {
+			preference = models.NotificationPreference{UserID: userID, Type: notificationType, InApp: true, Push: true}
+		}
+		preferences = append(preferences, preference)
+	}
+	return preferences, nil
+}
+
+func (s *notificationService) UpdatePreferences(userID uuid.UUID, preferences []models.NotificationPreference) ([]models.NotificationPreference, error) {
+	// The last preference given for a type wins
+	byType := make(map[models.NotificationType]models.NotificationPreference, len(preferences))
+	for _, preference := range preferences {
+		if !preference.Type.IsValid() {
+			return nil, ErrInvalidNotificationType
+		}
+		preference.UserID = userID
+		byType[preference.Type] = preference
+	}
+	updated := make([]models.NotificationPreference, 0, len(byType))
+	for _, preference := range byType {
+		updated = append(updated, preference)
+	}
+	if err := s.notificationRepo.SavePreferences(updated); err != nil {
+		return nil, err
+	}
+	return s.Preferences(userID)
+}
+
+func (s *notificationService) RegisterDevice(userID uuid.UUID, req *models.RegisterDeviceRequest) error {
+	return s.notificationRepo.SaveDeviceToken(&models.DeviceToken{
+		UserID:   userID,
+		Token:    req.Token,
+		Platform: req.Platform,
+	})
+}
+
+func (s *notificationService) UnregisterDevice(userID uuid.UUID, token string) error {
+	return s.notificationRepo.DeleteDeviceToken(userID, token)
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// fakeUsers finds the users in its map and nobody else
+type fakeUsers struct {
+	repositories.UserRepository
+	users map[uuid.UUID]*models.User
+}
+
+func (f *fakeUsers) FindByID(id uuid.UUID) (*models.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestNotificationRender(t *testing.T) {
+	owl := uuid.New()
+	users := &fakeUsers{users: map[uuid.UUID]*models.User{owl: {ID: owl, Username: "owl_watcher"}}}
+	s := &notificationService{userRepo: users}
+	recipient := uuid.New()
+	long := strings.Repeat("é", notificationPreviewLength+1)
+
+	tests := []struct {
+		name      string
+		event     events.Event
+		wantTitle string
+		wantBody  string
+	}{
+		{"catch verified",
+			events.New(events.TypeCatchVerified, recipient, uuid.Nil, events.CatchVerifiedData{Status: string(models.VerificationApproved)}),
+			"Catch verified", "One of your catches has been verified."},
+		{"catch rejected",
+			events.New(events.TypeCatchVerified, recipient, uuid.Nil, events.CatchVerifiedData{Status: string(models.VerificationRejected)}),
+			"Catch rejected", "One of your catches didn't pass verification."},
+		{"badge earned",
+			events.New(events.TypeBadgeEarned, recipient, uuid.Nil, events.BadgeEarnedData{Name: "Night Owl"}),
+			"Badge earned", "You earned the Night Owl badge!"},
+		{"like",
+			events.New(events.TypeLike, recipient, owl, events.LikeData{UserID: owl}),
+			"New like", "owl_watcher liked your catch."},
+		{"like by an unknown user",
+			events.New(events.TypeLike, recipient, uuid.New(), events.LikeData{}),
+			"New like", "Someone liked your catch."},
+		{"comment",
+			events.New(events.TypeComment, recipient, owl, events.CommentData{Content: "What a find!"}),
+			"New comment", "owl_watcher commented: What a find!"},
+		{"long comment",
+			events.New(events.TypeComment, recipient, owl, events.CommentData{Content: long}),
+			"New comment", "owl_watcher commented: " + strings.Repeat("é", notificationPreviewLength) + "…"},
+		{"streak milestone",
+			events.New(events.TypeStreakMilestone, recipient, uuid.Nil, events.StreakMilestoneData{Days: 7}),
+			"Streak milestone", "You've caught something 7 days in a row!"},
+		{"streak milestone with a freeze",
+			events.New(events.TypeStreakMilestone, recipient, uuid.Nil, events.StreakMilestoneData{Days: 30, FreezeEarned: true}),
+			"Streak milestone", "You've caught something 30 days in a row! You earned a streak freeze."},
+		{"new follower",
+			events.New(events.TypeFollow, recipient, owl, events.FollowData{UserID: owl, Status: string(models.FollowAccepted)}),
+			"New follower", "owl_watcher started following you."},
+		{"follow request",
+			events.New(events.TypeFollow, recipient, owl, events.FollowData{UserID: owl, Status: string(models.FollowPending)}),
+			"Follow request", "owl_watcher wants to follow you."},
+		{"follow request approved",
+			events.New(events.TypeFollow, recipient, owl, events.FollowData{UserID: owl, Status: string(models.FollowAccepted), Approved: true}),
+			"Follow request approved", "owl_watcher approved your follow request."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, body, err := s.render(tt.event)
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+			if title != tt.wantTitle || body != tt.wantBody {
+				t.Errorf("render() = %q, %q, want %q, %q", title, body, tt.wantTitle, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNotificationRenderRejects(t *testing.T) {
+	s := &notificationService{userRepo: &fakeUsers{}}
+	badData := events.Event{Type: events.TypeBadgeEarned, RecipientID: uuid.New(), Data: json.RawMessage(`"not an object"`)}
+	tests := []struct {
+		name  string
+		event events.Event
+	}{
+		{"no notification for the type", events.NewNearby(events.TypeCatchCreated, uuid.New(), events.Position{}, events.CatchCreatedData{})},
+		{"malformed data", badData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := s.render(tt.event); err == nil {
+				t.Error("render() succeeded")
+			}
+		})
+	}
+}
+
+func TestLocalPushProviderUnregister(t *testing.T) {
+	provider := NewLocalPushProvider()
+	provider.Unregister("uninstalled")
+	devices := []models.DeviceToken{
+		{Token: "phone", Platform: models.PushPlatformAndroid},
+		{Token: "uninstalled", Platform: models.PushPlatformIOS},
+	}
+
+	invalid, err := provider.Send(context.Background(), devices, PushMessage{Title: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(invalid, []string{"uninstalled"}) {
+		t.Errorf("Send() invalid tokens = %v, want [uninstalled]", invalid)
+	}
+	if sent := provider.Sent(); len(sent) != 1 || sent[0].Token != "phone" {
+		t.Errorf("Sent() = %v, want one push to phone", sent)
+	}
+}
+
+// testTx returns a transaction on the database in TEST_DATABASE_URL, with the
+// notification tables, rolled back when the test ends. The test is skipped if
+// the variable isn't set.
+func testTx(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	if err := tx.AutoMigrate(&models.Notification{}, &models.NotificationPreference{}, &models.DeviceToken{}); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// newTestNotificationService returns a service running in tx that pushes
+// through a LocalPushProvider, without background workers
+func newTestNotificationService(tx *gorm.DB) (*notificationService, *LocalPushProvider) {
+	push := NewLocalPushProvider()
+	return &notificationService{
+		notificationRepo: repositories.NewNotificationRepository().WithTx(tx),
+		userRepo:         &fakeUsers{},
+		push:             push,
+		next:             events.NewLocalBus(),
+	}, push
+}
+
+func TestNotifyPreferences(t *testing.T) {
+	tx := testTx(t)
+
+	tests := []struct {
+		name       string
+		preference *models.NotificationPreference
+		wantInbox  bool
+		wantPush   bool
+	}{
+		{"no preference", nil, true, true},
+		{"both", &models.NotificationPreference{InApp: true, Push: true}, true, true},
+		{"inbox only", &models.NotificationPreference{InApp: true}, true, false},
+		{"push only", &models.NotificationPreference{Push: true}, false, true},
+		{"neither", &models.NotificationPreference{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, push := newTestNotificationService(tx)
+			userID := uuid.New()
+			if err := s.RegisterDevice(userID, &models.RegisterDeviceRequest{Token: "token-" + userID.String(), Platform: models.PushPlatformAndroid}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.preference != nil {
+				tt.preference.Type = models.NotificationBadgeEarned
+				if _, err := s.UpdatePreferences(userID, []models.NotificationPreference{*tt.preference}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			event := events.New(events.TypeBadgeEarned, userID, uuid.Nil, events.BadgeEarnedData{Name: "Night Owl"})
+			if err := s.notify(event); err != nil {
+				t.Fatalf("notify() error = %v", err)
+			}
+
+			inbox, _, err := s.List(userID, false, nil, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(inbox) == 1; got != tt.wantInbox {
+				t.Errorf("inbox has %d notifications, want it to have one: %v", len(inbox), tt.wantInbox)
+			}
+			sent := push.Sent()
+			if got := len(sent) == 1; got != tt.wantPush {
+				t.Fatalf("%d pushes sent, want one: %v", len(sent), tt.wantPush)
+			}
+			if tt.wantPush {
+				data := sent[0].Message.Data
+				if data["type"] != string(models.NotificationBadgeEarned) {
+					t.Errorf("push type = %q, want %s", data["type"], models.NotificationBadgeEarned)
+				}
+				// Pushes link to the inbox entry, if there is one
+				if _, linked := data["notification_id"]; linked != tt.wantInbox {
+					t.Errorf("push notification_id set = %v, want %v", linked, tt.wantInbox)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyRemovesUnregisteredTokens(t *testing.T) {
+	tx := testTx(t)
+	s, push := newTestNotificationService(tx)
+	userID := uuid.New()
+	for _, token := range []string{"phone-" + userID.String(), "uninstalled-" + userID.String()} {
+		if err := s.RegisterDevice(userID, &models.RegisterDeviceRequest{Token: token, Platform: models.PushPlatformAndroid}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push.Unregister("uninstalled-" + userID.String())
+
+	event := events.New(events.TypeLike, userID, uuid.New(), events.LikeData{})
+	if err := s.notify(event); err != nil {
+		t.Fatalf("notify() error = %v", err)
+	}
+
+	devices, err := s.notificationRepo.GetDeviceTokens(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].Token != "phone-"+userID.String() {
+		t.Errorf("devices left = %v, want only the phone", devices)
+	}
+	if sent := push.Sent(); len(sent) != 1 || sent[0].Token != "phone-"+userID.String() {
+		t.Errorf("Sent() = %v, want one push to the phone", sent)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/anidex/backend/internal/imaging"
+	"github.com/anidex/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Storage key prefixes. Only objects under PublicPhotoPrefix may be served to
+// clients; originals keep their EXIF data (including GPS) and stay private.
+const (
+	PublicPhotoPrefix   = "photos"
+	OriginalPhotoPrefix = "originals"
+)
+
+const (
+	maxPublicDimension  = 4096
+	blurHashXComponents = 4
+	blurHashYComponents = 3
+	maxUTCOffset        = 14 * time.Hour
+)
+
+// photoRendition describes one resized copy generated for every photo
+type photoRendition struct {
+	name   string
+	width  int
+	height int
+	crop   bool // Fill the exact size instead of fitting inside it
+}
+
+var photoRenditions = []photoRendition{
+	{name: "grid", width: 400, height: 400, crop: true},
+	{name: "feed", width: 1080, height: 1350},
+	{name: "detail", width: 2048, height: 2048},
+}
+
+func originalPhotoKey(userID, photoID uuid.UUID, ext string) string {
+	return fmt.Sprintf("%s/%s/%s%s", OriginalPhotoPrefix, userID, photoID, ext)
+}
+
+func publicPhotoKey(userID, photoID uuid.UUID, rendition string) string {
+	if rendition == "" {
+		return fmt.Sprintf("%s/%s/%s.jpg", PublicPhotoPrefix, userID, photoID)
+	}
+	return fmt.Sprintf("%s/%s/%s_%s.jpg", PublicPhotoPrefix, userID, photoID, rendition)
+}
+
+// process decodes the original image and writes the stripped public copy and
+// renditions to storage, filling in dimensions, blurhash and perceptual hash.
+// The photo is marked as uploaded and saved once everything is in place.
+func (s *photoService) process(ctx context.Context, photo *models.Photo, original []byte) error {
+	img, err := imaging.Decode(original, s.maxPixels)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return ErrPhotoTooLarge
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	// Re-encoding drops every metadata segment of the original
+	if err := s.putJPEG(ctx, publicPhotoKey(photo.UserID, photo.ID, ""), imaging.Fit(img, maxPublicDimension, maxPublicDimension)); err != nil {
+		return err
+	}
+
+	for _, rendition := range photoRenditions {
+		var resized *image.RGBA
+		if rendition.crop {
+			resized = imaging.Fill(img, rendition.width, rendition.height)
+		} else {
+			resized = imaging.Fit(img, rendition.width, rendition.height)
+		}
+
+		key := publicPhotoKey(photo.UserID, photo.ID, rendition.name)
+		if err := s.putJPEG(ctx, key, resized); err != nil {
+			return err
+		}
+
+		switch rendition.name {
+		case "grid":
+			photo.Renditions.Grid = s.storage.URL(key)
+		case "feed":
+			photo.Renditions.Feed = s.storage.URL(key)
+		case "detail":
+			photo.Renditions.Detail = s.storage.URL(key)
+		}
+	}
+
+	applyExifMetadata(photo, original)
+
+	hash := int64(imaging.PerceptualHash(img))
+	now := time.Now()
+
+	photo.Width = img.Bounds().Dx()
+	photo.Height = img.Bounds().Dy()
+	photo.BlurHash = imaging.BlurHash(img, blurHashXComponents, blurHashYComponents)
+	photo.PerceptualHash = &hash
+	photo.Size = int64(len(original))
+	photo.Status = models.PhotoUploaded
+	photo.ProcessedAt = &now
+
+	return s.photoRepo.Update(photo)
+}
+
+// applyExifMetadata copies the capture time and GPS fix of the original onto the photo
+func applyExifMetadata(photo *models.Photo, original []byte) {
+	exif, err := imaging.ParseExif(original)
+	if err != nil {
+		return
+	}
+
+	if taken, hasZone, ok := exif.DateTimeOriginal(); ok {
+		if !hasZone {
+			// The gap between the camera's wall clock and the UTC GPS fix is its UTC offset
+			if gpsTime, ok := exif.GPSTime(); ok {
+				offset := taken.Sub(gpsTime).Round(15 * time.Minute)
+				if offset.Abs() <= maxUTCOffset {
+					taken = gpsTime.In(time.FixedZone("", int(offset.Seconds())))
+					hasZone = true
+				}
+			}
+		}
+
+		if hasZone {
+			_, offset := taken.Zone()
+			photo.TakenAtOffset = &offset
+		}
+		photo.TakenAt = &taken
+	}
+
+	if lat, lng, ok := exif.GPSPosition(); ok {
+		photo.ExifLatitude = &lat
+		photo.ExifLongitude = &lng
+
+		if altitude, ok := exif.GPSAltitude(); ok {
+			photo.ExifAltitude = &altitude
+		}
+		if accuracy, ok := exif.GPSAccuracy(); ok {
+			photo.ExifAccuracy = &accuracy
+		}
+	}
+}
+
+func (s *photoService) putJPEG(ctx context.Context, key string, img image.Image) error {
+	data, err := imaging.EncodeJPEG(img)
+	if err != nil {
+		return err
+	}
+	return s.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg")
+}
+
+// readOriginal reads an upload body into memory, enforcing the size limit
+func (s *photoService) readOriginal(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, s.maxSize+1))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, ErrPhotoTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrPhotoTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const signedUploadExpiry = 15 * time.Minute
+
+var (
+	ErrPhotoNotFound    = errors.New("photo not found")
+	ErrPhotoNotOwned    = errors.New("photo belongs to another user")
+	ErrPhotoNotUploaded = errors.New("photo has not been uploaded yet")
+	ErrPhotoRequired    = errors.New("photo_id is required")
+	ErrUnsupportedImage = errors.New("only JPEG and PNG images are supported")
+	ErrPhotoTooLarge    = errors.New("photo exceeds the maximum upload size or resolution")
+)
+
+// allowedImageTypes maps accepted content types to the file extension used in storage
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// SignedUpload describes how the client should upload a photo directly to storage
+type SignedUpload struct {
+	Photo     *models.Photo     `json:"photo"`
+	UploadURL string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type PhotoService interface {
+	Upload(ctx context.Context, userID uuid.UUID, body io.Reader, size int64) (*models.Photo, error)
+	CreateSignedUpload(userID uuid.UUID, contentType string) (*SignedUpload, error)
+	ReceiveSignedUpload(ctx context.Context, key, contentType string, expires int64, signature string, body io.Reader, size int64) (*models.Photo, error)
+	CompleteUpload(ctx context.Context, userID, photoID uuid.UUID) (*models.Photo, error)
+	ResolveForCatch(userID uuid.UUID, photoID *uuid.UUID, photoURL string) (*models.Photo, error)
+}
+
+type photoService struct {
+	photoRepo *repositories.PhotoRepository
+	storage   StorageService
+	maxSize   int64
+	maxPixels int64
+}
+
+func NewPhotoService(photoRepo *repositories.PhotoRepository, storage StorageService, maxSize, maxPixels int64) PhotoService {
+	return &photoService{
+		photoRepo: photoRepo,
+		storage:   storage,
+		maxSize:   maxSize,
+		maxPixels: maxPixels,
+	}
+}
+
+// Upload stores a photo sent through the API and generates its public renditions
+func (s *photoService) Upload(ctx context.Context, userID uuid.UUID, body io.Reader, size int64) (*models.Photo, error) {
+	if size > s.maxSize {
+		return nil, ErrPhotoTooLarge
+	}
+
+	contentType, body, err := sniffImage(body)
+	if err != nil {
+		return nil, err
+	}
+	original, err := s.readOriginal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	photo := s.newPhoto(userID, contentType)
+	if err := s.storage.Put(ctx, photo.StorageKey, bytes.NewReader(original), int64(len(original)), contentType); err != nil {
+		return nil, err
+	}
+
+	if err := s.photoRepo.Create(photo); err != nil {
+		s.storage.Delete(ctx, photo.StorageKey)
+		return nil, err
+	}
+
+	if err := s.process(ctx, photo, original); err != nil {
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+// CreateSignedUpload reserves a photo and returns a URL the client can upload it to
+func (s *photoService) CreateSignedUpload(userID uuid.UUID, contentType string) (*SignedUpload, error) {
+	if _, ok := allowedImageTypes[contentType]; !ok {
+		return nil, ErrUnsupportedImage
+	}
+
+	photo := s.newPhoto(userID, contentType)
+	uploadURL, err := s.storage.SignedUploadURL(photo.StorageKey, contentType, signedUploadExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.photoRepo.Create(photo); err != nil {
+		return nil, err
+	}
+
+	return &SignedUpload{
+		Photo:     photo,
+		UploadURL: uploadURL,
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: time.Now().Add(signedUploadExpiry),
+	}, nil
+}
+
+// ReceiveSignedUpload handles a signed upload sent back to this API (local storage)
+func (s *photoService) ReceiveSignedUpload(ctx context.Context, key, contentType string, expires int64, signature string, body io.Reader, size int64) (*models.Photo, error) {
+	if err := s.storage.VerifyUploadSignature(key, contentType, expires, signature); err != nil {
+		return nil, err
+	}
+	if size > s.maxSize {
+		return nil, ErrPhotoTooLarge
+	}
+
+	photo, err := s.photoRepo.GetByStorageKey(key)
+	if err != nil {
+		return nil, ErrPhotoNotFound
+	}
+	if photo.IsUploaded() {
+		return photo, nil
+	}
+
+	sniffed, body, err := sniffImage(body)
+	if err != nil {
+		return nil, err
+	}
+	if sniffed != photo.ContentType {
+		return nil, ErrUnsupportedImage
+	}
+	original, err := s.readOriginal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Put(ctx, key, bytes.NewReader(original), int64(len(original)), photo.ContentType); err != nil {
+		return nil, err
+	}
+	if err := s.process(ctx, photo, original); err != nil {
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+// CompleteUpload confirms that a signed upload made straight to storage has landed
+// and generates its public renditions
+func (s *photoService) CompleteUpload(ctx context.Context, userID, photoID uuid.UUID) (*models.Photo, error) {
+	photo, err := s.getOwnedPhoto(userID, photoID)
+	if err != nil {
+		return nil, err
+	}
+	if photo.IsUploaded() {
+		return photo, nil
+	}
+
+	object, err := s.storage.Get(ctx, photo.StorageKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil, ErrPhotoNotUploaded
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	original, err := s.readOriginal(object)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.process(ctx, photo, original); err != nil {
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+// ResolveForCatch looks up the photo a catch refers to, either by ID or by URL,
+// and makes sure it was uploaded by the caller
+func (s *photoService) ResolveForCatch(userID uuid.UUID, photoID *uuid.UUID, photoURL string) (*models.Photo, error) {
+	var photo *models.Photo
+	var err error
+
+	switch {
+	case photoID != nil:
+		photo, err = s.photoRepo.GetByID(*photoID)
+	case photoURL != "":
+		photo, err = s.photoRepo.GetByURL(photoURL)
+	default:
+		return nil, ErrPhotoRequired
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPhotoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if photo.UserID != userID {
+		return nil, ErrPhotoNotOwned
+	}
+	if !photo.IsUploaded() {
+		return nil, ErrPhotoNotUploaded
+	}
+
+	return photo, nil
+}
+
+func (s *photoService) getOwnedPhoto(userID, photoID uuid.UUID) (*models.Photo, error) {
+	photo, err := s.photoRepo.GetByID(photoID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPhotoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if photo.UserID != userID {
+		return nil, ErrPhotoNotOwned
+	}
+	return photo, nil
+}
+
+func (s *photoService) newPhoto(userID uuid.UUID, contentType string) *models.Photo {
+	id := uuid.New()
+
+	return &models.Photo{
+		ID:          id,
+		UserID:      userID,
+		StorageKey:  originalPhotoKey(userID, id, allowedImageTypes[contentType]),
+		URL:         s.storage.URL(publicPhotoKey(userID, id, "")),
+		ContentType: contentType,
+		Status:      models.PhotoPending,
+	}
+}
+
+// sniffImage detects the content type from the first bytes of the body and
+// returns a reader that still yields the full body
+func sniffImage(body io.Reader) (string, io.Reader, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", nil, ErrUnsupportedImage
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if _, ok := allowedImageTypes[contentType]; !ok {
+		return "", nil, ErrUnsupportedImage
+	}
+
+	return contentType, io.MultiReader(bytes.NewReader(head), body), nil
+}
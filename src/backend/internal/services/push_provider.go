@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/anidex/backend/internal/config"
+	"github.com/anidex/backend/internal/models"
+)
+
+// fcmMaxTokens is how many tokens FCM accepts in one multicast
+const fcmMaxTokens = 500
+
+// PushMessage is a notification as shown on a device
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string // Delivered to the app alongside the notification
+}
+
+// PushProvider delivers push notifications to devices
+type PushProvider interface {
+	// Send pushes the message to the devices and returns the tokens the provider
+	// reported as no longer registered, which should be forgotten
+	Send(ctx context.Context, devices []models.DeviceToken, message PushMessage) (invalidTokens []string, err error)
+}
+
+// NewPushProvider creates the push provider selected by PUSH_PROVIDER
+func NewPushProvider(firebaseService FirebaseService) PushProvider {
+	switch config.AppConfig.PushProvider {
+	case "fcm":
+		client, err := firebaseService.Messaging(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to initialize Firebase Cloud Messaging client: %v", err)
+		}
+		return &fcmPushProvider{client: client}
+	case "local":
+		return NewLocalPushProvider()
+	default:
+		log.Fatalf("Unknown push provider %q", config.AppConfig.PushProvider)
+		return nil
+	}
+}
+
+// fcmPushProvider sends through Firebase Cloud Messaging, which relays to APNs
+// for iOS devices
+type fcmPushProvider struct {
+	client *messaging.Client
+}
+
+func (p *fcmPushProvider) Send(ctx context.Context, devices []models.DeviceToken, message PushMessage) ([]string, error) {
+	tokens := make([]string, len(devices))
+	for i, device := range devices {
+		tokens[i] = device.Token
+	}
+
+	var invalid []string
+	for start := 0; start < len(tokens); start += fcmMaxTokens {
+		batch := tokens[start:min(start+fcmMaxTokens, len(tokens))]
+		response, err := p.client.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+			Tokens: batch,
+			Data:   message.Data,
+			Notification: &messaging.Notification{
+				Title: message.Title,
+				Body:  message.Body,
+			},
+			Android: &messaging.AndroidConfig{Priority: "high"},
+			APNS: &messaging.APNSConfig{
+				Payload: &messaging.APNSPayload{Aps: &messaging.Aps{Sound: "default"}},
+			},
+		})
+		if err != nil {
+			return invalid, err
+		}
+		for i, result := range response.Responses {
+			if result.Error != nil && messaging.IsRegistrationTokenNotRegistered(result.Error) {
+				invalid = append(invalid, batch[i])
+			}
+		}
+	}
+	return invalid, nil
+}
+
+// SentPush is a message recorded by LocalPushProvider
+type SentPush struct {
+	Token   string
+	Message PushMessage
+}
+
+// LocalPushProvider logs pushes instead of sending them and keeps them for
+// inspection. It is used in development and in tests.
+type LocalPushProvider struct {
+	mu           sync.Mutex
+	sent         []SentPush
+	unregistered map[string]bool
+}
+
+func NewLocalPushProvider() *LocalPushProvider {
+	return &LocalPushProvider{}
+}
+
+func (p *LocalPushProvider) Send(ctx context.Context, devices []models.DeviceToken, message PushMessage) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var invalid []string
+	for _, device := range devices {
+		if p.unregistered[device.Token] {
+			invalid = append(invalid, device.Token)
+			continue
+		}
+		log.Printf("Push to %s device of user %s: %s - %s", device.Platform, device.UserID, message.Title, message.Body)
+		p.sent = append(p.sent, SentPush{Token: device.Token, Message: message})
+	}
+	return invalid, nil
+}
+
+// Unregister makes later sends report the tokens as no longer registered, like
+// FCM does for apps that were uninstalled
+func (p *LocalPushProvider) Unregister(tokens ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.unregistered == nil {
+		p.unregistered = map[string]bool{}
+	}
+	for _, token := range tokens {
+		p.unregistered[token] = true
+	}
+}
+
+// Sent returns the pushes sent so far
+func (p *LocalPushProvider) Sent() []SentPush {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]SentPush(nil), p.sent...)
+}
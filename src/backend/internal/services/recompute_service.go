@@ -0,0 +1,318 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/pagination"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/anidex/backend/internal/timezone"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const defaultRecomputePageSize = 1000
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+// RecomputeOptions controls how a user's stats and badges are recomputed
+type RecomputeOptions struct {
+	DryRun       bool // Work out the changes without saving them
+	RevokeBadges bool // Take back earned badges that are no longer complete
+	PageSize     int  // Catches loaded at a time
+}
+
+// StatChange is a stats field whose recomputed value differs from the saved one
+type StatChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// RecomputeResult is what recomputing a user changed, or would change on a
+// dry run
+type RecomputeResult struct {
+	UserID          uuid.UUID      `json:"user_id"`
+	Catches         int            `json:"catches"` // Catches replayed
+	StatChanges     []StatChange   `json:"stat_changes"`
+	Awarded         []models.Badge `json:"awarded"`
+	Revoked         []models.Badge `json:"revoked"`
+	ProgressChanged int            `json:"progress_changed"` // Badges, not awarded or revoked, whose progress moved
+}
+
+// Changed reports whether anything differs from what was saved
+func (r *RecomputeResult) Changed() bool {
+	return len(r.StatChanges) > 0 || len(r.Awarded) > 0 || len(r.Revoked) > 0 || r.ProgressChanged > 0
+}
+
+// RecomputeService rebuilds users' stats and badges from scratch by replaying
+// their catch history, for when badges or the rules behind them change after
+// users have caught things. Rejected catches don't count. Streak freezes held
+// are kept; rankings and the home region are left to the ranking job.
+type RecomputeService interface {
+	// RecomputeUser rebuilds one user's stats and badges in a transaction that
+	// holds the lock on their stats
+	RecomputeUser(userID uuid.UUID, options RecomputeOptions) (*RecomputeResult, error)
+}
+
+type recomputeService struct {
+	catchRepo       *repositories.AnimalCatchRepository
+	interactionRepo *repositories.CatchInteractionRepository
+	followRepo      *repositories.FollowRepository
+	statsRepo       *repositories.UserStatsRepository
+	badgeRepo       *repositories.BadgeRepository
+	badgeEngine     BadgeEngine
+	streakService   StreakService
+}
+
+func NewRecomputeService(catchRepo *repositories.AnimalCatchRepository, interactionRepo *repositories.CatchInteractionRepository, followRepo *repositories.FollowRepository, statsRepo *repositories.UserStatsRepository, badgeRepo *repositories.BadgeRepository, badgeEngine BadgeEngine, streakService StreakService) RecomputeService {
+	return &recomputeService{
+		catchRepo:       catchRepo,
+		interactionRepo: interactionRepo,
+		followRepo:      followRepo,
+		statsRepo:       statsRepo,
+		badgeRepo:       badgeRepo,
+		badgeEngine:     badgeEngine,
+		streakService:   streakService,
+	}
+}
+
+func (s *recomputeService) RecomputeUser(userID uuid.UUID, options RecomputeOptions) (*RecomputeResult, error) {
+	if options.PageSize < 1 {
+		options.PageSize = defaultRecomputePageSize
+	}
+	result := &RecomputeResult{UserID: userID}
+
+	err := repositories.Transaction(func(tx *gorm.DB) error {
+		statsRepo := s.statsRepo.WithTx(tx)
+		badgeRepo := s.badgeRepo.WithTx(tx)
+
+		saved, err := statsRepo.GetForUpdate(userID)
+		if err != nil {
+			return err
+		}
+		stats := &models.UserStats{
+			ID:            saved.ID,
+			UserID:        userID,
+			StreakFreezes: saved.StreakFreezes,
+			GlobalRank:    saved.GlobalRank,
+			CountryRank:   saved.CountryRank,
+			CityRank:      saved.CityRank,
+			HomeCountry:   saved.HomeCountry,
+			HomeCity:      saved.HomeCity,
+			CreatedAt:     saved.CreatedAt,
+		}
+
+		if result.Catches, err = s.replayCatches(tx, stats, options.PageSize); err != nil {
+			return err
+		}
+		// With the freezes that kept them going, which catches alone don't show
+		if err := s.streakService.Replay(tx, stats); err != nil {
+			return err
+		}
+		if err := s.countSocial(tx, stats); err != nil {
+			return err
+		}
+
+		current, err := badgeRepo.GetUserBadgesWithBadge(userID)
+		if err != nil {
+			return err
+		}
+		rebuilt, err := s.badgeEngine.Rebuild(tx, stats, current, options.RevokeBadges)
+		if err != nil {
+			return err
+		}
+		changed := s.diffBadges(result, stats, current, rebuilt)
+		result.StatChanges = statChanges(saved, stats)
+
+		if options.DryRun {
+			return errDryRun
+		}
+		for i := range changed {
+			if err := badgeRepo.SaveUserBadge(changed[i]); err != nil {
+				return err
+			}
+		}
+		if len(result.StatChanges) == 0 {
+			return nil
+		}
+		return statsRepo.Update(stats)
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// replayCatches feeds the user's catches into stats, oldest first, the way they
+// were recorded when made, and returns how many there were
+func (s *recomputeService) replayCatches(tx *gorm.DB, stats *models.UserStats, pageSize int) (int, error) {
+	catchRepo := s.catchRepo.WithTx(tx)
+	species := make(map[uuid.UUID]bool)
+	countries := make(map[string]bool)
+	cities := make(map[[2]string]bool)
+	zones := make(zoneCache)
+	var home *models.Location // Where the first catch was made
+
+	replayed := 0
+	var after *pagination.Cursor
+	for {
+		catches, err := catchRepo.GetHistoryPage(stats.UserID, after, pageSize)
+		if err != nil {
+			return 0, err
+		}
+
+		for i := range catches {
+			catch := &catches[i]
+			newSpecies := !species[catch.SpeciesID]
+			species[catch.SpeciesID] = true
+			stats.RecordCatch(&catch.Species, catch.PointsAwarded, newSpecies, catch.CaughtAt.In(zones.zone(&catch.Location)))
+			if home == nil {
+				home = &catch.Location
+			}
+			stats.RecordDistance(home.DistanceTo(&catch.Location))
+
+			if country := catch.Location.Country; country != "" {
+				if !countries[country] {
+					countries[country] = true
+					stats.CountriesVisited++
+				}
+				if city := [2]string{country, catch.Location.City}; city[1] != "" && !cities[city] {
+					cities[city] = true
+					stats.CitiesVisited++
+				}
+			}
+		}
+
+		replayed += len(catches)
+		if len(catches) < pageSize {
+			return replayed, nil
+		}
+		last := catches[len(catches)-1]
+		after = &pagination.Cursor{Time: last.CaughtAt, ID: last.ID}
+	}
+}
+
+// countSocial sets the likes, comments and follows the user has
+func (s *recomputeService) countSocial(tx *gorm.DB, stats *models.UserStats) error {
+	likes, comments, err := s.interactionRepo.WithTx(tx).CountReceived(stats.UserID)
+	if err != nil {
+		return err
+	}
+	followers, following, err := s.followRepo.WithTx(tx).CountAccepted(stats.UserID)
+	if err != nil {
+		return err
+	}
+	stats.TotalLikes = int(likes)
+	stats.TotalComments = int(comments)
+	stats.FollowersCount = int(followers)
+	stats.FollowingCount = int(following)
+	return nil
+}
+
+// diffBadges records in result how the rebuilt badges differ from the current
+// ones, adds the earned badges to stats, and returns the records to save
+func (s *recomputeService) diffBadges(result *RecomputeResult, stats *models.UserStats, current, rebuilt []models.UserBadge) []*models.UserBadge {
+	byBadge := make(map[uuid.UUID]*models.UserBadge, len(current))
+	for i := range current {
+		byBadge[current[i].BadgeID] = &current[i]
+	}
+
+	var changed []*models.UserBadge
+	for i := range rebuilt {
+		userBadge := &rebuilt[i]
+		if userBadge.IsEarned() {
+			stats.BadgesEarned++
+			stats.TotalPoints += userBadge.Badge.PointsAwarded
+		}
+
+		before := byBadge[userBadge.BadgeID]
+		wasEarned := before != nil && before.IsEarned()
+		switch {
+		case userBadge.IsEarned() && !wasEarned:
+			result.Awarded = append(result.Awarded, userBadge.Badge)
+		case !userBadge.IsEarned() && wasEarned:
+			result.Revoked = append(result.Revoked, userBadge.Badge)
+		case before != nil && before.Progress == userBadge.Progress && before.MaxProgress == userBadge.MaxProgress:
+			continue
+		default:
+			result.ProgressChanged++
+		}
+		changed = append(changed, userBadge)
+	}
+	return changed
+}
+
+// statChanges lists the fields of the recomputed stats that differ from before
+func statChanges(before, after *models.UserStats) []StatChange {
+	var changes []StatChange
+	compare := func(field string, from, to interface{}) {
+		if from != to {
+			changes = append(changes, StatChange{Field: field, Before: from, After: to})
+		}
+	}
+	compare("total_catches", before.TotalCatches, after.TotalCatches)
+	compare("unique_species", before.UniqueSpecies, after.UniqueSpecies)
+	compare("total_points", before.TotalPoints, after.TotalPoints)
+	compare("current_streak", before.CurrentStreak, after.CurrentStreak)
+	compare("longest_streak", before.LongestStreak, after.LongestStreak)
+	compare("last_catch_date", formatDay(before.LastCatchDate), formatDay(after.LastCatchDate))
+	compare("last_streak_date", formatDay(before.LastStreakDate), formatDay(after.LastStreakDate))
+	compare("common_catches", before.CommonCatches, after.CommonCatches)
+	compare("uncommon_catches", before.UncommonCatches, after.UncommonCatches)
+	compare("rare_catches", before.RareCatches, after.RareCatches)
+	compare("epic_catches", before.EpicCatches, after.EpicCatches)
+	compare("legendary_catches", before.LegendaryCatches, after.LegendaryCatches)
+	compare("mammal_catches", before.MammalCatches, after.MammalCatches)
+	compare("bird_catches", before.BirdCatches, after.BirdCatches)
+	compare("reptile_catches", before.ReptileCatches, after.ReptileCatches)
+	compare("amphibian_catches", before.AmphibianCatches, after.AmphibianCatches)
+	compare("fish_catches", before.FishCatches, after.FishCatches)
+	compare("insect_catches", before.InsectCatches, after.InsectCatches)
+	compare("other_catches", before.OtherCatches, after.OtherCatches)
+	compare("countries_visited", before.CountriesVisited, after.CountriesVisited)
+	compare("cities_visited", before.CitiesVisited, after.CitiesVisited)
+	// Distances are worked out in SQL and in Go, which may differ in the last digits
+	compare("farthest_distance", roundKm(before.FarthestDistance), roundKm(after.FarthestDistance))
+	compare("badges_earned", before.BadgesEarned, after.BadgesEarned)
+	compare("followers_count", before.FollowersCount, after.FollowersCount)
+	compare("following_count", before.FollowingCount, after.FollowingCount)
+	compare("total_likes", before.TotalLikes, after.TotalLikes)
+	compare("total_comments", before.TotalComments, after.TotalComments)
+	return changes
+}
+
+func roundKm(km float64) float64 {
+	return math.Round(km*1000) / 1000
+}
+
+func formatDay(day *time.Time) string {
+	if day == nil {
+		return ""
+	}
+	return day.UTC().Format("2006-01-02")
+}
+
+// zoneCache resolves the time zones of catch locations, loading each named zone once
+type zoneCache map[string]*time.Location
+
+func (c zoneCache) zone(location *models.Location) *time.Location {
+	if location.ID == uuid.Nil {
+		return time.UTC
+	}
+	if location.Timezone == "" {
+		return timezone.Lookup(location.Latitude, location.Longitude)
+	}
+	if zone, ok := c[location.Timezone]; ok {
+		return zone
+	}
+	zone, err := time.LoadLocation(location.Timezone)
+	if err != nil {
+		return timezone.Lookup(location.Latitude, location.Longitude)
+	}
+	c[location.Timezone] = zone
+	return zone
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+)
+
+// Scoring rules. Additive bonuses are a share of the species points so they
+// scale with how valuable the species already is.
+const (
+	firstCatchMultiplier = 1.5
+
+	streakStepMultiplier = 0.1 // Added per consecutive day after the first
+	maxStreakMultiplier  = 2.0
+
+	regionalRarityRadiusKm  = 50
+	regionalRareSightings   = 5   // Fewer earlier sightings than this count as rare in the region
+	regionalFirstBonusShare = 1.0 // First recorded sighting in the region
+	regionalRareBonusShare  = 0.5
+)
+
+// conservationBonusShares is the share of the species points added for threatened species
+var conservationBonusShares = map[models.ConservationStatus]float64{
+	models.StatusNearThreatened:       0.1,
+	models.StatusVulnerable:           0.25,
+	models.StatusEndangered:           0.5,
+	models.StatusCriticallyEndangered: 1.0,
+	models.StatusExtinctInWild:        1.0,
+}
+
+// ScoreInput describes a catch about to be recorded
+type ScoreInput struct {
+	Species      *models.Species
+	Location     *models.Location
+	IsFirstCatch bool
+	StreakDays   int // Length of the daily streak the catch extends, 0 if none
+}
+
+type ScoringService interface {
+	// Score computes the itemized points for a catch. Breakdown.Total is the
+	// amount to award and Breakdown.Multiplier the combined combo multiplier.
+	Score(input ScoreInput) (*models.PointsBreakdown, error)
+}
+
+type scoringService struct {
+	catchRepo *repositories.AnimalCatchRepository
+}
+
+func NewScoringService(catchRepo *repositories.AnimalCatchRepository) ScoringService {
+	return &scoringService{
+		catchRepo: catchRepo,
+	}
+}
+
+func (s *scoringService) Score(input ScoreInput) (*models.PointsBreakdown, error) {
+	breakdown := &models.PointsBreakdown{}
+
+	speciesPoints := input.Species.CalculatePoints()
+	breakdown.AddPoints(models.PointsSpecies,
+		fmt.Sprintf("%s (%s, difficulty %d)", input.Species.CommonName, input.Species.Rarity, input.Species.DifficultyLevel),
+		speciesPoints)
+
+	sightings, err := s.catchRepo.CountSpeciesNear(input.Species.ID, input.Location.Latitude, input.Location.Longitude, regionalRarityRadiusKm)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case sightings == 0:
+		breakdown.AddPoints(models.PointsRegionalRarity, "First sighting in this region", share(speciesPoints, regionalFirstBonusShare))
+	case sightings < regionalRareSightings:
+		breakdown.AddPoints(models.PointsRegionalRarity,
+			fmt.Sprintf("Only %d earlier sightings in this region", sightings),
+			share(speciesPoints, regionalRareBonusShare))
+	}
+
+	if bonus, ok := conservationBonusShares[input.Species.ConservationStatus]; ok {
+		breakdown.AddPoints(models.PointsConservation,
+			fmt.Sprintf("Conservation status %s", input.Species.ConservationStatus),
+			share(speciesPoints, bonus))
+	}
+
+	if input.IsFirstCatch {
+		breakdown.AddMultiplier(models.PointsFirstCatch, "First catch of this species", firstCatchMultiplier)
+	}
+
+	if input.StreakDays > 1 {
+		breakdown.AddMultiplier(models.PointsStreak, fmt.Sprintf("%d day streak", input.StreakDays), streakMultiplier(input.StreakDays))
+	}
+
+	breakdown.Compute()
+	return breakdown, nil
+}
+
+// withFirstCatchBonus returns a copy of the breakdown of a catch, scored when it
+// wasn't the user's first of its species, with the first-catch bonus applied.
+// Catches scored before breakdowns were kept get one from their points.
+func withFirstCatchBonus(breakdown *models.PointsBreakdown, points int) *models.PointsBreakdown {
+	bonused := &models.PointsBreakdown{}
+	if breakdown != nil {
+		bonused.Items = slices.Clone(breakdown.Items)
+	} else {
+		bonused.AddPoints(models.PointsSpecies, "Points awarded", points)
+	}
+	bonused.AddMultiplier(models.PointsFirstCatch, "First catch of this species", firstCatchMultiplier)
+	bonused.Compute()
+	return bonused
+}
+
+func streakMultiplier(days int) float64 {
+	multiplier := math.Round((1+float64(days-1)*streakStepMultiplier)*100) / 100
+	return math.Min(multiplier, maxStreakMultiplier)
+}
+
+// share returns the given share of points, rounded, and at least one point
+func share(points int, fraction float64) int {
+	return max(1, int(float64(points)*fraction+0.5))
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/anidex/backend/internal/config"
@@ -153,6 +154,15 @@ func (s *SeederService) SeedBadges() error {
 		// Check if badge already exists
 		var existing models.Badge
 		if err := s.db.Where("name = ?", badge.Name).First(&existing).Error; err == nil {
+			// Give existing badges without a rule the seeded one, leaving rules
+			// changed since alone
+			err := s.db.Model(&existing).
+				Where("rule IS NULL").
+				Select("rule").
+				Updates(&badge).Error
+			if err != nil {
+				return fmt.Errorf("failed to update badge %s: %w", badge.Name, err)
+			}
 			log.Printf("Badge %s already exists, skipping...", badge.Name)
 			continue
 		}
@@ -206,19 +216,22 @@ func (s *SeederService) SeedSampleData() error {
 		}
 	}
 
-	// Create user stats for sample users
+	// Award some sample badges
+	if err := s.createSampleBadges(users); err != nil {
+		return fmt.Errorf("failed to create sample badges: %w", err)
+	}
+
+	// Create user stats for sample users from their catches and badges
 	for _, user := range users {
-		stats := s.createUserStats(user.ID)
+		stats, err := s.createUserStats(user.ID, catches, species, locations)
+		if err != nil {
+			return fmt.Errorf("failed to compute user stats: %w", err)
+		}
 		if err := s.db.Create(&stats).Error; err != nil {
 			return fmt.Errorf("failed to create user stats: %w", err)
 		}
 	}
 
-	// Award some sample badges
-	if err := s.createSampleBadges(users); err != nil {
-		return fmt.Errorf("failed to create sample badges: %w", err)
-	}
-
 	log.Printf("✅ Successfully created sample data: %d users, %d catches", len(users), len(catches))
 	return nil
 }
@@ -245,6 +258,7 @@ func (s *SeederService) createSampleUsers() []models.User {
 			Name:     "Sarah Green",
 			Avatar:   "https://example.com/avatars/sarah.jpg",
 			Provider: models.AuthProviderLocal,
+			Role:     models.RoleModerator,
 		},
 		{
 			Email:    "david.naturalist@example.com",
@@ -314,32 +328,76 @@ func (s *SeederService) createSampleCatches(users []models.User, species []model
 	return catches
 }
 
-func (s *SeederService) createUserStats(userID uuid.UUID) models.UserStats {
-	return models.UserStats{
-		UserID:             userID,
-		TotalCatches:       4, // Average catches per user
-		UniqueSpecies:      4,
-		TotalPoints:        350,
-		CurrentStreak:      2,
-		LongestStreak:      3,
-		LastCatchDate:      timePtr(time.Now().Add(-24 * time.Hour)),
-		CommonCatches:      2,
-		UncommonCatches:    1,
-		RareCatches:        1,
-		EpicCatches:        0,
-		LegendaryCatches:   0,
-		MammalCatches:      2,
-		BirdCatches:        1,
-		ReptileCatches:     1,
-		CountriesVisited:   2,
-		CitiesVisited:      3,
-		BadgesEarned:       2,
-		FollowersCount:     5,
-		FollowingCount:     8,
-		TotalLikes:         15,
-		TotalComments:      8,
-		LastUpdated:        time.Now(),
+// createUserStats works out a sample user's stats from their sample catches,
+// oldest first, and the badges they were awarded
+func (s *SeederService) createUserStats(userID uuid.UUID, catches []models.AnimalCatch, species []models.Species, locations []models.Location) (models.UserStats, error) {
+	stats := models.UserStats{UserID: userID, LastUpdated: time.Now()}
+
+	var owned []models.AnimalCatch
+	for _, catch := range catches {
+		if catch.UserID == userID {
+			owned = append(owned, catch)
+		}
 	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].CaughtAt.Before(owned[j].CaughtAt) })
+
+	seenSpecies := make(map[uuid.UUID]bool)
+	countries := make(map[string]bool)
+	cities := make(map[[2]string]bool)
+	var home *models.Location
+	for _, catch := range owned {
+		catchSpecies := findSpecies(species, catch.SpeciesID)
+		location := findLocation(locations, catch.LocationID)
+		if catchSpecies == nil || location == nil {
+			continue
+		}
+
+		stats.RecordCatch(catchSpecies, catch.PointsAwarded, !seenSpecies[catch.SpeciesID], catch.CaughtAt)
+		seenSpecies[catch.SpeciesID] = true
+		if home == nil {
+			home = location
+		}
+		stats.RecordDistance(home.DistanceTo(location))
+
+		if location.Country != "" {
+			if !countries[location.Country] {
+				countries[location.Country] = true
+				stats.CountriesVisited++
+			}
+			if city := [2]string{location.Country, location.City}; city[1] != "" && !cities[city] {
+				cities[city] = true
+				stats.CitiesVisited++
+			}
+		}
+	}
+
+	var earned []models.UserBadge
+	if err := s.db.Preload("Badge").Where("user_id = ? AND earned_at IS NOT NULL", userID).Find(&earned).Error; err != nil {
+		return stats, err
+	}
+	for _, userBadge := range earned {
+		stats.BadgesEarned++
+		stats.TotalPoints += userBadge.Badge.PointsAwarded
+	}
+	return stats, nil
+}
+
+func findSpecies(species []models.Species, id uuid.UUID) *models.Species {
+	for i := range species {
+		if species[i].ID == id {
+			return &species[i]
+		}
+	}
+	return nil
+}
+
+func findLocation(locations []models.Location, id uuid.UUID) *models.Location {
+	for i := range locations {
+		if locations[i].ID == id {
+			return &locations[i]
+		}
+	}
+	return nil
 }
 
 func (s *SeederService) createSampleBadges(users []models.User) error {
@@ -359,8 +417,8 @@ func (s *SeederService) createSampleBadges(users []models.User) error {
 				userBadge := models.UserBadge{
 					UserID:      user.ID,
 					BadgeID:     badge.ID,
-					Progress:    getProgressValue(badge.RequiredCount),
-					MaxProgress: getProgressValue(badge.RequiredCount),
+					Progress:    getProgressValue(badge.Rule),
+					MaxProgress: getProgressValue(badge.Rule),
 					EarnedAt:    timePtr(time.Now().Add(-time.Duration(j*24) * time.Hour)),
 					IsDisplayed: true,
 				}
@@ -439,9 +497,12 @@ func timePtr(t time.Time) *time.Time {
 }
 
 // Helper function for progress values
-func getProgressValue(requiredCount *int) int {
-	if requiredCount != nil {
-		return *requiredCount
+func getProgressValue(rule *models.BadgeRule) int {
+	total := 0
+	if rule != nil {
+		for _, requirement := range rule.Requirements {
+			total += requirement.Target
+		}
 	}
-	return 1
+	return max(total, 1)
 }
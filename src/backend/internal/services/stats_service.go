@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RarityCatches counts a user's catches by species rarity
+type RarityCatches struct {
+	Common    int `json:"common"`
+	Uncommon  int `json:"uncommon"`
+	Rare      int `json:"rare"`
+	Epic      int `json:"epic"`
+	Legendary int `json:"legendary"`
+}
+
+// CategoryCatches counts a user's catches by animal category
+type CategoryCatches struct {
+	Mammal    int `json:"mammal"`
+	Bird      int `json:"bird"`
+	Reptile   int `json:"reptile"`
+	Amphibian int `json:"amphibian"`
+	Fish      int `json:"fish"`
+	Insect    int `json:"insect"`
+	Other     int `json:"other"`
+}
+
+// UserStatsSummary is a user's stats as shown on their profile, with the level
+// their points reach
+type UserStatsSummary struct {
+	UserID            uuid.UUID       `json:"user_id"`
+	Level             int             `json:"level"`
+	PointsToNextLevel int             `json:"points_to_next_level"`
+	TotalPoints       int             `json:"total_points"`
+	TotalCatches      int             `json:"total_catches"`
+	UniqueSpecies     int             `json:"unique_species"`
+	BadgesEarned      int             `json:"badges_earned"`
+	CurrentStreak     int             `json:"current_streak"`
+	LongestStreak     int             `json:"longest_streak"`
+	LastCatchDate     *time.Time      `json:"last_catch_date"`
+	StreakFreezes     int             `json:"streak_freezes"`
+	Rarity            RarityCatches   `json:"rarity"`
+	Categories        CategoryCatches `json:"categories"`
+	CountriesVisited  int             `json:"countries_visited"`
+	CitiesVisited     int             `json:"cities_visited"`
+	FarthestDistance  float64         `json:"farthest_distance"` // km from where the user's first catch was made
+	FollowersCount    int             `json:"followers_count"`
+	FollowingCount    int             `json:"following_count"`
+	TotalLikes        int             `json:"total_likes"`
+	TotalComments     int             `json:"total_comments"`
+	GlobalRank        *int            `json:"global_rank"`
+	CountryRank       *int            `json:"country_rank"`
+	CityRank          *int            `json:"city_rank"`
+	HomeCountry       string          `json:"home_country"`
+	HomeCity          string          `json:"home_city"`
+	LastUpdated       *time.Time      `json:"last_updated"` // Null for users who haven't done anything yet
+}
+
+// StatsService serves users' stats and keeps the parts that depend on their
+// catch history correct. Catches are added to the stats by CatchService as they
+// are created; catches that stop counting, deleted or rejected, are taken out
+// here, along with the badges they earned. Rejected catches don't count, as in
+// RecomputeService.
+type StatsService interface {
+	// Get returns the user's stats
+	Get(userID uuid.UUID) (*UserStatsSummary, error)
+	// RecordDistance raises the farthest distance from home in the stats, locked
+	// by the caller in tx, for a catch just inserted in tx at location
+	RecordDistance(tx *gorm.DB, stats *models.UserStats, catch *models.AnimalCatch, location *models.Location) error
+	// RemoveCatches takes catches, with their species, out of their owners' stats
+	// in tx, after they were deleted or rejected in it. The user's next catch of
+	// a species takes over the first-catch bonus, and badges the catches were
+	// needed for are taken back.
+	RemoveCatches(tx *gorm.DB, catches []models.AnimalCatch) error
+}
+
+type statsService struct {
+	userRepo      repositories.UserRepository
+	catchRepo     *repositories.AnimalCatchRepository
+	statsRepo     *repositories.UserStatsRepository
+	badgeEngine   BadgeEngine
+	streakService StreakService
+}
+
+func NewStatsService(userRepo repositories.UserRepository, catchRepo *repositories.AnimalCatchRepository, statsRepo *repositories.UserStatsRepository, badgeEngine BadgeEngine, streakService StreakService) StatsService {
+	return &statsService{
+		userRepo:      userRepo,
+		catchRepo:     catchRepo,
+		statsRepo:     statsRepo,
+		badgeEngine:   badgeEngine,
+		streakService: streakService,
+	}
+}
+
+func (s *statsService) Get(userID uuid.UUID) (*UserStatsSummary, error) {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	stats, err := s.statsRepo.GetByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Stats are created with the user's first catch or follow
+		return summarizeStats(&models.UserStats{UserID: userID}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return summarizeStats(stats), nil
+}
+
+func summarizeStats(stats *models.UserStats) *UserStatsSummary {
+	summary := &UserStatsSummary{
+		UserID:            stats.UserID,
+		Level:             stats.GetLevel(),
+		PointsToNextLevel: stats.GetPointsToNextLevel(),
+		TotalPoints:       stats.TotalPoints,
+		TotalCatches:      stats.TotalCatches,
+		UniqueSpecies:     stats.UniqueSpecies,
+		BadgesEarned:      stats.BadgesEarned,
+		CurrentStreak:     stats.CurrentStreak,
+		LongestStreak:     stats.LongestStreak,
+		LastCatchDate:     stats.LastCatchDate,
+		StreakFreezes:     stats.StreakFreezes,
+		Rarity: RarityCatches{
+			Common:    stats.CommonCatches,
+			Uncommon:  stats.UncommonCatches,
+			Rare:      stats.RareCatches,
+			Epic:      stats.EpicCatches,
+			Legendary: stats.LegendaryCatches,
+		},
+		Categories: CategoryCatches{
+			Mammal:    stats.MammalCatches,
+			Bird:      stats.BirdCatches,
+			Reptile:   stats.ReptileCatches,
+			Amphibian: stats.AmphibianCatches,
+			Fish:      stats.FishCatches,
+			Insect:    stats.InsectCatches,
+			Other:     stats.OtherCatches,
+		},
+		CountriesVisited: stats.CountriesVisited,
+		CitiesVisited:    stats.CitiesVisited,
+		FarthestDistance: stats.FarthestDistance,
+		FollowersCount:   stats.FollowersCount,
+		FollowingCount:   stats.FollowingCount,
+		TotalLikes:       stats.TotalLikes,
+		TotalComments:    stats.TotalComments,
+		GlobalRank:       stats.GlobalRank,
+		CountryRank:      stats.CountryRank,
+		CityRank:         stats.CityRank,
+		HomeCountry:      stats.HomeCountry,
+		HomeCity:         stats.HomeCity,
+	}
+	if !stats.LastUpdated.IsZero() {
+		summary.LastUpdated = &stats.LastUpdated
+	}
+	return summary
+}
+
+func (s *statsService) RecordDistance(tx *gorm.DB, stats *models.UserStats, catch *models.AnimalCatch, location *models.Location) error {
+	catchRepo := s.catchRepo.WithTx(tx)
+	home, err := catchRepo.GetFirstCatch(stats.UserID)
+	if err != nil {
+		return err
+	}
+	if home.ID != catch.ID {
+		stats.RecordDistance(home.Location.DistanceTo(location))
+		return nil
+	}
+	// A catch synced late from offline can move home, and every distance with it
+	stats.FarthestDistance, err = catchRepo.GetFarthestDistance(stats.UserID, location.Latitude, location.Longitude)
+	return err
+}
+
+func (s *statsService) RemoveCatches(tx *gorm.DB, catches []models.AnimalCatch) error {
+	if len(catches) == 0 {
+		return nil
+	}
+	catchRepo := s.catchRepo.WithTx(tx)
+	statsRepo := s.statsRepo.WithTx(tx)
+
+	// Catch rows are written before the owners' stats are locked, the order
+	// likes and comments take them in
+	pointsDelta := make(map[uuid.UUID]int)
+	byUser := make(map[uuid.UUID][]*models.AnimalCatch)
+	for i := range catches {
+		catch := &catches[i]
+		byUser[catch.UserID] = append(byUser[catch.UserID], catch)
+		if !catch.IsFirstCatch {
+			continue
+		}
+		delta, err := s.handOverFirstCatch(catchRepo, catch)
+		if err != nil {
+			return err
+		}
+		pointsDelta[catch.UserID] += delta
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(byUser))
+	for userID := range byUser {
+		userIDs = append(userIDs, userID)
+	}
+	slices.SortFunc(userIDs, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+	for _, userID := range userIDs {
+		stats, err := statsRepo.GetForUpdate(userID)
+		if err != nil {
+			return err
+		}
+
+		goneSpecies := make(map[uuid.UUID]bool)
+		for _, catch := range byUser[userID] {
+			lastOfSpecies := false
+			if !goneSpecies[catch.SpeciesID] {
+				if lastOfSpecies, err = catchRepo.IsFirstCatchForUser(userID, catch.SpeciesID); err != nil {
+					return err
+				}
+				goneSpecies[catch.SpeciesID] = lastOfSpecies
+			}
+			stats.RemoveCatch(&catch.Species, catch.PointsAwarded, lastOfSpecies)
+		}
+		stats.TotalPoints = max(stats.TotalPoints+pointsDelta[userID], 0)
+
+		if err := s.recountHistory(tx, stats); err != nil {
+			return err
+		}
+		revoked, err := s.badgeEngine.Reevaluate(tx, stats)
+		if err != nil {
+			return err
+		}
+		for _, badge := range revoked {
+			log.Printf("Took badge %s back from user %s after their catches were removed", badge.Name, userID)
+		}
+		if err := statsRepo.Update(stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handOverFirstCatch makes the user's next catch of the species of a removed
+// first catch their first, rescoring it with the first-catch bonus, and
+// returns the points it gained
+func (s *statsService) handOverFirstCatch(catchRepo *repositories.AnimalCatchRepository, removed *models.AnimalCatch) (int, error) {
+	next, err := catchRepo.GetFirstOfSpecies(removed.UserID, removed.SpeciesID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil || next.IsFirstCatch {
+		return 0, err
+	}
+
+	before := next.PointsAwarded
+	breakdown := withFirstCatchBonus(next.PointsBreakdown, before)
+	next.IsFirstCatch = true
+	next.PointsBreakdown = breakdown
+	next.PointsAwarded = breakdown.Total
+	next.ComboMultiplier = breakdown.Multiplier
+	if err := catchRepo.UpdateScore(next); err != nil {
+		return 0, err
+	}
+	return next.PointsAwarded - before, nil
+}
+
+// recountHistory recomputes the stats that depend on the user's whole catch
+// history rather than on single catches: streaks, places visited and the
+// farthest distance from home
+func (s *statsService) recountHistory(tx *gorm.DB, stats *models.UserStats) error {
+	if err := s.streakService.Replay(tx, stats); err != nil {
+		return err
+	}
+
+	catchRepo := s.catchRepo.WithTx(tx)
+
+	countries, cities, err := catchRepo.CountPlaces(stats.UserID)
+	if err != nil {
+		return err
+	}
+	stats.CountriesVisited = int(countries)
+	stats.CitiesVisited = int(cities)
+
+	home, err := catchRepo.GetFirstCatch(stats.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		stats.FarthestDistance = 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	stats.FarthestDistance, err = catchRepo.GetFarthestDistance(stats.UserID, home.Location.Latitude, home.Location.Longitude)
+	return err
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/anidex/backend/internal/config"
+)
+
+var (
+	ErrObjectNotFound   = errors.New("object not found")
+	ErrInvalidSignature = errors.New("invalid or expired upload signature")
+)
+
+// StorageService stores photo objects. Keys are slash separated paths such as
+// "photos/<user id>/<photo id>.jpg".
+type StorageService interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+
+	// URL returns the public URL of an object
+	URL(key string) string
+
+	// SignedUploadURL returns a URL the client can PUT the object to directly
+	SignedUploadURL(key, contentType string, expires time.Duration) (string, error)
+
+	// VerifyUploadSignature validates a signed upload that was sent to this API.
+	// Backends whose signed URLs point elsewhere always return an error.
+	VerifyUploadSignature(key, contentType string, expires int64, signature string) error
+}
+
+// NewStorageService creates the storage backend selected by STORAGE_BACKEND
+func NewStorageService() StorageService {
+	switch config.AppConfig.StorageBackend {
+	case "s3":
+		return newS3Storage(
+			config.AppConfig.S3Endpoint,
+			config.AppConfig.S3Region,
+			config.AppConfig.S3Bucket,
+			config.AppConfig.S3AccessKey,
+			config.AppConfig.S3SecretKey,
+			config.AppConfig.S3PublicURL,
+		)
+	case "local":
+		return newLocalStorage(config.AppConfig.StorageLocalDir, config.AppConfig.PublicURL, config.AppConfig.JWTSecret)
+	default:
+		log.Fatalf("Unknown storage backend %q", config.AppConfig.StorageBackend)
+		return nil
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const defaultStreakBatchSize = 500
+
+// streakMilestones are the streak lengths, in days, announced to the user when
+// reached. Each earns a streak freeze.
+var streakMilestones = []int{3, 7, 14, 30, 50, 100, 200, 365}
+
+// StreakConfig sets how forgiving daily streaks are
+type StreakConfig struct {
+	Grace      time.Duration // Catches this long after midnight still count for the day before
+	MaxFreezes int           // Freezes a user can hold; 0 turns them off
+}
+
+// StreakMilestone is a streak length a user just reached
+type StreakMilestone struct {
+	Days         int
+	FreezeEarned bool
+}
+
+// StreakResetResult is what a nightly streak run changed
+type StreakResetResult struct {
+	Checked int // Users whose streak had a day go by without a catch
+	Frozen  int // Users whose missed days freezes covered
+	Broken  int // Users whose streak ended
+}
+
+// StreakService keeps daily catch streaks. A streak counts consecutive calendar
+// days with a catch, in the user's time zone or, if they haven't set one, where
+// each catch was made. Catches in the grace period after midnight still count
+// for the day before. Freezes, earned at milestones, cover missed days one
+// each: when the user next catches something or, failing that, when the
+// nightly run finds the day over, which otherwise ends the streak.
+type StreakService interface {
+	// CatchDay returns the streak day of a catch made by the user at caughtAt,
+	// given in the time zone where it was made
+	CatchDay(user *models.User, caughtAt time.Time) time.Time
+	// CoverMissedDays covers the days missed before day, a catch's streak day,
+	// with freezes, in tx with the user's stats locked. The streak ends if there
+	// aren't enough.
+	CoverMissedDays(tx *gorm.DB, stats *models.UserStats, day time.Time) error
+	// ReachMilestone returns the milestone the streak reached since it was
+	// before days long, nil if none, and awards a freeze for it
+	ReachMilestone(stats *models.UserStats, before int) *StreakMilestone
+	// Announce tells the user about a milestone they reached, once it is saved
+	Announce(stats *models.UserStats, milestone *StreakMilestone)
+	// Replay recomputes the streaks in stats, locked by the caller in tx, from
+	// the user's catches and frozen days. A streak whose last day is over is
+	// then covered with freezes or ended, as the nightly run would.
+	Replay(tx *gorm.DB, stats *models.UserStats) error
+	// ResetBroken applies freezes to, or ends, the streaks of users who went a
+	// whole day without a catch, batchSize users at a time. Meant to run nightly.
+	ResetBroken(batchSize int) (*StreakResetResult, error)
+}
+
+type streakService struct {
+	userRepo  repositories.UserRepository
+	catchRepo *repositories.AnimalCatchRepository
+	statsRepo *repositories.UserStatsRepository
+	publisher events.Publisher
+	config    StreakConfig
+}
+
+func NewStreakService(userRepo repositories.UserRepository, catchRepo *repositories.AnimalCatchRepository, statsRepo *repositories.UserStatsRepository, publisher events.Publisher, config StreakConfig) StreakService {
+	return &streakService{
+		userRepo:  userRepo,
+		catchRepo: catchRepo,
+		statsRepo: statsRepo,
+		publisher: publisher,
+		config:    config,
+	}
+}
+
+func (s *streakService) CatchDay(user *models.User, caughtAt time.Time) time.Time {
+	zone := caughtAt.Location()
+	if user != nil && user.Timezone != "" {
+		if userZone, err := time.LoadLocation(user.Timezone); err == nil {
+			zone = userZone
+		}
+	}
+	return models.StreakDay(caughtAt, zone, s.config.Grace)
+}
+
+func (s *streakService) CoverMissedDays(tx *gorm.DB, stats *models.UserStats, day time.Time) error {
+	frozen := stats.CoverMissedDays(day.AddDate(0, 0, -1))
+	return s.statsRepo.WithTx(tx).AddStreakFreezes(stats.UserID, frozen)
+}
+
+func (s *streakService) ReachMilestone(stats *models.UserStats, before int) *StreakMilestone {
+	var reached *StreakMilestone
+	for _, days := range streakMilestones {
+		if before < days && stats.CurrentStreak >= days {
+			reached = &StreakMilestone{Days: days}
+		}
+	}
+	if reached != nil && stats.StreakFreezes < s.config.MaxFreezes {
+		stats.StreakFreezes++
+		reached.FreezeEarned = true
+	}
+	return reached
+}
+
+func (s *streakService) Announce(stats *models.UserStats, milestone *StreakMilestone) {
+	if milestone == nil {
+		return
+	}
+	s.publisher.Publish(events.New(events.TypeStreakMilestone, stats.UserID, uuid.Nil, events.StreakMilestoneData{
+		Days:          milestone.Days,
+		FreezeEarned:  milestone.FreezeEarned,
+		StreakFreezes: stats.StreakFreezes,
+	}))
+}
+
+func (s *streakService) Replay(tx *gorm.DB, stats *models.UserStats) error {
+	user, err := s.userRepo.FindByID(stats.UserID)
+	if err != nil {
+		return err
+	}
+	days, err := s.catchRepo.WithTx(tx).GetStreakDays(stats.UserID, user.Timezone, s.config.Grace)
+	if err != nil {
+		return err
+	}
+	frozen, err := s.statsRepo.WithTx(tx).GetStreakFreezeDays(stats.UserID)
+	if err != nil {
+		return err
+	}
+	stats.ReplayStreak(days, frozen)
+
+	zone, err := s.userZone(user)
+	if err != nil {
+		return err
+	}
+	missed := stats.CoverMissedDays(s.lastOverDay(zone, time.Now()))
+	return s.statsRepo.WithTx(tx).AddStreakFreezes(stats.UserID, missed)
+}
+
+func (s *streakService) ResetBroken(batchSize int) (*StreakResetResult, error) {
+	if batchSize < 1 {
+		batchSize = defaultStreakBatchSize
+	}
+
+	// A streak covering today in UTC can't be over yet in any time zone
+	now := time.Now()
+	today := models.StreakDay(now, time.UTC, 0)
+
+	result := &StreakResetResult{}
+	afterID := uuid.Nil
+	for {
+		userIDs, err := s.statsRepo.GetUserIDsWithStreakBefore(today, afterID, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range userIDs {
+			if err := s.resetUser(userID, now, result); err != nil {
+				return nil, err
+			}
+		}
+		if len(userIDs) < batchSize {
+			return result, nil
+		}
+		afterID = userIDs[len(userIDs)-1]
+	}
+}
+
+// resetUser covers the days the user has missed with freezes or ends their
+// streak, if the last of those days is over in their time zone
+func (s *streakService) resetUser(userID uuid.UUID, now time.Time, result *StreakResetResult) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	zone, err := s.userZone(user)
+	if err != nil {
+		return err
+	}
+	over := s.lastOverDay(zone, now)
+
+	return repositories.Transaction(func(tx *gorm.DB) error {
+		statsRepo := s.statsRepo.WithTx(tx)
+		stats, err := statsRepo.GetForUpdate(userID)
+		if err != nil {
+			return err
+		}
+		if stats.CurrentStreak == 0 || stats.LastStreakDate == nil || !stats.LastStreakDate.UTC().Before(over) {
+			return nil
+		}
+
+		result.Checked++
+		frozen := stats.CoverMissedDays(over)
+		if stats.CurrentStreak == 0 {
+			result.Broken++
+		} else {
+			result.Frozen++
+		}
+		if err := statsRepo.AddStreakFreezes(userID, frozen); err != nil {
+			return err
+		}
+		return statsRepo.Update(stats)
+	})
+}
+
+// lastOverDay returns the last streak day a catch can no longer be made for in
+// zone. Streaks that ended before it need freezes to carry on.
+func (s *streakService) lastOverDay(zone *time.Location, now time.Time) time.Time {
+	return models.StreakDay(now, zone, s.config.Grace).AddDate(0, 0, -1)
+}
+
+// userZone returns the time zone the user's streak days are counted in: theirs,
+// or where they last caught something
+func (s *streakService) userZone(user *models.User) (*time.Location, error) {
+	if user.Timezone != "" {
+		zone, err := time.LoadLocation(user.Timezone)
+		if err == nil {
+			return zone, nil
+		}
+		log.Printf("Ignoring unknown time zone %q of user %s", user.Timezone, user.ID)
+	}
+
+	last, err := s.catchRepo.GetPreviousUserCatch(user.ID, time.Now(), uuid.Nil)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return make(zoneCache).zone(&last.Location), nil
+}
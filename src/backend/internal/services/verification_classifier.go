@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+)
+
+// ClassifierVerifier asks an external image classifier whether the photo shows
+// the claimed species. Any service that speaks the JSON protocol below can be
+// plugged in; cmd/classifier-stub provides a local stand-in for development.
+type ClassifierVerifier struct {
+	endpoint string
+	client   *http.Client
+}
+
+type classifierRequest struct {
+	CatchID        string `json:"catch_id"`
+	PhotoURL       string `json:"photo_url"`
+	SpeciesID      string `json:"species_id"`
+	ScientificName string `json:"scientific_name"`
+	CommonName     string `json:"common_name"`
+}
+
+type classifierResponse struct {
+	Label      string  `json:"label"`      // Scientific name the classifier recognised
+	Confidence float64 `json:"confidence"` // Confidence (0-1) in that label
+}
+
+func NewClassifierVerifier(endpoint string) *ClassifierVerifier {
+	return &ClassifierVerifier{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+func (v *ClassifierVerifier) Name() string {
+	return "classifier"
+}
+
+func (v *ClassifierVerifier) Verify(ctx context.Context, catch *models.AnimalCatch) (*VerificationResult, error) {
+	body, err := json.Marshal(classifierRequest{
+		CatchID:        catch.ID.String(),
+		PhotoURL:       catch.UserPhotoURL,
+		SpeciesID:      catch.SpeciesID.String(),
+		ScientificName: catch.Species.ScientificName,
+		CommonName:     catch.Species.CommonName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("classifier returned status %d", resp.StatusCode)
+	}
+
+	var classified classifierResponse
+	if err := json.NewDecoder(resp.Body).Decode(&classified); err != nil {
+		return nil, err
+	}
+
+	confidence := classified.Confidence
+	if confidence < 0 {
+		confidence = 0
+	} else if confidence > 1 {
+		confidence = 1
+	}
+
+	result := &VerificationResult{Score: confidence}
+	if classified.Label != "" && !strings.EqualFold(classified.Label, catch.Species.ScientificName) {
+		// The classifier is confident it's something else
+		result.Score = 1 - confidence
+		result.Reasons = append(result.Reasons, fmt.Sprintf("classifier identified %s", classified.Label))
+	} else if confidence < 0.5 {
+		result.Reasons = append(result.Reasons, "classifier could not confirm the species")
+	}
+
+	return result, nil
+}
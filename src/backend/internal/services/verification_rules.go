@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"gorm.io/gorm"
+)
+
+const (
+	maxCatchAge       = 30 * 24 * time.Hour
+	maxClockSkew      = 5 * time.Minute
+	maxTravelSpeedKmh = 1000.0 // Faster than a commercial flight
+	maxGPSAccuracy    = 1000.0 // meters
+	rangeToleranceDeg = 2.0
+)
+
+// geoBox is a rough latitude/longitude bounding box
+type geoBox struct {
+	minLat, maxLat, minLng, maxLng float64
+}
+
+func (b geoBox) contains(lat, lng float64) bool {
+	return lat >= b.minLat-rangeToleranceDeg && lat <= b.maxLat+rangeToleranceDeg &&
+		lng >= b.minLng-rangeToleranceDeg && lng <= b.maxLng+rangeToleranceDeg
+}
+
+var (
+	boxAfrica       = geoBox{-35, 38, -18, 52}
+	boxAsia         = geoBox{-11, 78, 25, 180}
+	boxAmericas     = geoBox{-56, 84, -170, -34}
+	boxNorthAmerica = geoBox{7, 84, -170, -50}
+	boxSouthAmerica = geoBox{-56, 13, -82, -34}
+	boxWorld        = geoBox{-90, 90, -180, 180}
+)
+
+// geographicRegions maps phrases used in Species.GeographicRange to bounding boxes
+var geographicRegions = map[string][]geoBox{
+	"worldwide":           {boxWorld},
+	"northern hemisphere": {{0, 90, -180, 180}},
+	"southern hemisphere": {{-90, 0, -180, 180}},
+	"sub-saharan africa":  {{-35, 20, -18, 52}},
+	"africa":              {boxAfrica},
+	"asia":                {boxAsia},
+	"china":               {{18, 54, 73, 135}},
+	"india":               {{6, 36, 68, 98}},
+	"russia":              {{41, 82, 27, 180}},
+	"europe":              {{34, 72, -25, 45}},
+	"north america":       {boxNorthAmerica},
+	"central america":     {{7, 19, -93, -77}},
+	"south america":       {boxSouthAmerica},
+	"americas":            {boxAmericas},
+	"america":             {boxAmericas},
+	"australia":           {{-44, -10, 112, 154}},
+	"oceania":             {{-48, 0, 110, 180}},
+	"rwanda":              {boxAfrica},
+	"uganda":              {boxAfrica},
+	"congo":               {boxAfrica},
+	"kenya":               {boxAfrica},
+	"tanzania":            {boxAfrica},
+}
+
+// regionPhrases holds the geographicRegions keys longest first, so that
+// "north america" is consumed before "america" gets a chance to match
+var regionPhrases = func() []string {
+	phrases := make([]string, 0, len(geographicRegions))
+	for phrase := range geographicRegions {
+		phrases = append(phrases, phrase)
+	}
+	sort.Slice(phrases, func(i, j int) bool { return len(phrases[i]) > len(phrases[j]) })
+	return phrases
+}()
+
+// speciesRangeContains reports whether the coordinates fall inside the species'
+// geographic range. known is false when the range text mentions no region we
+// recognise, in which case the check should be skipped.
+func speciesRangeContains(geographicRange string, lat, lng float64) (inRange, known bool) {
+	text := strings.ToLower(geographicRange)
+	for _, phrase := range regionPhrases {
+		if !strings.Contains(text, phrase) {
+			continue
+		}
+		text = strings.ReplaceAll(text, phrase, "")
+		known = true
+		for _, box := range geographicRegions[phrase] {
+			if box.contains(lat, lng) {
+				return true, true
+			}
+		}
+	}
+	return false, known
+}
+
+// RuleVerifier checks a catch against simple plausibility rules: species range,
+// capture time, GPS sanity, travel speed and photo reuse
+type RuleVerifier struct {
+	catchRepo *repositories.AnimalCatchRepository
+}
+
+func NewRuleVerifier(catchRepo *repositories.AnimalCatchRepository) *RuleVerifier {
+	return &RuleVerifier{
+		catchRepo: catchRepo,
+	}
+}
+
+func (v *RuleVerifier) Name() string {
+	return "rules"
+}
+
+func (v *RuleVerifier) Verify(ctx context.Context, catch *models.AnimalCatch) (*VerificationResult, error) {
+	result := &VerificationResult{Score: 1.0}
+	penalize := func(factor float64, reason string) {
+		result.Score *= factor
+		result.Reasons = append(result.Reasons, reason)
+	}
+
+	lat, lng := catch.Location.Latitude, catch.Location.Longitude
+
+	// GPS sanity
+	switch {
+	case lat < -90 || lat > 90 || lng < -180 || lng > 180:
+		penalize(0, "coordinates out of range")
+	case lat == 0 && lng == 0:
+		penalize(0.2, "coordinates are 0,0")
+	}
+	if catch.Location.Accuracy != nil && *catch.Location.Accuracy > maxGPSAccuracy {
+		penalize(0.7, fmt.Sprintf("GPS accuracy is %.0fm", *catch.Location.Accuracy))
+	}
+
+	if catch.LocationMismatch && catch.ExifDistance != nil {
+		penalize(0.3, fmt.Sprintf("photo GPS is %.1fkm from the submitted location", *catch.ExifDistance))
+	}
+
+	// Species geographic range
+	if inRange, known := speciesRangeContains(catch.Species.GeographicRange, lat, lng); known && !inRange {
+		penalize(0.3, fmt.Sprintf("%s is outside its known range (%s)", catch.Species.CommonName, catch.Species.GeographicRange))
+	}
+
+	// Plausible capture time
+	now := time.Now()
+	switch {
+	case catch.CaughtAt.After(now.Add(maxClockSkew)):
+		penalize(0.1, "caught_at is in the future")
+	case now.Sub(catch.CaughtAt) > maxCatchAge:
+		penalize(0.7, "caught more than 30 days before submission")
+	}
+
+	// Travel speed since the user's previous catch
+	previous, err := v.catchRepo.GetPreviousUserCatch(catch.UserID, catch.CaughtAt, catch.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if previous != nil {
+		distance := previous.Location.DistanceTo(&catch.Location)
+		hours := catch.CaughtAt.Sub(previous.CaughtAt).Hours()
+		if distance > 1 && (hours <= 0 || distance/hours > maxTravelSpeedKmh) {
+			penalize(0.3, fmt.Sprintf("%.0fkm from previous catch in %.1fh", distance, hours))
+		}
+	}
+
+	// Photo resembling one another user already submitted; the same user's
+	// resubmissions are refused before the catch is created
+	if catch.DuplicateOf != nil || catch.ModerationReason == models.ModerationPossibleDuplicate {
+		penalize(0.1, "photo resembles another user's catch")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/anidex/backend/internal/events"
+	"github.com/anidex/backend/internal/models"
+	"github.com/anidex/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+const (
+	verificationWorkers   = 4
+	verificationQueueSize = 256
+	verificationTimeout   = 30 * time.Second
+
+	// Catches dropped from a full queue, or lost with it on restart, are found
+	// again by a periodic sweep. It leaves alone catches changed within the
+	// grace period, which are most likely still queued.
+	verificationSweepInterval = 5 * time.Minute
+	verificationSweepGrace    = time.Minute
+)
+
+// VerificationResult is a single verifier's opinion about a catch
+type VerificationResult struct {
+	Score   float64  // Confidence (0-1) that the catch is genuine
+	Reasons []string // Findings that lowered the score
+}
+
+// Verifier inspects a catch and returns how confident it is that the catch is genuine
+type Verifier interface {
+	Name() string
+	Verify(ctx context.Context, catch *models.AnimalCatch) (*VerificationResult, error)
+}
+
+// WeightedVerifier assigns a weight to a verifier's score in the combined confidence
+type WeightedVerifier struct {
+	Verifier Verifier
+	Weight   float64
+}
+
+type VerificationService interface {
+	Enqueue(catchID uuid.UUID)
+	VerifyCatch(ctx context.Context, catchID uuid.UUID) error
+}
+
+type verificationService struct {
+	catchRepo   *repositories.AnimalCatchRepository
+	verifiers   []WeightedVerifier
+	threshold   float64
+	queue       chan uuid.UUID
+	publisher   events.Publisher
+	badgeEngine BadgeEngine
+}
+
+// NewVerificationService starts the background workers that auto-verify new catches,
+// and the sweep that re-enqueues pending catches which were never scored.
+// Catches whose combined confidence reaches the threshold become auto_approved,
+// all others stay pending for the moderator queue.
+func NewVerificationService(catchRepo *repositories.AnimalCatchRepository, verifiers []WeightedVerifier, threshold float64, publisher events.Publisher, badgeEngine BadgeEngine) VerificationService {
+	s := &verificationService{
+		catchRepo:   catchRepo,
+		verifiers:   verifiers,
+		threshold:   threshold,
+		queue:       make(chan uuid.UUID, verificationQueueSize),
+		publisher:   publisher,
+		badgeEngine: badgeEngine,
+	}
+
+	for i := 0; i < verificationWorkers; i++ {
+		go s.worker()
+	}
+	go s.sweep()
+
+	return s
+}
+
+// Enqueue schedules a catch for asynchronous verification. If the queue is full the
+// catch stays pending until the next sweep picks it up.
+func (s *verificationService) Enqueue(catchID uuid.UUID) {
+	select {
+	case s.queue <- catchID:
+	default:
+		log.Printf("Verification queue full, leaving catch %s for the next sweep", catchID)
+	}
+}
+
+// sweep re-enqueues unscored pending catches at startup and then periodically
+func (s *verificationService) sweep() {
+	for {
+		if err := s.requeueUnscored(); err != nil {
+			log.Printf("Failed to re-enqueue unverified catches: %v", err)
+		}
+		time.Sleep(verificationSweepInterval)
+	}
+}
+
+// requeueUnscored fills the free room in the queue with the oldest pending
+// catches that have no auto-verification result
+func (s *verificationService) requeueUnscored() error {
+	room := cap(s.queue) - len(s.queue)
+	if room == 0 {
+		return nil
+	}
+	ids, err := s.catchRepo.GetUnscoredPendingIDs(time.Now().Add(-verificationSweepGrace), room)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		select {
+		case s.queue <- id:
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *verificationService) worker() {
+	for catchID := range s.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), verificationTimeout)
+		if err := s.VerifyCatch(ctx, catchID); err != nil {
+			log.Printf("Auto-verification of catch %s failed: %v", catchID, err)
+		}
+		cancel()
+	}
+}
+
+// VerifyCatch runs every verifier against the catch and records the combined score
+func (s *verificationService) VerifyCatch(ctx context.Context, catchID uuid.UUID) error {
+	catch, err := s.catchRepo.GetByID(catchID)
+	if err != nil {
+		return err
+	}
+	if catch.VerificationStatus != models.VerificationPending {
+		return nil
+	}
+
+	var weightedSum, totalWeight float64
+	var scores, reasons []string
+
+	for _, wv := range s.verifiers {
+		result, err := wv.Verifier.Verify(ctx, catch)
+		if err != nil {
+			// A broken verifier shouldn't block the others; its vote is just skipped
+			log.Printf("Verifier %s failed for catch %s: %v", wv.Verifier.Name(), catchID, err)
+			continue
+		}
+
+		weightedSum += result.Score * wv.Weight
+		totalWeight += wv.Weight
+		scores = append(scores, fmt.Sprintf("%s %.2f", wv.Verifier.Name(), result.Score))
+		reasons = append(reasons, result.Reasons...)
+	}
+
+	if totalWeight == 0 {
+		return errors.New("no verifier produced a result")
+	}
+
+	score := weightedSum / totalWeight
+	status := models.VerificationPending
+	if score >= s.threshold && !catch.RequiresModeration() {
+		status = models.VerificationAuto
+	}
+
+	notes := fmt.Sprintf("Auto-verification confidence %.2f (%s)", score, strings.Join(scores, ", "))
+	if len(reasons) > 0 {
+		notes += ": " + strings.Join(reasons, "; ")
+	}
+	if catch.RequiresModeration() {
+		notes += fmt.Sprintf(" [held for moderation: %s]", catch.ModerationReason)
+	}
+
+	applied, err := s.catchRepo.ApplyAutoVerification(catch.ID, status, score, notes)
+	if err != nil {
+		return err
+	}
+	if applied && status != models.VerificationPending {
+		s.publisher.Publish(events.New(events.TypeCatchVerified, catch.UserID, uuid.Nil, events.CatchVerifiedData{
+			CatchID: catch.ID,
+			Status:  string(status),
+		}))
+		if _, err := s.badgeEngine.EvaluateUser(catch.UserID, &catch.ID); err != nil {
+			log.Printf("Failed to update badges of user %s: %v", catch.UserID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Package solar computes sunrise, sunset and twilight times using the NOAA
+// sunrise equation, which is accurate to about a minute outside polar regions
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+// Solar elevation angles (degrees) that define the daily events
+const (
+	SunriseElevation       = -0.833 // Upper limb on the horizon, corrected for refraction
+	CivilTwilightElevation = -6.0
+	julianDayUnixEpoch     = 2440587.5
+	julianDayJ2000         = 2451545.0
+	earthAxialTiltDegrees  = 23.4397
+	secondsPerDay          = 86400.0
+	degreesToRadians       = math.Pi / 180
+)
+
+// Day holds the solar events of the solar day closest to a given instant. When
+// the sun never crosses an elevation on that day the corresponding times are
+// zero and the Always* flags tell whether it stays above or below.
+type Day struct {
+	Noon time.Time // Solar transit
+
+	Sunrise, Sunset time.Time
+	AlwaysUp        bool // Midnight sun: the sun never sets
+	AlwaysDown      bool // Polar night: the sun never rises
+
+	CivilDawn, CivilDusk time.Time
+	AlwaysLight          bool // Never darker than civil twilight
+	AlwaysDark           bool // Never brighter than civil twilight
+}
+
+// ForInstant computes the solar day at the coordinate whose solar noon is
+// nearest to t. Longitude is positive east.
+func ForInstant(t time.Time, lat, lng float64) Day {
+	jd := float64(t.Unix())/secondsPerDay + julianDayUnixEpoch
+
+	// Pick the day number whose transit is closest to t
+	n := math.Round(jd - julianDayJ2000 + lng/360)
+	meanSolarTime := n - lng/360
+
+	meanAnomaly := math.Mod(357.5291+0.98560028*meanSolarTime, 360)
+	m := meanAnomaly * degreesToRadians
+	center := 1.9148*math.Sin(m) + 0.0200*math.Sin(2*m) + 0.0003*math.Sin(3*m)
+	eclipticLongitude := math.Mod(meanAnomaly+center+180+102.9372, 360) * degreesToRadians
+
+	transit := julianDayJ2000 + meanSolarTime + 0.0053*math.Sin(m) - 0.0069*math.Sin(2*eclipticLongitude)
+	declination := math.Asin(math.Sin(eclipticLongitude) * math.Sin(earthAxialTiltDegrees*degreesToRadians))
+
+	day := Day{Noon: fromJulian(transit, t.Location())}
+
+	var ok bool
+	day.Sunrise, day.Sunset, ok = crossings(transit, lat, declination, SunriseElevation, t.Location())
+	if !ok {
+		day.AlwaysUp, day.AlwaysDown = alwaysAbove(lat, declination, SunriseElevation)
+	}
+	day.CivilDawn, day.CivilDusk, ok = crossings(transit, lat, declination, CivilTwilightElevation, t.Location())
+	if !ok {
+		day.AlwaysLight, day.AlwaysDark = alwaysAbove(lat, declination, CivilTwilightElevation)
+	}
+
+	return day
+}
+
+// crossings returns when the sun passes the elevation before and after transit
+func crossings(transit, lat, declination, elevation float64, loc *time.Location) (rise, set time.Time, ok bool) {
+	cosHourAngle := hourAngleCosine(lat, declination, elevation)
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	hourAngle := math.Acos(cosHourAngle) / degreesToRadians
+	return fromJulian(transit-hourAngle/360, loc), fromJulian(transit+hourAngle/360, loc), true
+}
+
+// alwaysAbove reports whether the sun stays above (or below) the elevation all day
+func alwaysAbove(lat, declination, elevation float64) (above, below bool) {
+	cosHourAngle := hourAngleCosine(lat, declination, elevation)
+	return cosHourAngle < -1, cosHourAngle > 1
+}
+
+func hourAngleCosine(lat, declination, elevation float64) float64 {
+	phi := lat * degreesToRadians
+	return (math.Sin(elevation*degreesToRadians) - math.Sin(phi)*math.Sin(declination)) /
+		(math.Cos(phi) * math.Cos(declination))
+}
+
+func fromJulian(jd float64, loc *time.Location) time.Time {
+	seconds := (jd - julianDayUnixEpoch) * secondsPerDay
+	return time.Unix(0, int64(seconds*float64(time.Second))).In(loc)
+}
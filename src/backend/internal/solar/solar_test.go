@@ -0,0 +1,98 @@
+package solar
+
+import (
+	"testing"
+	"time"
+)
+
+// tolerance is how far computed times may be from published ones
+const tolerance = 2 * time.Minute
+
+func TestForInstantSunriseSunset(t *testing.T) {
+	// Sunrise and sunset from the NOAA solar calculator, in UTC
+	tests := []struct {
+		name     string
+		at       string
+		lat, lng float64
+		sunrise  string
+		sunset   string
+	}{
+		{"London, summer solstice", "2024-06-21T12:00:00Z", 51.5074, -0.1278, "2024-06-21T03:43:00Z", "2024-06-21T20:21:00Z"},
+		{"New York, winter solstice", "2024-12-21T17:00:00Z", 40.7128, -74.0060, "2024-12-21T12:16:00Z", "2024-12-21T21:32:00Z"},
+		{"Sydney, summer solstice", "2024-12-21T02:00:00Z", -33.8688, 151.2093, "2024-12-20T18:41:00Z", "2024-12-21T09:05:00Z"},
+		{"Nairobi, equinox", "2024-03-20T09:00:00Z", -1.2921, 36.8219, "2024-03-20T03:36:00Z", "2024-03-20T15:43:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := ForInstant(parse(t, tt.at), tt.lat, tt.lng)
+			if day.AlwaysUp || day.AlwaysDown {
+				t.Fatalf("ForInstant() AlwaysUp = %v, AlwaysDown = %v, want a sunrise and sunset", day.AlwaysUp, day.AlwaysDown)
+			}
+			checkNear(t, "Sunrise", day.Sunrise, parse(t, tt.sunrise))
+			checkNear(t, "Sunset", day.Sunset, parse(t, tt.sunset))
+			if !day.CivilDawn.Before(day.Sunrise) || !day.Sunset.Before(day.CivilDusk) {
+				t.Errorf("civil twilight %v to %v doesn't surround the day", day.CivilDawn, day.CivilDusk)
+			}
+			if !day.Sunrise.Before(day.Noon) || !day.Noon.Before(day.Sunset) {
+				t.Errorf("Noon = %v, want it between sunrise and sunset", day.Noon)
+			}
+		})
+	}
+}
+
+func TestForInstantPolar(t *testing.T) {
+	tests := []struct {
+		name                    string
+		at                      string
+		lat, lng                float64
+		alwaysUp, alwaysDown    bool
+		alwaysLight, alwaysDark bool
+	}{
+		{"Tromsø, midnight sun", "2024-06-21T11:00:00Z", 69.6492, 18.9553, true, false, true, false},
+		// The sun doesn't rise, but it gets light at noon
+		{"Tromsø, polar night", "2024-12-21T11:00:00Z", 69.6492, 18.9553, false, true, false, false},
+		{"Longyearbyen, polar night", "2024-12-21T11:00:00Z", 78.2232, 15.6267, false, true, false, true},
+		{"McMurdo, midnight sun", "2024-12-21T00:00:00Z", -77.8419, 166.6863, true, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := ForInstant(parse(t, tt.at), tt.lat, tt.lng)
+			if day.AlwaysUp != tt.alwaysUp || day.AlwaysDown != tt.alwaysDown {
+				t.Errorf("AlwaysUp = %v, AlwaysDown = %v, want %v, %v", day.AlwaysUp, day.AlwaysDown, tt.alwaysUp, tt.alwaysDown)
+			}
+			if day.AlwaysLight != tt.alwaysLight || day.AlwaysDark != tt.alwaysDark {
+				t.Errorf("AlwaysLight = %v, AlwaysDark = %v, want %v, %v", day.AlwaysLight, day.AlwaysDark, tt.alwaysLight, tt.alwaysDark)
+			}
+			if (tt.alwaysUp || tt.alwaysDown) && (!day.Sunrise.IsZero() || !day.Sunset.IsZero()) {
+				t.Errorf("Sunrise = %v, Sunset = %v, want zero", day.Sunrise, day.Sunset)
+			}
+			if !tt.alwaysLight && !tt.alwaysDark && (day.CivilDawn.IsZero() || day.CivilDusk.IsZero()) {
+				t.Errorf("CivilDawn = %v, CivilDusk = %v, want civil twilight", day.CivilDawn, day.CivilDusk)
+			}
+		})
+	}
+}
+
+func TestForInstantKeepsLocation(t *testing.T) {
+	zone := time.FixedZone("UTC+3", 3*60*60)
+	day := ForInstant(time.Date(2024, 3, 20, 12, 0, 0, 0, zone), -1.2921, 36.8219)
+	if day.Sunrise.Location() != zone || day.Noon.Location() != zone {
+		t.Errorf("times are in %v, want %v", day.Sunrise.Location(), zone)
+	}
+}
+
+func parse(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func checkNear(t *testing.T, name string, got, want time.Time) {
+	t.Helper()
+	if diff := got.Sub(want).Abs(); diff > tolerance {
+		t.Errorf("%s = %v, want %v (off by %v)", name, got.UTC(), want, diff)
+	}
+}
@@ -0,0 +1,119 @@
+// Package timezone resolves the IANA time zone for a coordinate without any
+// external service. It uses an embedded list of reference places and picks the
+// zone of the nearest one, which is accurate away from zone borders; points far
+// from any place (open ocean) fall back to a nautical zone based on longitude.
+package timezone
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Embed the zone rules so lookups work on hosts without zoneinfo
+)
+
+// maxReferenceDistanceKm is how far a coordinate may be from the nearest
+// reference place before we stop trusting it and use the nautical zone
+const maxReferenceDistanceKm = 1500
+
+//go:embed zones.csv
+var zonesCSV string
+
+type referencePoint struct {
+	lat, lng float64
+	zone     string
+}
+
+var (
+	loadOnce   sync.Once
+	references []referencePoint
+	locations  sync.Map // zone name -> *time.Location
+)
+
+func loadReferences() {
+	scanner := bufio.NewScanner(strings.NewReader(zonesCSV))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			log.Printf("timezone: skipping malformed reference %q", line)
+			continue
+		}
+		lat, errLat := strconv.ParseFloat(fields[0], 64)
+		lng, errLng := strconv.ParseFloat(fields[1], 64)
+		if errLat != nil || errLng != nil {
+			log.Printf("timezone: skipping malformed reference %q", line)
+			continue
+		}
+
+		references = append(references, referencePoint{lat: lat, lng: lng, zone: fields[2]})
+	}
+}
+
+// Name returns the IANA zone name for the coordinate
+func Name(lat, lng float64) string {
+	loadOnce.Do(loadReferences)
+
+	best, bestDistance := "", math.MaxFloat64
+	for _, ref := range references {
+		if d := distanceKm(lat, lng, ref.lat, ref.lng); d < bestDistance {
+			best, bestDistance = ref.zone, d
+		}
+	}
+
+	if best == "" || bestDistance > maxReferenceDistanceKm {
+		return nauticalZone(lng)
+	}
+	return best
+}
+
+// Lookup returns the time zone for the coordinate
+func Lookup(lat, lng float64) *time.Location {
+	name := Name(lat, lng)
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("timezone: unknown zone %s, using nautical zone: %v", name, err)
+		loc, err = time.LoadLocation(nauticalZone(lng))
+		if err != nil {
+			return time.UTC
+		}
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+// nauticalZone returns the fixed Etc/GMT zone for a longitude. Note the
+// inverted sign in these names: Etc/GMT-5 is five hours ahead of UTC.
+func nauticalZone(lng float64) string {
+	offset := int(math.Round(lng / 15))
+	if offset == 0 {
+		return "Etc/GMT"
+	}
+	return fmt.Sprintf("Etc/GMT%+d", -offset)
+}
+
+// distanceKm is the haversine distance between two coordinates
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371.0
+	toRad := math.Pi / 180
+
+	dLat := (lat2 - lat1) * toRad
+	dLng := (lng2 - lng1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return earthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
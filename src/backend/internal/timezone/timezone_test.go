@@ -0,0 +1,78 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     string
+	}{
+		{"London", 51.5074, -0.1278, "Europe/London"},
+		{"Tokyo", 35.6762, 139.6503, "Asia/Tokyo"},
+		{"Sydney", -33.8688, 151.2093, "Australia/Sydney"},
+		// Either side of the US-Mexico border, a few kilometres apart
+		{"San Diego", 32.70, -117.15, "America/Los_Angeles"},
+		{"Tijuana", 32.53, -117.03, "America/Tijuana"},
+		// Open ocean, far from every reference place
+		{"mid Atlantic", 30.0, -40.0, "Etc/GMT+3"},
+		{"south Pacific", -45.0, -130.0, "Etc/GMT+9"},
+		{"Southern Ocean", -55.0, 80.0, "Etc/GMT-5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Name(tt.lat, tt.lng); got != tt.want {
+				t.Errorf("Name(%v, %v) = %s, want %s", tt.lat, tt.lng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNauticalZone(t *testing.T) {
+	tests := []struct {
+		lng  float64
+		want string
+	}{
+		{0, "Etc/GMT"},
+		{7.4, "Etc/GMT"},
+		{-7.4, "Etc/GMT"},
+		{7.6, "Etc/GMT-1"},
+		{-7.6, "Etc/GMT+1"},
+		{-40, "Etc/GMT+3"},
+		{172.5, "Etc/GMT-12"},
+		{-172.5, "Etc/GMT+12"},
+	}
+	for _, tt := range tests {
+		if got := nauticalZone(tt.lng); got != tt.want {
+			t.Errorf("nauticalZone(%v) = %s, want %s", tt.lng, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	winter := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		lat, lng   float64
+		wantOffset int // Hours ahead of UTC in January
+	}{
+		{"New York", 40.7128, -74.0060, -5},
+		{"Sydney", -33.8688, 151.2093, 11},
+		{"mid Atlantic", 30.0, -40.0, -3},
+		{"Southern Ocean", -55.0, 80.0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := Lookup(tt.lat, tt.lng)
+			if _, offset := winter.In(zone).Zone(); offset != tt.wantOffset*60*60 {
+				t.Errorf("Lookup(%v, %v) = %v, %d hours ahead of UTC, want %d", tt.lat, tt.lng, zone, offset/3600, tt.wantOffset)
+			}
+			if again := Lookup(tt.lat, tt.lng); again != zone {
+				t.Errorf("Lookup(%v, %v) returned a different location the second time", tt.lat, tt.lng)
+			}
+		})
+	}
+}
@@ -91,7 +91,7 @@ func ValidateRefreshToken(tokenString string) (uuid.UUID, error) {
 
 // GetUserIDFromContext extracts user ID from Gin context (set by auth middleware)
 func GetUserIDFromContext(c *gin.Context) (uuid.UUID, error) {
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		return uuid.Nil, errors.New("user ID not found in context")
 	}